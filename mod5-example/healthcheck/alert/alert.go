@@ -0,0 +1,474 @@
+// Package alert sends notifications about target state changes to webhook
+// endpoints and email recipients, following the rules in the config file.
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+)
+
+// defaultRetries is how often a failed delivery is retried when the config
+// doesn't say.
+const defaultRetries = 3
+
+// Event is the data available to payload templates and sent as-is by the
+// generic format.
+type Event struct {
+	Rule   string       `json:"rule"`
+	Target string       `json:"target"`
+	URL    string       `json:"url"`
+	From   status.State `json:"from"`
+	To     status.State `json:"to"`
+	Time   time.Time    `json:"time"`
+	Reason string       `json:"reason,omitempty"`
+	// Incident is the ID to acknowledge the alert with, when incidents
+	// are tracked.
+	Incident string `json:"incident,omitempty"`
+	// Step is the escalation step that sent the event, or zero for the
+	// rule's own webhooks and emails.
+	Step int `json:"step,omitempty"`
+}
+
+type transition struct {
+	from, to string
+}
+
+func (t transition) matches(from, to status.State) bool {
+	return (t.from == "*" || t.from == string(from)) && (t.to == "*" || t.to == string(to))
+}
+
+// step is a set of channels notified once an incident has been open for
+// after. A rule's first step is its own webhooks and emails.
+type step struct {
+	after    time.Duration
+	webhooks []string
+	emails   []string
+}
+
+type rule struct {
+	name    string
+	on      []transition
+	targets map[string]bool
+	steps   []step
+}
+
+func (r rule) matches(tr status.Transition) bool {
+	if len(r.targets) > 0 && !r.targets[tr.Target] {
+		return false
+	}
+	for _, t := range r.on {
+		if t.matches(tr.From, tr.To) {
+			return true
+		}
+	}
+	return false
+}
+
+type webhook struct {
+	config.Webhook
+	tmpl *template.Template
+}
+
+// payload renders the JSON body sent to the webhook for e.
+func (w *webhook) payload(e Event) ([]byte, error) {
+	if w.tmpl != nil {
+		var buf bytes.Buffer
+		if err := w.tmpl.Execute(&buf, e); err != nil {
+			return nil, fmt.Errorf("executing template: %w", err)
+		}
+		if !json.Valid(buf.Bytes()) {
+			return nil, fmt.Errorf("template did not produce valid JSON: %s", buf.String())
+		}
+		return buf.Bytes(), nil
+	}
+	if w.Format == "slack" {
+		return json.Marshal(map[string]string{"text": slackText(e)})
+	}
+	return json.Marshal(e)
+}
+
+var slackEmoji = map[status.State]string{
+	status.Up:       ":large_green_circle:",
+	status.Down:     ":red_circle:",
+	status.Degraded: ":warning:",
+	status.Burning:  ":fire:",
+}
+
+func slackText(e Event) string {
+	text := fmt.Sprintf("%s *%s* is %s (was %s)", slackEmoji[e.To], e.Target, strings.ToUpper(string(e.To)), e.From)
+	if e.Reason != "" {
+		text += ": " + e.Reason
+	}
+	if e.Step > 0 {
+		text = "Escalated, still unacknowledged: " + text
+	}
+	if e.Incident != "" && e.To != status.Up {
+		text += fmt.Sprintf(" (ack with `healthcheck alerts ack %s`)", e.Incident)
+	}
+	return strings.TrimSpace(text)
+}
+
+// templateFuncs are available to payload templates. json renders a value as
+// JSON, which safely quotes strings such as error messages.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Notifier delivers alerts for state transitions.
+type Notifier struct {
+	rules    []rule
+	webhooks map[string]*webhook
+	emails   map[string][]string
+	mailer   *Mailer
+	retries  int
+	// Backoff is the delay before the first retry; it doubles with every
+	// further attempt.
+	Backoff time.Duration
+	// Grace is how long deliveries go on after the context they were
+	// started with is done, so that alerts raised while shutting down, such
+	// as resolutions, still go out.
+	Grace time.Duration
+	// Incidents, when set, records an incident for every alert so it can
+	// be acknowledged and escalated, and so a restarted monitor doesn't
+	// alert again for targets that are still down.
+	Incidents *Incidents
+	// Silenced, when set, reports whether the alerts of the named target
+	// are suppressed, such as during a maintenance window. Its incidents
+	// don't escalate meanwhile.
+	Silenced func(target string) bool
+
+	client *http.Client
+	log    *slog.Logger
+	wg     sync.WaitGroup
+}
+
+// New builds a notifier from the alerts section of the config file.
+func New(cfg config.Alerts, log *slog.Logger) (*Notifier, error) {
+	n := &Notifier{
+		webhooks: make(map[string]*webhook),
+		emails:   make(map[string][]string),
+		retries:  cfg.Retries,
+		Backoff:  time.Second,
+		Grace:    10 * time.Second,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		log:      log,
+	}
+	if n.retries == 0 {
+		n.retries = defaultRetries
+	}
+	for _, w := range cfg.Webhooks {
+		hook := &webhook{Webhook: w}
+		if w.Template != "" {
+			tmpl, err := template.New(w.Name).Funcs(templateFuncs).Parse(w.Template)
+			if err != nil {
+				return nil, fmt.Errorf("webhook %q: invalid template: %w", w.Name, err)
+			}
+			hook.tmpl = tmpl
+		}
+		n.webhooks[w.Name] = hook
+	}
+	for _, e := range cfg.Emails {
+		n.emails[e.Name] = e.To
+	}
+	if cfg.SMTP.Host != "" {
+		n.mailer = NewMailer(cfg.SMTP)
+	}
+	escalations := make(map[string][]step)
+	for _, e := range cfg.Escalations {
+		for _, s := range e.Steps {
+			escalations[e.Name] = append(escalations[e.Name], step{after: s.After, webhooks: s.Webhooks, emails: s.Emails})
+		}
+		sort.SliceStable(escalations[e.Name], func(i, j int) bool {
+			return escalations[e.Name][i].after < escalations[e.Name][j].after
+		})
+	}
+	for i, r := range cfg.Rules {
+		parsed := rule{
+			name:    r.Name,
+			targets: make(map[string]bool),
+			steps:   append([]step{{webhooks: r.Webhooks, emails: r.Emails}}, escalations[r.Escalation]...),
+		}
+		if parsed.name == "" {
+			parsed.name = fmt.Sprintf("rule-%d", i+1)
+		}
+		for _, on := range r.On {
+			from, to, ok := strings.Cut(on, "->")
+			if !ok {
+				from, to = "*", on
+			}
+			parsed.on = append(parsed.on, transition{strings.TrimSpace(from), strings.TrimSpace(to)})
+		}
+		for _, t := range r.Targets {
+			parsed.targets[t] = true
+		}
+		n.rules = append(n.rules, parsed)
+	}
+	return n, nil
+}
+
+// Notify sends tr to the webhooks and emails of every matching rule.
+// Deliveries happen in the background; use Wait to let them finish.
+//
+// With Incidents set, an alert about a problem opens an incident, and one
+// about a target recovering resolves it and also goes to every step the
+// incident had escalated to.
+func (n *Notifier) Notify(ctx context.Context, tr status.Transition, url string) {
+	var resolved map[string]Incident
+	if n.Incidents != nil && recovers(tr) {
+		resolved = n.resolve(ctx, tr.Target, tr.Time)
+	}
+	for _, r := range n.rules {
+		if !r.matches(tr) {
+			continue
+		}
+		e := Event{Rule: r.name, Target: tr.Target, URL: url, From: tr.From, To: tr.To, Time: tr.Time, Reason: tr.Reason}
+		steps := 1
+		if inc, ok := resolved[r.name]; ok {
+			e.Incident, steps = inc.ID, inc.Step
+		} else if n.Incidents != nil && !recovers(tr) {
+			inc, opened, err := n.open(r, e)
+			switch {
+			case err != nil:
+				n.log.ErrorContext(ctx, "failed to record incident", "rule", r.name, "target", tr.Target, "err", err)
+			case !opened && tr.From == status.Unknown:
+				// The monitor restarted while the incident was open, and
+				// whoever it paged already knows.
+				n.log.InfoContext(ctx, "incident still open", "incident", inc.ID, "rule", r.name, "target", tr.Target)
+				continue
+			default:
+				e.Incident, steps = inc.ID, inc.Step
+			}
+		}
+		// The incident may have been paged by more steps than the rule has,
+		// if its escalation shrank since.
+		for _, s := range r.steps[:min(steps, len(r.steps))] {
+			n.send(ctx, s, e)
+		}
+	}
+}
+
+// Resolve resolves the open incidents of target without notifying anyone,
+// for targets that recover while their alerts are suppressed or that are
+// no longer monitored.
+func (n *Notifier) Resolve(ctx context.Context, target string, at time.Time) {
+	if n.Incidents != nil {
+		n.resolve(ctx, target, at)
+	}
+}
+
+// recovers reports whether tr ends the problems of its target: it is up
+// again, or at least responding after being down.
+func recovers(tr status.Transition) bool {
+	return tr.To == status.Up || (tr.From == status.Down && tr.To == status.Degraded)
+}
+
+// Escalate notifies the next steps of every open, unacknowledged incident
+// that has waited long enough for them, unless its target is silenced.
+func (n *Notifier) Escalate(ctx context.Context, now time.Time) {
+	if n.Incidents == nil {
+		return
+	}
+	rules := make(map[string]rule)
+	for _, r := range n.rules {
+		rules[r.name] = r
+	}
+	var due []Event
+	var steps []step
+	err := n.Incidents.update(func(incidents []Incident) ([]Incident, error) {
+		for i := range incidents {
+			inc := &incidents[i]
+			r, ok := rules[inc.Rule]
+			if !ok || !inc.Open() || inc.Acknowledged() || (n.Silenced != nil && n.Silenced(inc.Target)) {
+				continue
+			}
+			inc.Step = max(inc.Step, 0)
+			for inc.Step < len(r.steps) && now.Sub(inc.Opened) >= r.steps[inc.Step].after {
+				due = append(due, Event{Rule: inc.Rule, Target: inc.Target, URL: inc.URL, From: inc.From, To: inc.State,
+					Time: now, Reason: inc.Reason, Incident: inc.ID, Step: inc.Step})
+				steps = append(steps, r.steps[inc.Step])
+				inc.Step++
+			}
+		}
+		return incidents, nil
+	})
+	if err != nil {
+		n.log.ErrorContext(ctx, "failed to escalate incidents", "err", err)
+		return
+	}
+	for i, e := range due {
+		n.log.InfoContext(ctx, "escalating incident", "incident", e.Incident, "rule", e.Rule, "target", e.Target, "step", e.Step)
+		n.send(ctx, steps[i], e)
+	}
+}
+
+// Escalates reports whether any rule has escalation steps.
+func (n *Notifier) Escalates() bool {
+	for _, r := range n.rules {
+		if len(r.steps) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// open returns the open incident of rule r for e's target, opening a new
+// one if there is none. opened reports whether it is new.
+func (n *Notifier) open(r rule, e Event) (inc Incident, opened bool, err error) {
+	err = n.Incidents.update(func(incidents []Incident) ([]Incident, error) {
+		for i := range incidents {
+			if existing := &incidents[i]; existing.Open() && existing.Rule == r.name && existing.Target == e.Target {
+				existing.State, existing.Reason = e.To, e.Reason
+				inc = *existing
+				return incidents, nil
+			}
+		}
+		inc = Incident{ID: newIncidentID(), Rule: r.name, Target: e.Target, URL: e.URL, From: e.From, State: e.To,
+			Reason: e.Reason, Opened: e.Time, Step: 1}
+		opened = true
+		return append(incidents, inc), nil
+	})
+	return inc, opened, err
+}
+
+// resolve closes the open incidents of target at the given time and returns
+// them by rule.
+func (n *Notifier) resolve(ctx context.Context, target string, at time.Time) map[string]Incident {
+	resolved := make(map[string]Incident)
+	err := n.Incidents.update(func(incidents []Incident) ([]Incident, error) {
+		for i := range incidents {
+			if inc := &incidents[i]; inc.Open() && inc.Target == target {
+				inc.Resolved = at
+				resolved[inc.Rule] = *inc
+			}
+		}
+		return incidents, nil
+	})
+	if err != nil {
+		n.log.ErrorContext(ctx, "failed to resolve incidents", "target", target, "err", err)
+	}
+	for _, inc := range resolved {
+		n.log.InfoContext(ctx, "incident resolved", "incident", inc.ID, "rule", inc.Rule, "target", inc.Target)
+	}
+	return resolved
+}
+
+// send delivers e to the webhooks and emails of s.
+func (n *Notifier) send(ctx context.Context, s step, e Event) {
+	for _, name := range s.webhooks {
+		w := n.webhooks[name]
+		n.deliver(ctx, "webhook "+name, e, func(ctx context.Context) error {
+			body, err := w.payload(e)
+			if err != nil {
+				return &renderError{err}
+			}
+			return n.post(ctx, w, body)
+		})
+	}
+	for _, name := range s.emails {
+		n.Email(ctx, name, emailSubject(e), emailBody(e), e)
+	}
+}
+
+// Email sends a message to the recipients of the named email in the
+// background, retrying like any other alert. e describes what the message
+// is about for logging and may be empty.
+func (n *Notifier) Email(ctx context.Context, name, subject, body string, e Event) {
+	to := n.emails[name]
+	n.deliver(ctx, "email "+name, e, func(context.Context) error {
+		if n.mailer == nil {
+			return &renderError{fmt.Errorf("no smtp server configured")}
+		}
+		return n.mailer.Send(to, subject, body)
+	})
+}
+
+// Wait blocks until all deliveries in flight have finished.
+func (n *Notifier) Wait() {
+	n.wg.Wait()
+}
+
+// renderError marks a failure that retrying won't fix.
+type renderError struct {
+	err error
+}
+
+func (e *renderError) Error() string {
+	return e.err.Error()
+}
+
+// deliver calls send in the background until it succeeds or the retries
+// run out, backing off between attempts and logging the outcome. Deliveries
+// outlive ctx by Grace.
+func (n *Notifier) deliver(ctx context.Context, channel string, e Event, send func(context.Context) error) {
+	attrs := []any{"channel", channel, "rule", e.Rule, "target", e.Target}
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		dctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		defer cancel()
+		stop := context.AfterFunc(ctx, func() { time.AfterFunc(n.Grace, cancel) })
+		defer stop()
+		ctx = dctx
+		backoff := n.Backoff
+		var err error
+		for attempt := 0; attempt <= n.retries; attempt++ {
+			err = send(ctx)
+			if err == nil {
+				n.log.InfoContext(ctx, "alert delivered", append(attrs, "to", e.To, "attempt", attempt)...)
+				return
+			}
+			var re *renderError
+			if errors.As(err, &re) {
+				n.log.ErrorContext(ctx, "failed to render alert", append(attrs, "err", err)...)
+				return
+			}
+			n.log.WarnContext(ctx, "alert delivery failed", append(attrs, "attempt", attempt, "err", err)...)
+			if attempt == n.retries {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		n.log.ErrorContext(ctx, "giving up on alert", append(attrs, "retries", n.retries, "err", err)...)
+	}()
+}
+
+func (n *Notifier) post(ctx context.Context, w *webhook, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/stretchr/testify/assert"
+)
+
+// receiver is a local webhook endpoint that records the payloads it gets.
+// It answers the first failures requests with a 500.
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	bodies   []map[string]any
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.calls++
+	if rc.calls <= rc.failures {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var body map[string]any
+	data, _ := io.ReadAll(r.Body)
+	json.Unmarshal(data, &body)
+	rc.bodies = append(rc.bodies, body)
+}
+
+func newNotifier(t *testing.T, cfg config.Alerts) *Notifier {
+	t.Helper()
+	n, err := New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.NoError(t, err)
+	n.Backoff = time.Millisecond
+	return n
+}
+
+var downTransition = status.Transition{
+	Target: "api",
+	From:   status.Up,
+	To:     status.Down,
+	Time:   time.Date(2024, 4, 19, 10, 0, 0, 0, time.UTC),
+	Reason: "unexpected status code 503",
+}
+
+func TestNotify_Generic(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	n := newNotifier(t, config.Alerts{
+		Webhooks: []config.Webhook{{Name: "hook", URL: srv.URL}},
+		Rules:    []config.AlertRule{{Name: "outages", On: []string{"up->down"}, Webhooks: []string{"hook"}}},
+	})
+	n.Notify(context.Background(), downTransition, "http://api.example.com")
+	n.Wait()
+
+	assert.Len(t, rc.bodies, 1)
+	assert.Equal(t, "outages", rc.bodies[0]["rule"])
+	assert.Equal(t, "api", rc.bodies[0]["target"])
+	assert.Equal(t, "http://api.example.com", rc.bodies[0]["url"])
+	assert.Equal(t, "down", rc.bodies[0]["to"])
+	assert.Equal(t, "unexpected status code 503", rc.bodies[0]["reason"])
+}
+
+func TestNotify_SlackAndTemplate(t *testing.T) {
+	slack, custom := &receiver{}, &receiver{}
+	slackSrv, customSrv := httptest.NewServer(slack), httptest.NewServer(custom)
+	defer slackSrv.Close()
+	defer customSrv.Close()
+
+	n := newNotifier(t, config.Alerts{
+		Webhooks: []config.Webhook{
+			{Name: "slack", URL: slackSrv.URL, Format: "slack"},
+			{Name: "custom", URL: customSrv.URL, Template: `{"summary": {{json (printf "%s went %s" .Target .To)}}, "why": {{json .Reason}}}`},
+		},
+		Rules: []config.AlertRule{{On: []string{"down"}, Webhooks: []string{"slack", "custom"}}},
+	})
+	n.Notify(context.Background(), downTransition, "http://api.example.com")
+	n.Wait()
+
+	assert.Len(t, slack.bodies, 1)
+	assert.Equal(t, ":red_circle: *api* is DOWN (was up): unexpected status code 503", slack.bodies[0]["text"])
+	assert.Len(t, custom.bodies, 1)
+	assert.Equal(t, "api went down", custom.bodies[0]["summary"])
+	assert.Equal(t, "unexpected status code 503", custom.bodies[0]["why"])
+}
+
+func TestNotify_Retries(t *testing.T) {
+	rc := &receiver{failures: 2}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	n := newNotifier(t, config.Alerts{
+		Webhooks: []config.Webhook{{Name: "hook", URL: srv.URL}},
+		Rules:    []config.AlertRule{{On: []string{"*->*"}, Webhooks: []string{"hook"}}},
+		Retries:  2,
+	})
+	n.Notify(context.Background(), downTransition, "")
+	n.Wait()
+
+	assert.Equal(t, 3, rc.calls)
+	assert.Len(t, rc.bodies, 1)
+}
+
+func TestNotify_RuleMatching(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	n := newNotifier(t, config.Alerts{
+		Webhooks: []config.Webhook{{Name: "hook", URL: srv.URL}},
+		Rules: []config.AlertRule{
+			{On: []string{"down->up"}, Webhooks: []string{"hook"}},
+			{On: []string{"degraded"}, Targets: []string{"web"}, Webhooks: []string{"hook"}},
+		},
+	})
+	n.Notify(context.Background(), downTransition, "")
+	n.Notify(context.Background(), status.Transition{Target: "api", From: status.Up, To: status.Degraded}, "")
+	n.Notify(context.Background(), status.Transition{Target: "web", From: status.Up, To: status.Degraded}, "")
+	n.Notify(context.Background(), status.Transition{Target: "api", From: status.Down, To: status.Up}, "")
+	n.Wait()
+
+	assert.Len(t, rc.bodies, 2)
+}
+
+func TestNew_InvalidTemplate(t *testing.T) {
+	_, err := New(config.Alerts{Webhooks: []config.Webhook{{Name: "bad", URL: "http://x", Template: "{{"}}}, slog.Default())
+	assert.Error(t, err)
+}
+
+func TestNotify_Shutdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		grace    time.Duration
+		bodies   int
+	}{
+		{"Delivered within the grace period", 1, time.Second, 1},
+		{"Given up after the grace period", 100, 20 * time.Millisecond, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rc := &receiver{failures: tc.failures}
+			srv := httptest.NewServer(rc)
+			defer srv.Close()
+			n := newNotifier(t, config.Alerts{
+				Webhooks: []config.Webhook{{Name: "hook", URL: srv.URL}},
+				Rules:    []config.AlertRule{{On: []string{"*->*"}, Webhooks: []string{"hook"}}},
+				Retries:  100,
+			})
+			n.Backoff, n.Grace = 5*time.Millisecond, tc.grace
+
+			// Recovering while shutting down.
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			start := time.Now()
+			n.Notify(ctx, status.Transition{Target: "api", From: status.Down, To: status.Up, Time: time.Now()}, "")
+			n.Wait()
+
+			assert.Len(t, rc.bodies, tc.bodies)
+			assert.Less(t, time.Since(start), time.Second)
+		})
+	}
+}
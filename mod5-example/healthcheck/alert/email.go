@@ -0,0 +1,115 @@
+package alert
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+)
+
+// Mailer sends plain-text email through an SMTP server.
+type Mailer struct {
+	cfg config.SMTP
+	// TLSConfig is used for STARTTLS. It defaults to verifying the server
+	// certificate against the host name.
+	TLSConfig *tls.Config
+}
+
+// NewMailer returns a mailer for the given server settings.
+func NewMailer(cfg config.SMTP) *Mailer {
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	if cfg.Password == "" && cfg.PasswordEnv != "" {
+		cfg.Password = os.Getenv(cfg.PasswordEnv)
+	}
+	return &Mailer{cfg: cfg, TLSConfig: &tls.Config{ServerName: cfg.Host}}
+}
+
+// Send delivers a message to the recipients in to.
+func (m *Mailer) Send(to []string, subject, body string) error {
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	c, err := smtp.Dial(addr)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", addr, err)
+	}
+	defer c.Close()
+
+	if err := c.Hello("localhost"); err != nil {
+		return err
+	}
+	if m.cfg.StartTLS {
+		if err := c.StartTLS(m.TLSConfig); err != nil {
+			return fmt.Errorf("starting TLS: %w", err)
+		}
+	}
+	if m.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+	if err := c.Mail(m.cfg.From); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("recipient %s: %w", rcpt, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message(m.cfg.From, to, subject, body)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func message(from string, to []string, subject, body string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// emailSubject and emailBody render a state change for email.
+func emailSubject(e Event) string {
+	subject := fmt.Sprintf("[healthcheck] %s is %s", e.Target, strings.ToUpper(string(e.To)))
+	if e.Step > 0 {
+		subject += " (escalated)"
+	}
+	return subject
+}
+
+func emailBody(e Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s changed from %s to %s at %s.\n\n", e.Target, e.From, e.To, e.Time.Format("01/02/2006 03:04:05PM MST"))
+	if e.URL != "" && e.URL != e.Target {
+		fmt.Fprintf(&b, "URL:    %s\n", e.URL)
+	}
+	if e.Reason != "" {
+		fmt.Fprintf(&b, "Reason: %s\n", e.Reason)
+	}
+	fmt.Fprintf(&b, "Rule:   %s\n", e.Rule)
+	if e.Incident != "" && e.To != status.Up {
+		fmt.Fprintf(&b, "\nAcknowledge with: healthcheck alerts ack %s\n", e.Incident)
+	}
+	return b.String()
+}
@@ -0,0 +1,209 @@
+package alert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// mail is a message captured by smtpStandIn.
+type mail struct {
+	from string
+	to   []string
+	data string
+	auth string
+	tls  bool
+}
+
+// smtpStandIn is a minimal local SMTP server that captures every message
+// it receives. It offers STARTTLS when tlsConfig is set.
+type smtpStandIn struct {
+	ln        net.Listener
+	tlsConfig *tls.Config
+
+	mu    sync.Mutex
+	mails []mail
+}
+
+func newSMTPStandIn(t *testing.T, tlsConfig *tls.Config) *smtpStandIn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStandIn{ln: ln, tlsConfig: tlsConfig}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStandIn) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStandIn) captured() []mail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]mail(nil), s.mails...)
+}
+
+func (s *smtpStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP stand-in")
+	var m mail
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-localhost")
+			if s.tlsConfig != nil && !m.tls {
+				tp.PrintfLine("250-STARTTLS")
+			}
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			tp.PrintfLine("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+			m.tls = true
+		case "AUTH":
+			_, creds, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(creds)
+			m.auth = string(decoded)
+			tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			m.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			m.to = append(m.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 send the message")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			m.data = string(data)
+			s.mu.Lock()
+			s.mails = append(s.mails, m)
+			s.mu.Unlock()
+			tp.PrintfLine("250 OK")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+// selfSignedTLS returns a server config for 127.0.0.1 and a client config
+// that trusts it.
+func selfSignedTLS(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	server = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	client = &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"}
+	return server, client
+}
+
+func TestMailer_StartTLSAndAuth(t *testing.T) {
+	serverTLS, clientTLS := selfSignedTLS(t)
+	srv := newSMTPStandIn(t, serverTLS)
+
+	m := NewMailer(config.SMTP{
+		Host:     "127.0.0.1",
+		Port:     srv.port(),
+		Username: "healthcheck",
+		Password: "secret",
+		From:     "healthcheck@example.com",
+		StartTLS: true,
+	})
+	m.TLSConfig = clientTLS
+	err := m.Send([]string{"ops@example.com", "dev@example.com"}, "hello", "line one\nline two")
+	assert.NoError(t, err)
+
+	mails := srv.captured()
+	assert.Len(t, mails, 1)
+	assert.True(t, mails[0].tls, "expected the message to be sent after STARTTLS")
+	assert.Equal(t, "\x00healthcheck\x00secret", mails[0].auth)
+	assert.Equal(t, "healthcheck@example.com", mails[0].from)
+	assert.Equal(t, []string{"ops@example.com", "dev@example.com"}, mails[0].to)
+	assert.Contains(t, mails[0].data, "Subject: hello")
+	assert.Contains(t, mails[0].data, "line one\nline two")
+}
+
+func TestNotify_Email(t *testing.T) {
+	srv := newSMTPStandIn(t, nil)
+	n := newNotifier(t, config.Alerts{
+		SMTP:   config.SMTP{Host: "127.0.0.1", Port: srv.port(), From: "healthcheck@example.com"},
+		Emails: []config.Email{{Name: "stakeholders", To: []string{"boss@example.com"}}},
+		Rules:  []config.AlertRule{{Name: "outages", On: []string{"down"}, Emails: []string{"stakeholders"}}},
+	})
+	n.Notify(context.Background(), downTransition, "http://api.example.com")
+	n.Wait()
+
+	mails := srv.captured()
+	assert.Len(t, mails, 1)
+	assert.Equal(t, []string{"boss@example.com"}, mails[0].to)
+	assert.Contains(t, mails[0].data, "Subject: [healthcheck] api is DOWN")
+	assert.Contains(t, mails[0].data, "Reason: unexpected status code 503")
+	assert.Contains(t, mails[0].data, "URL:    http://api.example.com")
+}
+
+func TestMailer_ConnectionRefused(t *testing.T) {
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	m := NewMailer(config.SMTP{Host: "127.0.0.1", Port: port, From: "a@example.com"})
+	err := m.Send([]string{"b@example.com"}, "s", "b")
+	assert.ErrorContains(t, err, "connecting to 127.0.0.1:"+strconv.Itoa(port))
+}
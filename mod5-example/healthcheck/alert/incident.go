@@ -0,0 +1,155 @@
+package alert
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+)
+
+// keepResolved is how long resolved incidents stay in the incidents file.
+const keepResolved = 7 * 24 * time.Hour
+
+// Incident is an alert that has been sent for a target and stays open
+// until the target recovers or is no longer monitored. Unacknowledged
+// incidents escalate.
+type Incident struct {
+	ID     string       `json:"id"`
+	Rule   string       `json:"rule"`
+	Target string       `json:"target"`
+	URL    string       `json:"url"`
+	From   status.State `json:"from"`
+	State  status.State `json:"state"`
+	Reason string       `json:"reason,omitempty"`
+	Opened time.Time    `json:"opened"`
+	// Step is the number of escalation steps notified so far, counting
+	// the rule's own webhooks and emails as the first.
+	Step     int       `json:"step"`
+	Acked    time.Time `json:"acked"`
+	AckedBy  string    `json:"acked_by,omitempty"`
+	Resolved time.Time `json:"resolved"`
+}
+
+// Open reports whether the incident hasn't been resolved.
+func (i Incident) Open() bool {
+	return i.Resolved.IsZero()
+}
+
+// Acknowledged reports whether someone has acknowledged the incident.
+func (i Incident) Acknowledged() bool {
+	return !i.Acked.IsZero()
+}
+
+// Incidents keeps incidents in a JSON file, so they survive restarts of the
+// monitor and can be acknowledged from another process. Every change reads
+// the file afresh while holding a lock on it shared with other processes,
+// so acknowledgements made meanwhile aren't lost.
+type Incidents struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewIncidents returns the incidents kept in the file at path.
+func NewIncidents(path string) *Incidents {
+	return &Incidents{path: path}
+}
+
+// List returns all incidents in the order they were opened.
+func (s *Incidents) List() ([]Incident, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// Ack acknowledges the open incident with the given ID, which stops its
+// escalation.
+func (s *Incidents) Ack(id, by string, at time.Time) (Incident, error) {
+	var acked Incident
+	err := s.update(func(incidents []Incident) ([]Incident, error) {
+		for i := range incidents {
+			inc := &incidents[i]
+			if inc.ID != id {
+				continue
+			}
+			if !inc.Open() {
+				return nil, fmt.Errorf("incident %s was already resolved", id)
+			}
+			if !inc.Acknowledged() {
+				inc.Acked, inc.AckedBy = at, by
+			}
+			acked = *inc
+			return incidents, nil
+		}
+		return nil, fmt.Errorf("no incident with id %s", id)
+	})
+	return acked, err
+}
+
+// update applies change to the incidents in the file and saves the result,
+// dropping incidents resolved more than keepResolved ago.
+func (s *Incidents) update(change func([]Incident) ([]Incident, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The file itself is replaced on every save, so the lock is taken on
+	// one next to it.
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return fmt.Errorf("unable to lock incidents file: %w", err)
+	}
+	defer unlock()
+	incidents, err := s.load()
+	if err != nil {
+		return err
+	}
+	incidents, err = change(incidents)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-keepResolved)
+	kept := incidents[:0]
+	for _, inc := range incidents {
+		if inc.Open() || inc.Resolved.After(cutoff) {
+			kept = append(kept, inc)
+		}
+	}
+	return s.save(kept)
+}
+
+func (s *Incidents) load() ([]Incident, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read incidents file: %w", err)
+	}
+	var incidents []Incident
+	if err := json.Unmarshal(data, &incidents); err != nil {
+		return nil, fmt.Errorf("unable to parse incidents file %s: %w", s.path, err)
+	}
+	return incidents, nil
+}
+
+func (s *Incidents) save(incidents []Incident) error {
+	data, err := json.MarshalIndent(incidents, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("unable to write incidents file: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func newIncidentID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,216 @@
+package alert
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/stretchr/testify/assert"
+)
+
+// escalatingNotifier pages first and then oncall after ten minutes.
+func escalatingNotifier(t *testing.T, path string, first, oncall *receiver) *Notifier {
+	t.Helper()
+	firstSrv, oncallSrv := httptest.NewServer(first), httptest.NewServer(oncall)
+	t.Cleanup(firstSrv.Close)
+	t.Cleanup(oncallSrv.Close)
+	n := newNotifier(t, config.Alerts{
+		Webhooks: []config.Webhook{{Name: "first", URL: firstSrv.URL}, {Name: "oncall", URL: oncallSrv.URL}},
+		Escalations: []config.Escalation{{Name: "page", Steps: []config.EscalationStep{
+			{After: 10 * time.Minute, Webhooks: []string{"oncall"}},
+		}}},
+		Rules: []config.AlertRule{{Name: "outages", On: []string{"down", "up"}, Webhooks: []string{"first"}, Escalation: "page"}},
+	})
+	n.Incidents = NewIncidents(path)
+	return n
+}
+
+func TestEscalate(t *testing.T) {
+	first, oncall := &receiver{}, &receiver{}
+	n := escalatingNotifier(t, filepath.Join(t.TempDir(), "incidents.json"), first, oncall)
+	ctx := context.Background()
+	// Resolved incidents are only kept for a week, so this one is recent.
+	down := downTransition
+	down.Time = time.Now()
+	opened := down.Time
+
+	n.Notify(ctx, down, "http://api.example.com")
+	n.Escalate(ctx, opened.Add(9*time.Minute))
+	n.Wait()
+	assert.Len(t, first.bodies, 1)
+	assert.Empty(t, oncall.bodies, "the second step is not due yet")
+	id := first.bodies[0]["incident"]
+	assert.NotEmpty(t, id)
+
+	n.Escalate(ctx, opened.Add(10*time.Minute))
+	n.Escalate(ctx, opened.Add(11*time.Minute))
+	n.Wait()
+	assert.Len(t, oncall.bodies, 1, "each step is notified once")
+	assert.Equal(t, id, oncall.bodies[0]["incident"])
+	assert.EqualValues(t, 1, oncall.bodies[0]["step"])
+
+	n.Notify(ctx, status.Transition{Target: "api", From: status.Down, To: status.Up, Time: opened.Add(15 * time.Minute)}, "")
+	n.Wait()
+	assert.Len(t, first.bodies, 2)
+	assert.Len(t, oncall.bodies, 2, "recovery goes to every step that was paged")
+
+	incidents, err := n.Incidents.List()
+	assert.NoError(t, err)
+	assert.Len(t, incidents, 1)
+	assert.False(t, incidents[0].Open())
+}
+
+func TestEscalate_Acknowledged(t *testing.T) {
+	first, oncall := &receiver{}, &receiver{}
+	n := escalatingNotifier(t, filepath.Join(t.TempDir(), "incidents.json"), first, oncall)
+	ctx := context.Background()
+
+	n.Notify(ctx, downTransition, "")
+	n.Wait()
+	inc, err := n.Incidents.Ack(first.bodies[0]["incident"].(string), "sam", time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, "sam", inc.AckedBy)
+
+	n.Escalate(ctx, downTransition.Time.Add(time.Hour))
+	n.Wait()
+	assert.Empty(t, oncall.bodies)
+
+	_, err = n.Incidents.Ack("missing", "sam", time.Now())
+	assert.ErrorContains(t, err, "no incident with id missing")
+}
+
+func TestNotify_Restart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "incidents.json")
+	first, oncall := &receiver{}, &receiver{}
+	ctx := context.Background()
+
+	n := escalatingNotifier(t, path, first, oncall)
+	n.Notify(ctx, downTransition, "")
+	n.Wait()
+
+	// A restarted monitor sees the target go from unknown to down.
+	restarted := escalatingNotifier(t, path, first, oncall)
+	restarted.Notify(ctx, status.Transition{Target: "api", From: status.Unknown, To: status.Down, Time: time.Now()}, "")
+	restarted.Wait()
+	assert.Len(t, first.bodies, 1, "a restart should not page again")
+
+	incidents, err := restarted.Incidents.List()
+	assert.NoError(t, err)
+	assert.Len(t, incidents, 1)
+	assert.True(t, incidents[0].Open())
+}
+
+func TestNotify_StepsRemoved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "incidents.json")
+	first, oncall := &receiver{}, &receiver{}
+	ctx := context.Background()
+
+	n := escalatingNotifier(t, path, first, oncall)
+	n.Notify(ctx, downTransition, "")
+	n.Wait()
+	// The incident was paged by steps a later config no longer has.
+	assert.NoError(t, n.Incidents.update(func(incidents []Incident) ([]Incident, error) {
+		incidents[0].Step = 5
+		return incidents, nil
+	}))
+
+	restarted := escalatingNotifier(t, path, first, oncall)
+	restarted.Escalate(ctx, time.Now().Add(time.Hour))
+	restarted.Notify(ctx, status.Transition{Target: "api", From: status.Down, To: status.Up, Time: time.Now()}, "")
+	restarted.Wait()
+	assert.Len(t, first.bodies, 2)
+	assert.Len(t, oncall.bodies, 1, "recovery goes to the steps the rule still has")
+
+	incidents, err := restarted.Incidents.List()
+	assert.NoError(t, err)
+	assert.False(t, incidents[0].Open())
+}
+
+func TestIncidents_Resolved(t *testing.T) {
+	tests := []struct {
+		name    string
+		resolve func(ctx context.Context, n *Notifier)
+	}{
+		{"Responding again", func(ctx context.Context, n *Notifier) {
+			n.Notify(ctx, status.Transition{Target: "api", From: status.Down, To: status.Degraded, Time: time.Now()}, "")
+		}},
+		{"Quietly", func(ctx context.Context, n *Notifier) {
+			n.Resolve(ctx, "api", time.Now())
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			first, oncall := &receiver{}, &receiver{}
+			n := escalatingNotifier(t, filepath.Join(t.TempDir(), "incidents.json"), first, oncall)
+			ctx := context.Background()
+			n.Notify(ctx, downTransition, "")
+			tc.resolve(ctx, n)
+			n.Escalate(ctx, time.Now().Add(time.Hour))
+			n.Wait()
+
+			assert.Len(t, first.bodies, 1, "only the outage was alerted")
+			assert.Empty(t, oncall.bodies, "resolved incidents don't escalate")
+			incidents, err := n.Incidents.List()
+			assert.NoError(t, err)
+			if assert.Len(t, incidents, 1) {
+				assert.False(t, incidents[0].Open())
+			}
+		})
+	}
+}
+
+func TestEscalate_Silenced(t *testing.T) {
+	first, oncall := &receiver{}, &receiver{}
+	n := escalatingNotifier(t, filepath.Join(t.TempDir(), "incidents.json"), first, oncall)
+	ctx := context.Background()
+	silenced := true
+	n.Silenced = func(target string) bool { return target == "api" && silenced }
+
+	n.Notify(ctx, downTransition, "")
+	n.Escalate(ctx, downTransition.Time.Add(time.Hour))
+	n.Wait()
+	assert.Empty(t, oncall.bodies, "incidents don't escalate during maintenance")
+
+	silenced = false
+	n.Escalate(ctx, downTransition.Time.Add(time.Hour))
+	n.Wait()
+	assert.Len(t, oncall.bodies, 1)
+}
+
+func TestIncidents_AckDuringUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "incidents.json")
+	monitor, cli := NewIncidents(path), NewIncidents(path)
+	assert.NoError(t, monitor.update(func([]Incident) ([]Incident, error) {
+		return []Incident{{ID: "abc", Rule: "outages", Target: "api", Opened: time.Now()}}, nil
+	}))
+
+	// Another process acknowledges the incident while the monitor is
+	// escalating it.
+	acked := make(chan error)
+	assert.NoError(t, monitor.update(func(incidents []Incident) ([]Incident, error) {
+		go func() {
+			_, err := cli.Ack("abc", "sam", time.Now())
+			acked <- err
+		}()
+		select {
+		case err := <-acked:
+			t.Errorf("the ack didn't wait for the update: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+		incidents[0].Step++
+		return incidents, nil
+	}))
+	assert.NoError(t, <-acked)
+
+	incidents, err := monitor.List()
+	assert.NoError(t, err)
+	if assert.Len(t, incidents, 1) {
+		assert.Equal(t, "sam", incidents[0].AckedBy, "the ack isn't lost")
+		assert.Equal(t, 1, incidents[0].Step)
+	}
+}
@@ -0,0 +1,226 @@
+// Package baseline learns the normal latency and error rate of each target,
+// by hour of day, and flags recent checks that stray too far from it.
+package baseline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/latency"
+)
+
+// memory is the number of checks after which the baseline stops averaging
+// and starts forgetting: older checks then weigh exponentially less, so the
+// baseline follows lasting changes in a target's behavior.
+const memory = 5000
+
+// z95 is the number of standard deviations below the 95th percentile of a
+// normal distribution.
+const z95 = 1.645
+
+// Stat is an exponentially weighted summary of checks. Latencies are
+// averaged on a log scale, since response times are skewed towards slow
+// outliers.
+type Stat struct {
+	// Checks counts every check and Timed the successful ones with a
+	// latency.
+	Checks int `json:"checks"`
+	Timed  int `json:"timed"`
+	// Mean and Var are the mean and variance of the natural logarithm of
+	// the latency in seconds.
+	Mean float64 `json:"mean"`
+	Var  float64 `json:"var"`
+	// ErrRate is the fraction of failed checks.
+	ErrRate float64 `json:"err_rate"`
+}
+
+func weight(n int) float64 {
+	return 1 / float64(min(n, memory))
+}
+
+func (s *Stat) add(up bool, d time.Duration) {
+	s.Checks++
+	failed := 0.0
+	if !up {
+		failed = 1
+	}
+	s.ErrRate += weight(s.Checks) * (failed - s.ErrRate)
+	if !up || d <= 0 {
+		return
+	}
+	s.Timed++
+	x := math.Log(d.Seconds())
+	if s.Timed == 1 {
+		s.Mean = x
+		return
+	}
+	a := weight(s.Timed)
+	delta := x - s.Mean
+	s.Mean += a * delta
+	s.Var = (1 - a) * (s.Var + a*delta*delta)
+}
+
+// quantile returns the latency z standard deviations above the mean.
+func (s Stat) quantile(z float64) time.Duration {
+	return time.Duration(math.Exp(s.Mean+z*math.Sqrt(s.Var)) * float64(time.Second))
+}
+
+// P95 is the 95th percentile latency the stat expects.
+func (s Stat) P95() time.Duration {
+	return s.quantile(z95)
+}
+
+// Target is the baseline of one target: a stat for each hour of the day and
+// one across all hours, used until an hour has seen enough checks.
+type Target struct {
+	Hours [24]Stat `json:"hours"`
+	All   Stat     `json:"all"`
+}
+
+// Options tune anomaly detection.
+type Options struct {
+	// Sensitivity is the number of standard deviations recent latency or
+	// error rates must exceed the baseline by to be anomalous. It defaults
+	// to 3.
+	Sensitivity float64
+	// Window is the number of recent checks compared with the baseline. It
+	// defaults to 20.
+	Window int
+	// MinChecks is the number of checks a baseline needs before it is
+	// trusted. It defaults to 100.
+	MinChecks int
+}
+
+// minRatio is how many times slower than the baseline's 95th percentile the
+// recent one must be to be anomalous, however steady the target normally is.
+const minRatio = 1.5
+
+// Baselines holds the baselines of all targets, keyed by URL, along with
+// their most recent checks.
+type Baselines struct {
+	mu      sync.Mutex
+	opts    Options
+	targets map[string]*Target
+	recent  map[string][]latency.Sample
+}
+
+// New returns empty baselines.
+func New(opts Options) *Baselines {
+	if opts.Sensitivity <= 0 {
+		opts.Sensitivity = 3
+	}
+	if opts.Window <= 0 {
+		opts.Window = 20
+	}
+	if opts.MinChecks <= 0 {
+		opts.MinChecks = 100
+	}
+	return &Baselines{opts: opts, targets: make(map[string]*Target), recent: make(map[string][]latency.Sample)}
+}
+
+// Load returns the baselines saved in the file at path, or empty ones if it
+// doesn't exist yet.
+func Load(path string, opts Options) (*Baselines, error) {
+	b := New(opts)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read baseline file: %w", err)
+	}
+	if err := json.Unmarshal(data, &b.targets); err != nil {
+		return nil, fmt.Errorf("unable to parse baseline file %s: %w", path, err)
+	}
+	return b, nil
+}
+
+// Save writes the baselines to the file at path.
+func (b *Baselines) Save(path string) error {
+	b.mu.Lock()
+	data, err := json.Marshal(b.targets)
+	b.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("unable to write baseline file: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Observe adds a check of url at t to its recent checks and compares them
+// with the baseline for that hour of day, before learning from the check.
+// It returns an explanation such as "p95 420ms vs baseline 90ms" when the
+// recent checks are anomalous, or "" otherwise.
+func (b *Baselines) Observe(url string, t time.Time, up bool, d time.Duration) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	target, ok := b.targets[url]
+	if !ok {
+		target = &Target{}
+		b.targets[url] = target
+	}
+	recent := append(b.recent[url], latency.Sample{Up: up, Latency: d})
+	if len(recent) > b.opts.Window {
+		recent = recent[len(recent)-b.opts.Window:]
+	}
+	b.recent[url] = recent
+
+	hour := &target.Hours[t.Hour()]
+	anomaly := b.compare(recent, *hour, target.All)
+	hour.add(up, d)
+	target.All.add(up, d)
+	return anomaly
+}
+
+// compare checks recent against hour, or against all while hour has too few
+// checks.
+func (b *Baselines) compare(recent []latency.Sample, hour, all Stat) string {
+	var reasons []string
+	k := b.opts.Sensitivity
+	failed := countFailed(recent)
+
+	base := hour
+	if base.Checks < b.opts.MinChecks {
+		base = all
+	}
+	if base.Checks >= b.opts.MinChecks {
+		rate := float64(failed) / float64(len(recent))
+		p := base.ErrRate
+		if failed >= 2 && rate > p+k*math.Sqrt(p*(1-p)/float64(len(recent))) {
+			reasons = append(reasons, fmt.Sprintf("error rate %.0f%% vs baseline %.1f%%", 100*rate, 100*p))
+		}
+	}
+
+	// A percentile of only a few successful checks says too little.
+	base = hour
+	if base.Timed < b.opts.MinChecks {
+		base = all
+	}
+	if base.Timed >= b.opts.MinChecks && 2*(len(recent)-failed) >= b.opts.Window {
+		p95 := latency.Summarize(recent, 0).P95
+		expected := base.P95()
+		if p95 > base.quantile(k) && float64(p95) > minRatio*float64(expected) {
+			reasons = append(reasons, fmt.Sprintf("p95 %s vs baseline %s", p95.Round(time.Millisecond), expected.Round(time.Millisecond)))
+		}
+	}
+	return strings.Join(reasons, ", ")
+}
+
+func countFailed(samples []latency.Sample) int {
+	n := 0
+	for _, s := range samples {
+		if !s.Up {
+			n++
+		}
+	}
+	return n
+}
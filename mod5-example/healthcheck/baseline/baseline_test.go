@@ -0,0 +1,105 @@
+package baseline
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// learn feeds n successful checks of url, one a minute from start, with
+// latencies spread around typical.
+func learn(b *Baselines, url string, start time.Time, n int, typical time.Duration) time.Time {
+	r := rand.New(rand.NewSource(1))
+	t := start
+	for i := 0; i < n; i++ {
+		jitter := time.Duration(r.Int63n(int64(typical / 5)))
+		b.Observe(url, t, true, typical-typical/10+jitter)
+		t = t.Add(time.Minute)
+	}
+	return t
+}
+
+func TestObserve_Latency(t *testing.T) {
+	b := New(Options{})
+	start := time.Date(2024, 4, 19, 8, 0, 0, 0, time.UTC)
+	now := learn(b, "http://api", start, 200, 90*time.Millisecond)
+
+	var anomaly string
+	for i := 0; i < 20; i++ {
+		anomaly = b.Observe("http://api", now, true, 420*time.Millisecond)
+		now = now.Add(time.Minute)
+	}
+	assert.Regexp(t, `^p95 420ms vs baseline \d+ms$`, anomaly)
+
+	// A lone slow check is no anomaly.
+	b = New(Options{})
+	now = learn(b, "http://api", start, 200, 90*time.Millisecond)
+	assert.Empty(t, b.Observe("http://api", now, true, 420*time.Millisecond))
+}
+
+func TestObserve_ErrorRate(t *testing.T) {
+	b := New(Options{})
+	start := time.Date(2024, 4, 19, 8, 0, 0, 0, time.UTC)
+	now := learn(b, "http://api", start, 200, 90*time.Millisecond)
+
+	assert.Empty(t, b.Observe("http://api", now, false, 0), "a single failure is no anomaly")
+	anomaly := b.Observe("http://api", now.Add(time.Minute), false, 0)
+	assert.Equal(t, "error rate 10% vs baseline 0.5%", anomaly)
+}
+
+func TestObserve_Learning(t *testing.T) {
+	b := New(Options{MinChecks: 50})
+	start := time.Date(2024, 4, 19, 8, 0, 0, 0, time.UTC)
+	now := learn(b, "http://api", start, 30, 90*time.Millisecond)
+	for i := 0; i < 19; i++ {
+		assert.Empty(t, b.Observe("http://api", now, true, time.Second), "too few checks to judge")
+		now = now.Add(time.Minute)
+	}
+}
+
+func TestObserve_HourOfDay(t *testing.T) {
+	b := New(Options{MinChecks: 50})
+	day := time.Date(2024, 4, 19, 0, 0, 0, 0, time.UTC)
+	// Nightly batch jobs make the API slow at 2am.
+	for d := 0; d < 3; d++ {
+		learn(b, "http://api", day.AddDate(0, 0, d).Add(2*time.Hour), 60, 400*time.Millisecond)
+		learn(b, "http://api", day.AddDate(0, 0, d).Add(10*time.Hour), 60, 90*time.Millisecond)
+	}
+
+	night := day.AddDate(0, 0, 3).Add(2 * time.Hour)
+	noon := day.AddDate(0, 0, 3).Add(10 * time.Hour)
+	var atNight, atNoon string
+	for i := 0; i < 20; i++ {
+		atNight = b.Observe("http://night", night, true, 400*time.Millisecond)
+		atNoon = b.Observe("http://noon", noon, true, 400*time.Millisecond)
+	}
+	assert.Empty(t, atNight, "no baseline for the URL yet")
+
+	for i := 0; i < 20; i++ {
+		atNight = b.Observe("http://api", night.Add(time.Duration(i)*time.Minute), true, 400*time.Millisecond)
+		atNoon = b.Observe("http://api", noon.Add(time.Duration(i)*time.Minute), true, 400*time.Millisecond)
+	}
+	assert.Empty(t, atNight, "slow is normal at night")
+	assert.Contains(t, atNoon, "p95 400ms vs baseline")
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "healthcheck.baseline.json")
+	b, err := Load(path, Options{})
+	assert.NoError(t, err)
+	now := learn(b, "http://api", time.Date(2024, 4, 19, 8, 0, 0, 0, time.UTC), 200, 90*time.Millisecond)
+	assert.NoError(t, b.Save(path))
+
+	loaded, err := Load(path, Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, b.targets, loaded.targets)
+
+	var anomaly string
+	for i := 0; i < 20; i++ {
+		anomaly = loaded.Observe("http://api", now, true, 420*time.Millisecond)
+	}
+	assert.NotEmpty(t, anomaly, "the baseline carries over")
+}
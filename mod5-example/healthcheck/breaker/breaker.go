@@ -0,0 +1,159 @@
+// Package breaker adapts how often targets are checked to their health.
+// Targets that are down are checked more often, to notice their recovery
+// quickly, until they have been down for long. Then their circuit opens and
+// they are left alone for a growing backoff, after which a single half-open
+// check decides whether to close the circuit again.
+package breaker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// State is the state of a target's circuit.
+type State string
+
+const (
+	// Closed is the normal state, in which the target is checked on its
+	// interval, or on the recovery interval while it is down.
+	Closed State = "closed"
+	// Open means the target has been down for long and isn't checked
+	// again until its backoff is over.
+	Open State = "open"
+	// HalfOpen means the backoff is over and a single check is under way
+	// to find out whether the target recovered.
+	HalfOpen State = "half-open"
+)
+
+// Options control when circuits open and how targets are checked meanwhile.
+type Options struct {
+	// RecoveryInterval is the time between checks of a down target while
+	// its circuit is closed. Targets that are checked more often anyway
+	// keep their own interval. A zero value uses a quarter of the target's
+	// interval.
+	RecoveryInterval time.Duration
+	// OpenAfter is how long a target must be down for its circuit to open.
+	// A zero value never opens circuits.
+	OpenAfter time.Duration
+	// Backoff is how long a circuit stays open at first. It doubles every
+	// time a half-open check fails, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Transition records a circuit moving from one state to another.
+type Transition struct {
+	Target string
+	From   State
+	To     State
+	Time   time.Time
+	// Reason explains the new state.
+	Reason string
+}
+
+type circuit struct {
+	state State
+	// downSince is when the target went down, or zero while it is up.
+	downSince time.Time
+	backoff   time.Duration
+}
+
+// Breakers holds the circuit of every target, all of which start closed.
+type Breakers struct {
+	opts     Options
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// New returns breakers with every circuit closed.
+func New(opts Options) *Breakers {
+	opts.MaxBackoff = max(opts.MaxBackoff, opts.Backoff)
+	return &Breakers{opts: opts, circuits: make(map[string]*circuit)}
+}
+
+func (b *Breakers) circuit(name string) *circuit {
+	c, ok := b.circuits[name]
+	if !ok {
+		c = &circuit{state: Closed}
+		b.circuits[name] = c
+	}
+	return c
+}
+
+// State returns the state of the circuit of name.
+func (b *Breakers) State(name string) State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.circuit(name).state
+}
+
+// Begin records that a check of name starts at now. The first check after
+// a circuit opened, which Interval delays by the backoff, turns it
+// half-open. Begin returns that transition and true if the circuit changed
+// state.
+func (b *Breakers) Begin(name string, now time.Time) (Transition, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(name)
+	if c.state != Open {
+		return Transition{}, false
+	}
+	c.state = HalfOpen
+	return Transition{Target: name, From: Open, To: HalfOpen, Time: now, Reason: fmt.Sprintf("backoff of %s is over", c.backoff)}, true
+}
+
+// Record records whether the check of name at now found it down. It returns
+// the resulting transition and true if the circuit changed state.
+func (b *Breakers) Record(name string, down bool, now time.Time) (Transition, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(name)
+	from := c.state
+	switch {
+	case !down:
+		c.state, c.downSince, c.backoff = Closed, time.Time{}, 0
+		if from == Closed {
+			return Transition{}, false
+		}
+		return Transition{Target: name, From: from, To: Closed, Time: now, Reason: "check succeeded"}, true
+	case c.downSince.IsZero():
+		c.downSince = now
+		return Transition{}, false
+	case from == HalfOpen:
+		c.backoff = min(2*c.backoff, b.opts.MaxBackoff)
+		c.state = Open
+		return Transition{Target: name, From: from, To: Open, Time: now, Reason: fmt.Sprintf("half-open check failed, retrying in %s", c.backoff)}, true
+	case from == Closed && b.opts.OpenAfter > 0 && now.Sub(c.downSince) >= b.opts.OpenAfter:
+		c.backoff = b.opts.Backoff
+		c.state = Open
+		return Transition{Target: name, From: from, To: Open, Time: now, Reason: fmt.Sprintf("down for %s, retrying in %s", now.Sub(c.downSince).Round(time.Second), c.backoff)}, true
+	}
+	return Transition{}, false
+}
+
+// Interval returns the time until name should be checked next, given its
+// normal interval: the backoff while its circuit is open, the recovery
+// interval while it is down and interval otherwise.
+func (b *Breakers) Interval(name string, interval time.Duration) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(name)
+	switch {
+	case c.state == Open:
+		return c.backoff
+	case c.downSince.IsZero():
+		return interval
+	case b.opts.RecoveryInterval > 0:
+		return min(b.opts.RecoveryInterval, interval)
+	default:
+		return interval / 4
+	}
+}
+
+// Remove forgets the circuit of name.
+func (b *Breakers) Remove(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.circuits, name)
+}
@@ -0,0 +1,73 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreakers(t *testing.T) {
+	b := New(Options{OpenAfter: time.Hour, Backoff: 5 * time.Minute, MaxBackoff: 15 * time.Minute})
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	const interval = time.Minute
+
+	type step struct {
+		begin    bool
+		down     bool
+		after    time.Duration
+		state    State
+		interval time.Duration
+		reason   string
+	}
+	steps := []step{
+		{down: false, state: Closed, interval: interval},
+		{down: true, after: time.Minute, state: Closed, interval: 15 * time.Second},
+		{down: true, after: 30 * time.Minute, state: Closed, interval: 15 * time.Second},
+		{down: true, after: 61 * time.Minute, state: Open, interval: 5 * time.Minute, reason: "down for 1h0m0s, retrying in 5m0s"},
+		{begin: true, after: 66 * time.Minute, state: HalfOpen, interval: 15 * time.Second, reason: "backoff of 5m0s is over"},
+		{down: true, after: 66 * time.Minute, state: Open, interval: 10 * time.Minute, reason: "half-open check failed, retrying in 10m0s"},
+		{begin: true, after: 76 * time.Minute, state: HalfOpen, interval: 15 * time.Second, reason: "backoff of 10m0s is over"},
+		{down: true, after: 76 * time.Minute, state: Open, interval: 15 * time.Minute, reason: "half-open check failed, retrying in 15m0s"},
+		{begin: true, after: 91 * time.Minute, state: HalfOpen, interval: 15 * time.Second, reason: "backoff of 15m0s is over"},
+		{down: true, after: 91 * time.Minute, state: Open, interval: 15 * time.Minute, reason: "half-open check failed, retrying in 15m0s"},
+		{begin: true, after: 106 * time.Minute, state: HalfOpen, interval: 15 * time.Second, reason: "backoff of 15m0s is over"},
+		{down: false, after: 106 * time.Minute, state: Closed, interval: interval, reason: "check succeeded"},
+	}
+	for i, s := range steps {
+		from := b.State("api")
+		var tr Transition
+		var changed bool
+		if s.begin {
+			tr, changed = b.Begin("api", start.Add(s.after))
+		} else {
+			tr, changed = b.Record("api", s.down, start.Add(s.after))
+		}
+		assert.Equal(t, s.state, b.State("api"), "step %d", i)
+		assert.Equal(t, s.interval, b.Interval("api", interval), "step %d", i)
+		assert.Equal(t, from != s.state, changed, "step %d", i)
+		if changed {
+			assert.Equal(t, Transition{Target: "api", From: from, To: s.state, Time: start.Add(s.after), Reason: s.reason}, tr, "step %d", i)
+		}
+	}
+}
+
+func TestBreakers_Begin(t *testing.T) {
+	b := New(Options{})
+	_, changed := b.Begin("api", time.Now())
+	assert.False(t, changed, "closed circuits should stay closed")
+	assert.Equal(t, Closed, b.State("api"))
+}
+
+func TestBreakers_Disabled(t *testing.T) {
+	b := New(Options{RecoveryInterval: 10 * time.Second})
+	start := time.Now()
+	b.Record("api", true, start)
+	_, changed := b.Record("api", true, start.Add(24*time.Hour))
+	assert.False(t, changed, "circuits shouldn't open without OpenAfter")
+	assert.Equal(t, 10*time.Second, b.Interval("api", time.Minute))
+	assert.Equal(t, 5*time.Second, b.Interval("api", 5*time.Second), "the recovery interval shouldn't slow checks down")
+
+	b.Remove("api")
+	assert.Equal(t, time.Minute, b.Interval("api", time.Minute))
+}
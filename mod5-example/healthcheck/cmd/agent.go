@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/fleet"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/scheduler"
+	"github.com/spf13/cobra"
+)
+
+var (
+	collectorURL string
+	agentName    string
+	refresh      time.Duration
+)
+
+const (
+	// maxUnreported caps the results an agent keeps while the collector
+	// can't be reached. The oldest are dropped first.
+	maxUnreported = 10000
+	// assignmentJob and reportJob are the names of an agent's own jobs.
+	// Check jobs are named by checkJob.
+	assignmentJob = "assignment"
+	reportJob     = "report"
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Run the checks a collector assigns and report their results to it",
+	Long: `Asks the "healthcheck collector" at --collector which targets to check every
+--refresh, checks them on their own schedules and reports the results in
+batches. Results are kept and sent later while the collector can't be
+reached. Run agents on machines in different networks so the collector can
+tell a target that is down apart from an agent that can't reach it.`,
+	Example: `  HEALTHCHECK_AGENT_TOKEN=secret healthcheck agent --collector https://collector.example.com:9200 --name eu-west`,
+	Args:    cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if u, err := url.Parse(collectorURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("--collector must be an http or https url")
+		}
+		if agentName == "" {
+			name, err := os.Hostname()
+			if err != nil {
+				return fmt.Errorf("--name is required: %w", err)
+			}
+			agentName = name
+		}
+		if refresh <= 0 {
+			return fmt.Errorf("--refresh must be greater than zero")
+		}
+		return requireAgentToken()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a := newAgent(&fleet.Client{URL: collectorURL, Agent: agentName, Token: agentToken})
+		l.InfoContext(cmd.Context(), "agent started", "agent", agentName, "collector", collectorURL)
+		a.run(cmd.Context())
+		return nil
+	},
+}
+
+func init() {
+	agentCmd.Flags().StringVar(&collectorURL, "collector", "", "URL of the collector to report to")
+	agentCmd.Flags().StringVar(&agentName, "name", "", "Name of the agent, which the collector assigns targets by (default the hostname)")
+	agentCmd.Flags().StringVar(&agentToken, "agent-token", "", "Bearer token to authenticate with (default $"+agentTokenEnv+")")
+	agentCmd.Flags().DurationVar(&refresh, "refresh", 15*time.Second, "How often to fetch the assignment, which also tells the collector the agent is alive")
+	rootCmd.AddCommand(agentCmd)
+}
+
+// agent runs the checks the collector assigns it.
+type agent struct {
+	client *fleet.Client
+	sched  *scheduler.Scheduler
+
+	mu      sync.Mutex
+	targets map[string]fleet.Target
+	every   time.Duration
+	pending []fleet.Result
+}
+
+func newAgent(client *fleet.Client) *agent {
+	a := &agent{
+		client:  client,
+		sched:   scheduler.New(jitter),
+		targets: make(map[string]fleet.Target),
+	}
+	a.sched.Add(scheduler.Job{Name: assignmentJob, Interval: refresh, Run: a.refresh})
+	return a
+}
+
+// run checks the assigned targets until ctx is done, then reports the last
+// results.
+func (a *agent) run(ctx context.Context) {
+	a.sched.Run(ctx)
+	flush, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	a.report(flush)
+}
+
+// refresh fetches the assignment and schedules its changes. The current
+// targets are kept if the collector can't be reached.
+func (a *agent) refresh(ctx context.Context) {
+	as, err := a.client.Assignment(ctx)
+	if err != nil {
+		l.WarnContext(ctx, "failed to fetch assignment", "collector", a.client.URL, "err", err)
+		return
+	}
+	if err := a.assign(as); err != nil {
+		l.WarnContext(ctx, "failed to apply assignment", "err", err)
+	}
+}
+
+// assign replaces the scheduled checks with those of as.
+func (a *agent) assign(as fleet.Assignment) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if as.ReportInterval > 0 && as.ReportInterval != a.every {
+		a.sched.Remove(reportJob)
+		a.sched.Add(scheduler.Job{Name: reportJob, Interval: as.ReportInterval, Run: a.report})
+		a.every = as.ReportInterval
+	}
+	assigned := make(map[string]fleet.Target, len(as.Targets))
+	for _, t := range as.Targets {
+		assigned[t.Name] = t
+	}
+	for name, t := range a.targets {
+		if next, ok := assigned[name]; !ok || !sameTarget(t, next) {
+			a.sched.Remove(checkJob(name))
+			delete(a.targets, name)
+			l.Info("target unassigned", "target", name)
+		}
+	}
+	var errs []error
+	for name, t := range assigned {
+		if _, ok := a.targets[name]; ok {
+			continue
+		}
+		job, err := a.checkJob(t)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		a.sched.Add(job)
+		a.targets[name] = t
+		l.Info("target assigned", "target", name, "url", t.URL)
+	}
+	return errors.Join(errs...)
+}
+
+func sameTarget(a, b fleet.Target) bool {
+	return a.URL == b.URL && a.Interval == b.Interval && a.Cron == b.Cron
+}
+
+// checkJob returns the job checking t and buffering its results.
+func (a *agent) checkJob(t fleet.Target) (scheduler.Job, error) {
+	job := scheduler.Job{
+		Name:     checkJob(t.Name),
+		Interval: t.Interval,
+		Run: func(ctx context.Context) {
+			r := runCheck(ctx, t.URL, threshold, retries)
+			if ctx.Err() != nil {
+				// The agent is stopping or the target was unassigned.
+				return
+			}
+			res := fleet.Result{
+				Target:     t.Name,
+				URL:        t.URL,
+				Time:       r.CheckedAt,
+				Up:         r.Up,
+				StatusCode: r.StatusCode,
+				Latency:    r.Latency,
+			}
+			if r.Err != nil {
+				res.Err = r.Err.Error()
+			} else if !r.Up {
+				res.Err = fmt.Sprintf("unexpected status code %d", r.StatusCode)
+			}
+			a.mu.Lock()
+			a.pending = append(a.pending, res)
+			if n := len(a.pending) - maxUnreported; n > 0 {
+				a.pending = a.pending[n:]
+			}
+			a.mu.Unlock()
+		},
+	}
+	if job.Interval <= 0 {
+		job.Interval = interval
+	}
+	if t.Cron != "" {
+		c, err := scheduler.ParseCron(t.Cron)
+		if err != nil {
+			return job, fmt.Errorf("target %q: %w", t.Name, err)
+		}
+		job.Cron = c
+	}
+	return job, nil
+}
+
+// report sends the buffered results to the collector. They are kept for
+// the next report if it can't be reached.
+func (a *agent) report(ctx context.Context) {
+	a.mu.Lock()
+	batch := a.pending
+	a.pending = nil
+	a.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+	if err := a.client.Report(ctx, batch); err != nil {
+		l.WarnContext(ctx, "failed to report results", "collector", a.client.URL, "results", len(batch), "err", err)
+		a.mu.Lock()
+		a.pending = append(batch, a.pending...)
+		if n := len(a.pending) - maxUnreported; n > 0 {
+			a.pending = a.pending[n:]
+		}
+		a.mu.Unlock()
+	}
+}
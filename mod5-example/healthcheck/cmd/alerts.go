@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/alert"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/scheduler"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// escalationInterval is how often monitor looks for incidents due to
+// escalate.
+const escalationInterval = 30 * time.Second
+
+var (
+	listAll bool
+	ackBy   string
+)
+
+var alertsCmd = &cobra.Command{
+	Use:   "alerts",
+	Short: "List and acknowledge alert incidents",
+	Long: `Every alert sent by monitor opens an incident, which stays open until the
+target is up again. Acknowledging an incident stops its escalation policy
+from paging anyone else. Incidents are kept in the --incidents file, so they
+survive restarts of monitor.`,
+}
+
+var alertsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List open incidents and their age",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, err := alert.NewIncidents(incidents).List()
+		if err != nil {
+			return err
+		}
+		var shown []alert.Incident
+		for _, inc := range all {
+			if listAll || inc.Open() {
+				shown = append(shown, inc)
+			}
+		}
+		return printIncidents(cmd.OutOrStdout(), shown, time.Now())
+	},
+}
+
+var alertsAckCmd = &cobra.Command{
+	Use:   "ack <id>",
+	Short: "Acknowledge an incident to stop its escalation",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inc, err := alert.NewIncidents(incidents).Ack(args[0], ackBy, time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Acknowledged incident %s for %s (%s)\n", inc.ID, inc.Target, inc.State)
+		return nil
+	},
+}
+
+func init() {
+	alertsListCmd.Flags().BoolVar(&listAll, "all", false, "Include incidents resolved in the last week")
+	alertsAckCmd.Flags().StringVar(&ackBy, "by", os.Getenv("USER"), "Who is acknowledging the incident")
+	alertsCmd.AddCommand(alertsListCmd, alertsAckCmd)
+	rootCmd.AddCommand(alertsCmd)
+}
+
+// addEscalation periodically escalates unacknowledged incidents, if any
+// alert rule has an escalation policy. Incidents of the targets found by
+// target that are in a maintenance window don't escalate.
+func addEscalation(sched *scheduler.Scheduler, obs *observer, target func(name string) (config.Target, bool)) {
+	if obs.notifier == nil || !obs.notifier.Escalates() {
+		return
+	}
+	obs.notifier.Silenced = func(name string) bool {
+		t, ok := target(name)
+		return ok && obs.maintenance(t) != nil
+	}
+	sched.Add(scheduler.Job{
+		Name:       "escalation",
+		Background: true,
+		Interval:   escalationInterval,
+		Run: func(ctx context.Context) {
+			obs.notifier.Escalate(ctx, time.Now())
+		},
+	})
+}
+
+func printIncidents(w io.Writer, list []alert.Incident, now time.Time) error {
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+	if len(list) == 0 {
+		fmt.Fprintln(w, "No open incidents.")
+		return nil
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"ID", "Target", "State", "Rule", "Age", "Step", "Acked"})
+	for _, inc := range list {
+		end, state := now, string(inc.State)
+		if !inc.Open() {
+			end, state = inc.Resolved, "resolved"
+		}
+		acked := "no"
+		if inc.Acknowledged() {
+			acked = "by " + inc.AckedBy
+			if inc.AckedBy == "" {
+				acked = "yes"
+			}
+		}
+		table.Append([]string{inc.ID, inc.Target, state, inc.Rule, end.Sub(inc.Opened).Round(time.Second).String(), fmt.Sprint(inc.Step), acked})
+	}
+	table.Render()
+	return nil
+}
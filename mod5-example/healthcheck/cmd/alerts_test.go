@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/alert"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/maintenance"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncidents_ResolvedWithoutAlert(t *testing.T) {
+	tests := []struct {
+		name    string
+		resolve func(sess *session)
+	}{
+		{"Recovered during maintenance", func(sess *session) {
+			api, _ := sess.target("api")
+			sess.obs.observe(context.Background(), api, checkResult{URL: api.URL, CheckedAt: time.Now(), Up: true, StatusCode: http.StatusOK}, &maintenance.Window{ID: "deploy"})
+		}},
+		{"Removed", func(sess *session) {
+			sess.remove("api")
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var alerts atomic.Int32
+			hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { alerts.Add(1) }))
+			defer hook.Close()
+			_, sess := newTestAPI(t)
+			n, err := alert.New(config.Alerts{
+				Webhooks: []config.Webhook{{Name: "hook", URL: hook.URL}},
+				Rules:    []config.AlertRule{{Name: "outages", On: []string{"down", "up"}, Webhooks: []string{"hook"}}},
+			}, l)
+			assert.NoError(t, err)
+			n.Incidents = alert.NewIncidents(filepath.Join(t.TempDir(), "incidents.json"))
+			sess.obs.notifier = n
+
+			api, _ := sess.target("api")
+			sess.obs.observe(context.Background(), api, checkResult{URL: api.URL, CheckedAt: time.Now(), Err: errors.New("connection refused")}, nil)
+			tc.resolve(sess)
+			n.Wait()
+
+			assert.EqualValues(t, 1, alerts.Load(), "only the outage is alerted")
+			incidents, err := n.Incidents.List()
+			assert.NoError(t, err)
+			if assert.Len(t, incidents, 1) {
+				assert.False(t, incidents[0].Open())
+			}
+		})
+	}
+}
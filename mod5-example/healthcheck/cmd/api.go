@@ -0,0 +1,352 @@
+package cmd
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/alert"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+)
+
+var (
+	listenAddr string
+	apiToken   string
+	tlsCert    string
+	tlsKey     string
+)
+
+// apiTokenEnv is the environment variable the API token is read from when
+// --api-token isn't set, which keeps it out of the process list.
+const apiTokenEnv = "HEALTHCHECK_API_TOKEN"
+
+// validateAPIFlags checks the --listen and TLS settings before monitor
+// starts.
+func validateAPIFlags() error {
+	if (tlsCert == "") != (tlsKey == "") {
+		return errors.New("--tls-cert and --tls-key must be set together")
+	}
+	if listenAddr == "" {
+		return nil
+	}
+	if apiToken == "" {
+		apiToken = os.Getenv(apiTokenEnv)
+	}
+	if apiToken == "" {
+		return fmt.Errorf("--listen requires a token, set with --api-token or %s", apiTokenEnv)
+	}
+	return nil
+}
+
+// targetStatus is a target as served by the API.
+type targetStatus struct {
+	Name     string            `json:"name"`
+	URL      string            `json:"url"`
+	Interval string            `json:"interval,omitempty"`
+	Cron     string            `json:"cron,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	Paused   bool              `json:"paused"`
+	State    status.State      `json:"state"`
+	// Last is the latest check, if the target has been checked yet.
+	Last *store.Result `json:"last,omitempty"`
+}
+
+// targetRequest is the body of a request to add a target.
+type targetRequest struct {
+	Name     string            `json:"name"`
+	URL      string            `json:"url"`
+	Interval string            `json:"interval"`
+	Cron     string            `json:"cron"`
+	Tags     map[string]string `json:"tags"`
+}
+
+// api serves the state of a monitor session over HTTP and lets clients
+// change its targets.
+type api struct {
+	sess  *session
+	token string
+}
+
+// startAPI serves the API on --listen until ctx is done.
+func startAPI(ctx context.Context, sess *session) error {
+	if listenAddr == "" {
+		return nil
+	}
+	return serve(ctx, "api", listenAddr, (&api{sess: sess, token: apiToken}).handler())
+}
+
+// serve serves h on addr in the background until ctx is done, over TLS
+// when --tls-cert is set. Requests in flight, such as event streams, are
+// cancelled along with ctx.
+func serve(ctx context.Context, name, addr string, h http.Handler) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %w", addr, err)
+	}
+	srv := &http.Server{
+		Handler:           h,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	go func() {
+		var err error
+		if tlsCert != "" {
+			err = srv.ServeTLS(ln, tlsCert, tlsKey)
+		} else {
+			err = srv.Serve(ln)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			l.ErrorContext(ctx, name+" server failed", "addr", addr, "err", err)
+		}
+	}()
+	l.InfoContext(ctx, name+" listening", "addr", ln.Addr().String(), "tls", tlsCert != "")
+	return nil
+}
+
+func (a *api) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/targets", a.listTargets)
+	mux.HandleFunc("POST /api/v1/targets", a.addTarget)
+	mux.HandleFunc("GET /api/v1/targets/{name}", a.getTarget)
+	mux.HandleFunc("DELETE /api/v1/targets/{name}", a.removeTarget)
+	mux.HandleFunc("POST /api/v1/targets/{name}/pause", a.pauseTarget(true))
+	mux.HandleFunc("POST /api/v1/targets/{name}/resume", a.pauseTarget(false))
+	mux.HandleFunc("GET /api/v1/results", a.listResults)
+	mux.HandleFunc("GET /api/v1/incidents", a.listIncidents)
+	return a.authenticate(mux)
+}
+
+// authenticate rejects requests without the bearer token.
+func (a *api) authenticate(next http.Handler) http.Handler {
+	return requireToken(a.token, next)
+}
+
+// requireToken rejects requests to next without the bearer token.
+func requireToken(want string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(want)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="healthcheck"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// statuses returns the status of every target of s, in target order.
+func (s *session) statuses() []targetStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]targetStatus, 0, len(s.targets))
+	for _, t := range s.targets {
+		statuses = append(statuses, s.targetStatus(t))
+	}
+	return statuses
+}
+
+// status returns the status of the target called name, if it exists.
+func (s *session) status(name string) (targetStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.byName[name]; ok {
+		return s.targetStatus(t), true
+	}
+	return targetStatus{}, false
+}
+
+// targetStatus returns the status of t. s.mu must be held.
+func (s *session) targetStatus(t config.Target) targetStatus {
+	ts := targetStatus{
+		Name:   t.Name,
+		URL:    t.URL,
+		Cron:   t.Cron,
+		Tags:   t.Tags,
+		Paused: s.paused[t.Name],
+		State:  s.obs.tracker.State(t.Name),
+	}
+	if t.Cron == "" {
+		ts.Interval = t.Interval.String()
+	}
+	if r, ok := s.latest[t.Name]; ok {
+		res := storeResult(t, r, nil)
+		res.State = r.State
+		ts.Last = &res
+	}
+	return ts
+}
+
+func (a *api) listTargets(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.sess.statuses())
+}
+
+func (a *api) getTarget(w http.ResponseWriter, r *http.Request) {
+	a.writeTarget(w, http.StatusOK, r.PathValue("name"))
+}
+
+func (a *api) writeTarget(w http.ResponseWriter, code int, name string) {
+	if ts, ok := a.sess.status(name); ok {
+		writeJSON(w, code, ts)
+		return
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("no target named %q", name))
+}
+
+func (a *api) addTarget(w http.ResponseWriter, r *http.Request) {
+	var req targetRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid target: %w", err))
+		return
+	}
+	if err := isValidURL(req.URL); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	t := config.Target{Name: req.Name, URL: req.URL, Cron: req.Cron, Tags: req.Tags}
+	if t.Name == "" {
+		t.Name = t.URL
+	}
+	if req.Interval != "" {
+		d, err := time.ParseDuration(req.Interval)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid interval %q", req.Interval))
+			return
+		}
+		t.Interval = d
+	}
+	if err := a.sess.add(t); err != nil {
+		code := http.StatusBadRequest
+		if errors.Is(err, errDuplicateTarget) {
+			code = http.StatusConflict
+		}
+		writeError(w, code, err)
+		return
+	}
+	l.InfoContext(r.Context(), "target added", "target", t.Name, "url", t.URL, "remote", r.RemoteAddr)
+	a.writeTarget(w, http.StatusCreated, t.Name)
+}
+
+func (a *api) removeTarget(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !a.sess.remove(name) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no target named %q", name))
+		return
+	}
+	l.InfoContext(r.Context(), "target removed", "target", name, "remote", r.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *api) pauseTarget(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if !a.sess.pause(name, paused) {
+			writeError(w, http.StatusNotFound, fmt.Errorf("no target named %q", name))
+			return
+		}
+		l.InfoContext(r.Context(), "target paused", "target", name, "paused", paused, "remote", r.RemoteAddr)
+		a.writeTarget(w, http.StatusOK, name)
+	}
+}
+
+// listResults serves the stored results of the last hour, or since the
+// RFC 3339 time in ?since, optionally for a single ?target and at most
+// ?limit of the latest ones.
+func (a *api) listResults(w http.ResponseWriter, r *http.Request) {
+	q := store.Query{From: time.Now().Add(-time.Hour)}
+	if since := r.URL.Query().Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid since %q, expected RFC 3339", since))
+			return
+		}
+		q.From = t
+	}
+	if name := r.URL.Query().Get("target"); name != "" {
+		s := a.sess
+		s.mu.Lock()
+		t, ok := s.byName[name]
+		if ok {
+			q.URLs = []string{t.URL}
+		}
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("no target named %q", name))
+			return
+		}
+	}
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", v))
+			return
+		}
+		limit = n
+	}
+
+	rec := a.sess.obs.recorder
+	if rec == nil {
+		writeJSON(w, http.StatusOK, []store.Result{})
+		return
+	}
+	rec.flush(r.Context())
+	results, err := rec.st.Query(q)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if limit > 0 && len(results) > limit {
+		results = results[len(results)-limit:]
+	}
+	if results == nil {
+		results = []store.Result{}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// listIncidents serves the open incidents, or all recent ones with ?all.
+func (a *api) listIncidents(w http.ResponseWriter, r *http.Request) {
+	incidents, err := alert.NewIncidents(incidents).List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	all := r.URL.Query().Has("all")
+	list := []alert.Incident{}
+	for _, inc := range incidents {
+		if all || inc.Open() {
+			list = append(list, inc)
+		}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/maintenance"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestAPI(t *testing.T) (*httptest.Server, *session) {
+	l = slog.New(slog.NewTextHandler(io.Discard, nil))
+	file := storeFile
+	storeFile = filepath.Join(t.TempDir(), "healthcheck.db")
+	t.Cleanup(func() { storeFile = file })
+	st, err := openStore()
+	assert.NoError(t, err)
+	obs := &observer{
+		tracker:  status.NewTracker(status.Options{}),
+		windows:  maintenance.NewRegistry(nil, ""),
+		recorder: &recorder{st: st},
+	}
+	sess, err := newSession([]config.Target{{Name: "api", URL: "http://api.test", Interval: time.Minute}}, obs, func(config.Target, checkResult) {})
+	assert.NoError(t, err)
+	srv := httptest.NewServer((&api{sess: sess, token: "secret"}).handler())
+	t.Cleanup(srv.Close)
+	return srv, sess
+}
+
+func apiRequest(t *testing.T, srv *httptest.Server, method, path, body string) (*http.Response, string) {
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := srv.Client().Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func TestAPI_Unauthorized(t *testing.T) {
+	srv, _ := newTestAPI(t)
+	for _, header := range []string{"", "Bearer wrong", "secret"} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/targets", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := srv.Client().Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, header)
+	}
+}
+
+func TestAPI_Targets(t *testing.T) {
+	srv, sess := newTestAPI(t)
+	sess.mu.Lock()
+	sess.latest["api"] = checkResult{URL: "http://api.test", Up: true, StatusCode: 200, Latency: time.Millisecond, CheckedAt: time.Now(), State: status.Up}
+	sess.mu.Unlock()
+
+	resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/targets", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var targets []targetStatus
+	assert.NoError(t, json.Unmarshal([]byte(body), &targets))
+	if assert.Len(t, targets, 1) && assert.NotNil(t, targets[0].Last) {
+		assert.Equal(t, "1m0s", targets[0].Interval)
+		assert.Equal(t, 200, targets[0].Last.StatusCode)
+		assert.Equal(t, status.Up, targets[0].Last.State)
+	}
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/targets", `{"url": "https://new.test/health", "interval": "30s", "tags": {"team": "web"}}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, body)
+	assert.Contains(t, body, `"name": "https://new.test/health"`)
+	assert.Contains(t, body, `"interval": "30s"`)
+
+	resp, _ = apiRequest(t, srv, http.MethodPost, "/api/v1/targets", `{"url": "https://new.test/health"}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp, _ = apiRequest(t, srv, http.MethodPost, "/api/v1/targets", `{"url": "new.test"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = apiRequest(t, srv, http.MethodPost, "/api/v1/targets", `{"url": "https://other.test", "interval": "soon"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	name := "/api/v1/targets/" + url.PathEscape("https://new.test/health")
+	resp, body = apiRequest(t, srv, http.MethodPost, name+"/pause", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `"paused": true`)
+	assert.True(t, sess.isPaused("https://new.test/health"))
+	resp, body = apiRequest(t, srv, http.MethodPost, name+"/resume", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `"paused": false`)
+
+	resp, _ = apiRequest(t, srv, http.MethodDelete, name, "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, _ = apiRequest(t, srv, http.MethodGet, name, "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = apiRequest(t, srv, http.MethodDelete, name, "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Len(t, sess.results(), 1)
+}
+
+func TestAPI_Results(t *testing.T) {
+	srv, sess := newTestAPI(t)
+	now := time.Now()
+	sess.obs.recorder.record(store.Result{Time: now.Add(-2 * time.Hour), URL: "http://api.test", Up: true, State: status.Up})
+	sess.obs.recorder.record(store.Result{Time: now.Add(-time.Minute), URL: "http://api.test", Up: true, State: status.Up})
+	sess.obs.recorder.record(store.Result{Time: now, URL: "http://api.test", State: status.Down, Err: "timeout"})
+
+	resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/results?target=api", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var results []store.Result
+	assert.NoError(t, json.Unmarshal([]byte(body), &results))
+	assert.Len(t, results, 2, "the last hour by default")
+
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/results?target=api&limit=1&since="+url.QueryEscape(now.Add(-3*time.Hour).Format(time.RFC3339)), "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NoError(t, json.Unmarshal([]byte(body), &results))
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "timeout", results[0].Err)
+	}
+
+	resp, _ = apiRequest(t, srv, http.MethodGet, "/api/v1/results?target=missing", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = apiRequest(t, srv, http.MethodGet, "/api/v1/results?since=yesterday", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAPI_Incidents(t *testing.T) {
+	srv, _ := newTestAPI(t)
+	resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/incidents", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, "[]", body)
+}
+
+func TestValidateAPIFlags(t *testing.T) {
+	defer func() { listenAddr, apiToken, tlsCert, tlsKey = "", "", "", "" }()
+	t.Setenv(apiTokenEnv, "")
+	listenAddr = "localhost:0"
+	assert.ErrorContains(t, validateAPIFlags(), "requires a token")
+
+	t.Setenv(apiTokenEnv, "from-env")
+	assert.NoError(t, validateAPIFlags())
+	assert.Equal(t, "from-env", apiToken)
+
+	tlsCert = "cert.pem"
+	assert.ErrorContains(t, validateAPIFlags(), "must be set together")
+}
+
+func TestStartAPI(t *testing.T) {
+	defer func() { listenAddr, apiToken = "", "" }()
+	_, sess := newTestAPI(t)
+	listenAddr, apiToken = "127.0.0.1:0", "secret"
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, startAPI(ctx, sess))
+
+	listenAddr = "not an address"
+	assert.Error(t, startAPI(ctx, sess))
+}
+
+func TestSession_Runtime(t *testing.T) {
+	httpmock.ActivateNonDefault(checkClient)
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(http.MethodGet, "http://api.test", httpmock.NewStringResponder(200, "OK"))
+	httpmock.RegisterResponder(http.MethodGet, "http://new.test", httpmock.NewStringResponder(200, "OK"))
+
+	_, sess := newTestAPI(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sess.sched.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	assert.Eventually(t, func() bool { return len(sess.results()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.NoError(t, sess.add(config.Target{Name: "new", URL: "http://new.test", Interval: 10 * time.Millisecond}))
+	assert.ErrorIs(t, sess.add(config.Target{Name: "new", URL: "http://new.test"}), errDuplicateTarget)
+	assert.Eventually(t, func() bool { return len(sess.results()) == 2 }, time.Second, 5*time.Millisecond)
+
+	assert.True(t, sess.pause("new", true))
+	time.Sleep(20 * time.Millisecond)
+	calls := httpmock.GetCallCountInfo()["GET http://new.test"]
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, calls, httpmock.GetCallCountInfo()["GET http://new.test"], "paused targets aren't checked")
+
+	assert.True(t, sess.remove("new"))
+	assert.False(t, sess.pause("new", false))
+	assert.Len(t, sess.results(), 1)
+}
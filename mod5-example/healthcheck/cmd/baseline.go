@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/baseline"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/scheduler"
+)
+
+var (
+	anomalies          bool
+	anomalySensitivity float64
+	baselineFile       string
+)
+
+// baselineInterval is how often monitor saves the learned baselines, so
+// little is lost if it doesn't exit cleanly.
+const baselineInterval = 5 * time.Minute
+
+// loadBaselines returns the baselines saved in --baseline.
+func loadBaselines() (*baseline.Baselines, error) {
+	return baseline.Load(baselineFile, baseline.Options{Sensitivity: anomalySensitivity})
+}
+
+// saveBaselines writes the baselines learned by obs to --baseline.
+func saveBaselines(ctx context.Context, obs *observer) {
+	if err := obs.baselines.Save(baselineFile); err != nil {
+		l.WarnContext(ctx, "failed to save baselines", "err", err)
+	}
+}
+
+// addBaselineJob schedules saving the learned baselines.
+func addBaselineJob(sched *scheduler.Scheduler, obs *observer) {
+	if obs.baselines == nil {
+		return
+	}
+	sched.Add(scheduler.Job{
+		Name:       "baseline",
+		Background: true,
+		Interval:   baselineInterval,
+		Run: func(ctx context.Context) {
+			saveBaselines(ctx, obs)
+		},
+	})
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/baseline"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/maintenance"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserve_Anomaly(t *testing.T) {
+	var logs bytes.Buffer
+	l = slog.New(slog.NewTextHandler(&logs, nil))
+	defer func(file string) { baselineFile = file }(baselineFile)
+	baselineFile = filepath.Join(t.TempDir(), "healthcheck.baseline.json")
+	obs := &observer{tracker: status.NewTracker(status.Options{}), baselines: baseline.New(baseline.Options{})}
+	target := config.Target{Name: "api", URL: "http://api"}
+	now := time.Now()
+	check := func(latency time.Duration, w *maintenance.Window) status.State {
+		now = now.Add(time.Second)
+		return obs.observe(context.Background(), target, checkResult{URL: target.URL, Up: true, Latency: latency, CheckedAt: now}, w)
+	}
+
+	for i := 0; i < 200; i++ {
+		assert.Equal(t, status.Up, check(time.Duration(80+i%20)*time.Millisecond, nil))
+	}
+	state := status.Up
+	for i := 0; i < 20; i++ {
+		state = check(420*time.Millisecond, &maintenance.Window{ID: "deploy"})
+	}
+	assert.Equal(t, status.Up, state, "checks during maintenance are not judged")
+	for i := 0; i < 20; i++ {
+		state = check(420*time.Millisecond, nil)
+	}
+	assert.Equal(t, status.Degraded, state)
+	assert.Contains(t, logs.String(), `reason="p95 420ms vs baseline`)
+
+	obs.wait()
+	assert.FileExists(t, baselineFile, "the baselines are saved on exit")
+	_, err := loadBaselines()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/breaker"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+)
+
+var (
+	recoveryInterval  time.Duration
+	breakerAfter      time.Duration
+	breakerBackoff    time.Duration
+	breakerMaxBackoff time.Duration
+)
+
+// newBreakers returns the circuit breakers of a monitor session, set up by
+// the --recovery-interval and --breaker-* flags.
+func newBreakers() *breaker.Breakers {
+	return breaker.New(breaker.Options{
+		RecoveryInterval: recoveryInterval,
+		OpenAfter:        breakerAfter,
+		Backoff:          breakerBackoff,
+		MaxBackoff:       breakerMaxBackoff,
+	})
+}
+
+func validateBreakerFlags() error {
+	if recoveryInterval < 0 || breakerAfter < 0 {
+		return errors.New("--recovery-interval and --breaker-after can't be negative")
+	}
+	if breakerAfter > 0 && breakerBackoff <= 0 {
+		return errors.New("--breaker-backoff must be greater than zero")
+	}
+	return nil
+}
+
+// isDownState reports whether s counts as down for the circuit breaker.
+// Unreachable targets are down too, so their circuit opens once their
+// parent has been down for long, but session.nextInterval doesn't check
+// them more often meanwhile.
+func isDownState(s status.State) bool {
+	return s == status.Down || s == status.Unreachable
+}
+
+func logCircuit(ctx context.Context, t config.Target, tr breaker.Transition) {
+	l.InfoContext(ctx, "circuit changed", "target", t.Name, "url", t.URL, "from", tr.From, "to", tr.To, "reason", tr.Reason)
+}
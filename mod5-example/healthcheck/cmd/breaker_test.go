@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/breaker"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession_Breaker(t *testing.T) {
+	defer func(r int, after, backoff time.Duration) {
+		retries, breakerAfter, breakerBackoff = r, after, backoff
+	}(retries, breakerAfter, breakerBackoff)
+	retries, breakerAfter, breakerBackoff = 0, time.Nanosecond, time.Minute
+	var hits atomic.Int32
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	_, sess := newTestAPI(t)
+	target := config.Target{Name: "backend", URL: srv.URL, Interval: time.Minute}
+	assert.NoError(t, sess.add(target))
+	target, _ = sess.target("backend")
+	ctx := context.Background()
+	circuit := func() breaker.State {
+		for _, r := range sess.results() {
+			if r.URL == srv.URL {
+				return r.Circuit
+			}
+		}
+		return ""
+	}
+
+	sess.check(ctx, target)
+	assert.Equal(t, breaker.Closed, circuit())
+	assert.Equal(t, 15*time.Second, sess.breakers.Interval("backend", target.Interval), "down targets should be checked more often")
+
+	sess.check(ctx, target)
+	assert.Equal(t, breaker.Open, circuit())
+	assert.Equal(t, time.Minute, sess.breakers.Interval("backend", target.Interval), "open circuits should back off")
+
+	retries = 1
+	hits.Store(0)
+	sess.check(ctx, target)
+	assert.EqualValues(t, 1, hits.Load(), "half-open checks shouldn't be retried")
+	assert.Equal(t, breaker.Open, circuit())
+	assert.Equal(t, 2*time.Minute, sess.breakers.Interval("backend", target.Interval))
+
+	healthy.Store(true)
+	sess.check(ctx, target)
+	assert.Equal(t, breaker.Closed, circuit())
+	assert.Equal(t, time.Minute, sess.breakers.Interval("backend", target.Interval))
+}
+
+func TestSession_BreakerUnreachable(t *testing.T) {
+	defer func(after, backoff time.Duration) {
+		breakerAfter, breakerBackoff = after, backoff
+	}(breakerAfter, breakerBackoff)
+	breakerAfter, breakerBackoff = time.Minute, 5*time.Minute
+	_, sess := newTestAPI(t)
+	lb := config.Target{Name: "lb", URL: "http://lb.test", Interval: time.Minute}
+	child := config.Target{Name: "child", URL: "http://child.test", Interval: time.Minute, DependsOn: []string{"lb"}}
+	start := time.Now()
+
+	tests := []struct {
+		name     string
+		target   config.Target
+		state    status.State
+		at       time.Time
+		expected time.Duration
+	}{
+		{"Down", lb, status.Down, start, 15 * time.Second},
+		{"Unreachable", child, status.Unreachable, start, time.Minute},
+		{"Unreachable for long", child, status.Unreachable, start.Add(time.Minute), 5 * time.Minute},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sess.obs.tracker.Observe(tc.target.Name, tc.state, tc.at, "")
+			sess.breakers.Record(tc.target.Name, isDownState(tc.state), tc.at)
+			assert.Equal(t, tc.expected, sess.nextInterval(tc.target))
+		})
+	}
+}
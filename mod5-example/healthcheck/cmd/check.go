@@ -5,19 +5,52 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/breaker"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/probe"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/tracing"
 	"github.com/spf13/cobra"
-
-	"github.com/briandowns/spinner"
-	"github.com/fatih/color"
-	"github.com/olekukonko/tablewriter"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// maxBody is how much of a response body a check reads, to time its
+// transfer.
+const maxBody = 1 << 20
+
+// requestTimeout bounds every attempt of a check, so a target that never
+// answers can't hold a monitor worker forever.
+var requestTimeout time.Duration
+
+// checkClient is shared by every check, so connections to a target are
+// pooled and kept alive between checks instead of being dialed anew each
+// time. Idle connections are capped in total as well as per host, so large
+// fleets don't run out of file descriptors.
+var checkClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          4096,
+		MaxIdleConnsPerHost:   64,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	},
+}
+
 var ExitFunction = os.Exit
 var outputWriter io.Writer = os.Stdout
 
@@ -37,27 +70,28 @@ var checkCmd = &cobra.Command{
 	//Args:  cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := cmd.Context()
-		if output == "table" {
-			// print table
-			table := tablewriter.NewWriter(outputWriter)
-			upColor := color.New(color.FgGreen).SprintFunc()
-			downColor := color.New(color.FgRed).SprintFunc()
-			table.SetHeader([]string{"URL", "Status"})
-			for _, url := range args {
-				status := downColor("Down")
-				if checkURL(ctx, url, threshold, retries) {
-					status = upColor("Up")
-				}
-				table.Append([]string{url, status})
+		results := make([]checkResult, 0, len(args))
+		for _, url := range args {
+			s := newProgress(url)
+			if !tableOutput() || silent {
+				s.Start()
 			}
-			table.Render()
-		} else {
-			for _, url := range args {
-				checkURL(ctx, url, threshold, retries)
+			results = append(results, runCheck(ctx, url, threshold, retries))
+			s.Stop()
+			if ctx.Err() != nil {
+				ExitFunction(1)
+				return
 			}
 		}
+		if tableOutput() {
+			renderTable(outputWriter, results, checkColumns)
+		}
+		recordChecks(ctx, results)
 	},
 	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateTableFlags(); err != nil {
+			return err
+		}
 		if len(args) == 0 {
 			reader := bufio.NewReader(os.Stdin)
 			fmt.Println("Enter URLs to check, one per line.  Press Enter twice to finish:")
@@ -91,64 +125,105 @@ func init() {
 	rootCmd.AddCommand(checkCmd)
 }
 
+type checkResult struct {
+	URL        string
+	Up         bool
+	StatusCode int
+	Latency    time.Duration
+	CertExpiry time.Time
+	Phases     probe.Phases
+	Attempts   int
+	CheckedAt  time.Time
+	Err        error
+	// State is the target's state after this check, as tracked by monitor.
+	// It is empty for one-off checks.
+	State status.State
+	// Circuit is the state of the target's circuit breaker after this
+	// check. It is empty for one-off checks and composite targets.
+	Circuit breaker.State
+}
+
 func checkURL(ctx context.Context, url string, threshold float64, retries int) bool {
-	var resp *http.Response
-	var lastError error
-	var duration time.Duration
+	return runCheck(ctx, url, threshold, retries).Up
+}
+
+func runCheck(ctx context.Context, url string, threshold float64, retries int) (result checkResult) {
+	result = checkResult{URL: url, CheckedAt: time.Now()}
+	ctx, span := tracing.Tracer().Start(ctx, "check", trace.WithAttributes(semconv.URLFull(url)))
+	defer func() {
+		span.SetAttributes(attribute.Bool("healthcheck.up", result.Up), attribute.Int("healthcheck.attempts", result.Attempts))
+		err := result.Err
+		if err == nil && !result.Up {
+			err = fmt.Errorf("unexpected status code %d", result.StatusCode)
+		}
+		tracing.End(span, err)
+	}()
 
-	s := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-	if output != "table" || silent {
-		s.Start()
-	}
 	for attempt := 0; attempt <= retries; attempt++ {
+		result.Attempts = attempt + 1
 		start := time.Now()
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-		if err != nil {
-			l.ErrorContext(ctx, "failed to create request", "url", url)
-			return false
+		actx, attemptSpan := tracing.Tracer().Start(ctx, http.MethodGet, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+			semconv.HTTPRequestMethodGet, semconv.URLFull(url), attribute.Int("healthcheck.attempt", attempt+1),
+		))
+		rctx, cancel := actx, context.CancelFunc(func() {})
+		if requestTimeout > 0 {
+			rctx, cancel = context.WithTimeout(actx, requestTimeout)
 		}
-
-		l.DebugContext(ctx, "request details", "method", req.Method, "url", req.URL, "headers", req.Header)
-
-		client := &http.Client{}
-		resp, err = client.Do(req)
-		s.Disable()
+		pt := &probe.Timer{}
+		req, err := http.NewRequestWithContext(httptrace.WithClientTrace(tracing.WithPhases(rctx), pt.Trace()), http.MethodGet, url, nil)
 		if err != nil {
-			l.ErrorContext(ctx, "failed to perform request", "url", url, "err", err)
-			return false
+			l.ErrorContext(actx, "failed to create request", "url", url)
+			cancel()
+			tracing.End(attemptSpan, err)
+			result.Err = err
+			return result
 		}
+		tracing.Inject(actx, req)
+
+		l.DebugContext(actx, "request details", "method", req.Method, "url", req.URL, "headers", req.Header)
 
-		duration = time.Since(start)
+		resp, err := checkClient.Do(req)
 		if err == nil {
-			defer resp.Body.Close()
-			if duration.Seconds() > threshold {
-				l.WarnContext(ctx, "exceeded threshold", "url", url, "responseTime", duration)
+			result.Latency = time.Since(start)
+			result.StatusCode = resp.StatusCode
+			if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+				result.CertExpiry = resp.TLS.PeerCertificates[0].NotAfter
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, maxBody))
+			resp.Body.Close()
+			cancel()
+			result.Phases = pt.Done()
+			tracing.EndResponse(attemptSpan, resp.StatusCode)
+			if result.Latency.Seconds() > threshold {
+				l.WarnContext(actx, "exceeded threshold", "url", url, "statusCode", resp.StatusCode, "responseTime", result.Latency)
 			} else {
-				l.InfoContext(ctx, "successful check", "url", url, "statusCode", resp.StatusCode, "duration", duration)
+				l.InfoContext(actx, "successful check", "url", url, "statusCode", resp.StatusCode, "duration", result.Latency)
 			}
+			result.Up = resp.StatusCode == http.StatusOK
+			result.Err = nil
+			return result
+		}
+
+		cancel()
+		l.ErrorContext(actx, "failed to perform request", "url", url, "attempt", attempt, "err", err)
+		tracing.End(attemptSpan, err)
+		result.Err = err
+		if attempt == retries {
 			break
 		}
 
 		select {
 		case <-ctx.Done():
 			l.ErrorContext(ctx, "check cancelled", "url", url, "attempt", attempt, "err", err)
-			ExitFunction(1)
+			result.Err = ctx.Err()
+			return result
 		case <-time.After(2 * time.Second):
+			span.AddEvent("backing off")
 			l.InfoContext(ctx, "backing off", "url", url)
 		}
-		lastError = err
-	}
-	if lastError != nil {
-		l.ErrorContext(ctx, "fetching error", "url", url, "retries", retries, "err", lastError)
-		return false
 	}
-
-	if resp.StatusCode == http.StatusOK {
-		return true
-	} else {
-		return false
-	}
-
+	l.ErrorContext(ctx, "fetching error", "url", url, "retries", retries, "err", result.Err)
+	return result
 }
 
 func isValidURL(u string) error {
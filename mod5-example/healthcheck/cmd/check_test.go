@@ -3,6 +3,7 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"errors"
 	"net/http"
 	"os"
 	"testing"
@@ -88,8 +89,8 @@ func TestIsValidURL(t *testing.T) {
 }
 
 func TestCheckURL(t *testing.T) {
-	setupTestLogger()
-	httpmock.Activate()
+	setupTestLogger(t)
+	httpmock.ActivateNonDefault(checkClient)
 	defer httpmock.DeactivateAndReset()
 
 	// Override the ExitFunction
@@ -106,7 +107,6 @@ func TestCheckURL(t *testing.T) {
 		retries           int
 		mockResp          httpmock.Responder
 		expected          bool
-		expectedExitCode  int
 		delayBetweenCalls time.Duration
 	}{
 		{
@@ -137,13 +137,12 @@ func TestCheckURL(t *testing.T) {
 			expected: true,
 		},
 		{
-			name:             "Failed request",
-			url:              "http://www.example.com",
-			threshold:        2.0,
-			retries:          1,
-			mockResp:         httpmock.NewErrorResponder(context.DeadlineExceeded),
-			expected:         false,
-			expectedExitCode: 1, //expected code when context is canceled
+			name:      "Failed request",
+			url:       "http://www.example.com",
+			threshold: 2.0,
+			retries:   1,
+			mockResp:  httpmock.NewErrorResponder(context.DeadlineExceeded),
+			expected:  false,
 		},
 	}
 
@@ -155,16 +154,14 @@ func TestCheckURL(t *testing.T) {
 			defer cancel()
 			actual := checkURL(ctx, tc.url, tc.threshold, tc.retries)
 			assert.Equal(t, tc.expected, actual)
-			if tc.expectedExitCode != 0 {
-				assert.Equal(t, tc.expectedExitCode, exitCode, "expected exit code does not match")
-			}
+			assert.Zero(t, exitCode, "checks shouldn't exit, even when cancelled")
 		})
 	}
 
 }
 
 func TestRun_OutputTable(t *testing.T) {
-	httpmock.Activate()
+	httpmock.ActivateNonDefault(checkClient)
 	defer httpmock.DeactivateAndReset()
 
 	httpmock.RegisterResponder(http.MethodGet, "http://example.com", httpmock.NewStringResponder(200, "OK"))
@@ -179,7 +176,7 @@ func TestRun_OutputTable(t *testing.T) {
 }
 
 func TestRun_MultipleURLs(t *testing.T) {
-	httpmock.Activate()
+	httpmock.ActivateNonDefault(checkClient)
 	defer httpmock.DeactivateAndReset()
 	httpmock.RegisterResponder(http.MethodGet, "http://example1.com", httpmock.NewStringResponder(200, "OK"))
 	httpmock.RegisterResponder(http.MethodGet, "http://example2.com", httpmock.NewStringResponder(200, "OK"))
@@ -187,3 +184,20 @@ func TestRun_MultipleURLs(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Contains(t, output, "successful check")
 }
+
+func TestRun_CheckCancelled(t *testing.T) {
+	httpmock.ActivateNonDefault(checkClient)
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(http.MethodGet, "http://example.com", httpmock.NewErrorResponder(errors.New("connection refused")))
+	defer func(exit func(int), r int) { ExitFunction, retries = exit, r }(ExitFunction, retries)
+	var exitCode int
+	ExitFunction = func(code int) { exitCode = code }
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	checkCmd.SetContext(ctx)
+	defer checkCmd.SetContext(context.Background())
+
+	_, err := executeCommandC(rootCmd, "check", "--output", "text", "--retries", "1", "http://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, exitCode, "an interrupted check command should exit")
+}
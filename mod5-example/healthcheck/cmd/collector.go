@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/fleet"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/scheduler"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/spf13/cobra"
+)
+
+var (
+	collectorAddr  string
+	agentToken     string
+	quorum         int
+	agentTimeout   time.Duration
+	reportInterval time.Duration
+)
+
+// agentTokenEnv is the environment variable the token shared by agents and
+// the collector is read from when --agent-token isn't set.
+const agentTokenEnv = "HEALTHCHECK_AGENT_TOKEN"
+
+var collectorCmd = &cobra.Command{
+	Use:   "collector [urls...]",
+	Short: "Assign checks to agents and decide target states from their results",
+	Long: `Serves the targets given as arguments or in the --config file to
+"healthcheck agent" processes, which check them and report back. A target is
+only considered down once --quorum agents see it fail, by default a majority
+of the agents checking it, so a problem with one agent's network doesn't
+page anyone. Targets with an agents list in the config file are only
+assigned to those agents.
+
+Agents authenticate with the bearer token from --agent-token or
+$HEALTHCHECK_AGENT_TOKEN. Agents not heard from within --agent-timeout no
+longer count. The agents' verdict on a target is taken once per interval of
+the target, stored and alerted on like the results of monitor, so
+--down-after and --up-after count rounds rather than agent reports.
+Composite targets of the config file are left to monitor.
+
+  GET  /api/v1/agents/{name}/assignment   the targets an agent checks
+  POST /api/v1/agents/{name}/results      results of an agent: {"results": [...]}
+  GET  /api/v1/status                     agents, and the verdict and agent results of every target`,
+	Example: `  HEALTHCHECK_AGENT_TOKEN=secret healthcheck collector --config targets.yaml --quorum 2
+  HEALTHCHECK_AGENT_TOKEN=secret healthcheck agent --collector http://collector:9200 --name eu-west`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		if len(args) == 0 && (cfg == nil || len(cfg.Targets) == 0) {
+			return fmt.Errorf("requires at least 1 url, either as an argument or in the --config file")
+		}
+		for _, arg := range args {
+			if err := isValidURL(arg); err != nil {
+				return err
+			}
+		}
+		if quorum < 0 {
+			return fmt.Errorf("--quorum can't be negative")
+		}
+		if agentTimeout <= 0 || reportInterval <= 0 || interval <= 0 {
+			return fmt.Errorf("--agent-timeout, --report-interval and --interval must be greater than zero")
+		}
+		if (tlsCert == "") != (tlsKey == "") {
+			return errors.New("--tls-cert and --tls-key must be set together")
+		}
+		return requireAgentToken()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		obs, err := newObserver()
+		if err != nil {
+			return err
+		}
+		defer obs.wait()
+		var targets []config.Target
+		for _, t := range resolveTargets(args) {
+			if t.Expr == "" {
+				targets = append(targets, t)
+			}
+		}
+		srv := &collectorServer{
+			fleet:          fleet.NewCollector(targets, quorum, agentTimeout),
+			obs:            obs,
+			token:          agentToken,
+			reportInterval: reportInterval,
+		}
+		if err := serve(ctx, "collector", collectorAddr, srv.handler()); err != nil {
+			return err
+		}
+		sched := scheduler.New(0)
+		if err := srv.addRounds(sched); err != nil {
+			return err
+		}
+		byName := make(map[string]config.Target, len(targets))
+		for _, t := range targets {
+			byName[t.Name] = t
+		}
+		addEscalation(sched, obs, func(name string) (config.Target, bool) {
+			t, ok := byName[name]
+			return t, ok
+		})
+		addStoreJobs(sched, obs)
+		sched.Run(ctx)
+		return nil
+	},
+}
+
+func init() {
+	collectorCmd.Flags().StringVar(&collectorAddr, "listen", ":9200", "Address to serve agents on")
+	collectorCmd.Flags().StringVar(&agentToken, "agent-token", "", "Bearer token agents authenticate with (default $"+agentTokenEnv+")")
+	collectorCmd.Flags().IntVar(&quorum, "quorum", 0, "Agents that must see a target fail for it to be down (default a majority of the agents checking it)")
+	collectorCmd.Flags().DurationVar(&agentTimeout, "agent-timeout", time.Minute, "How long after an agent was last heard from its results stop counting")
+	collectorCmd.Flags().DurationVar(&reportInterval, "report-interval", 5*time.Second, "How often agents send their results")
+	collectorCmd.Flags().DurationVar(&interval, "interval", 2*time.Second, "Interval between healthchecks, for targets that don't set their own")
+	collectorCmd.Flags().IntVar(&downAfter, "down-after", 1, "Consecutive down verdicts before a target is considered down")
+	collectorCmd.Flags().IntVar(&upAfter, "up-after", 1, "Consecutive up verdicts before a down target is considered up again")
+	collectorCmd.Flags().StringVar(&tlsCert, "tls-cert", "", "Certificate file to serve agents over TLS")
+	collectorCmd.Flags().StringVar(&tlsKey, "tls-key", "", "Private key file of --tls-cert")
+	rootCmd.AddCommand(collectorCmd)
+}
+
+// requireAgentToken reads the agent token from the environment unless
+// --agent-token is set.
+func requireAgentToken() error {
+	if agentToken == "" {
+		agentToken = os.Getenv(agentTokenEnv)
+	}
+	if agentToken == "" {
+		return fmt.Errorf("an agent token is required, set with --agent-token or %s", agentTokenEnv)
+	}
+	return nil
+}
+
+// collectorServer serves assignments to agents and turns their results into
+// target states.
+type collectorServer struct {
+	fleet          *fleet.Collector
+	obs            *observer
+	token          string
+	reportInterval time.Duration
+
+	mu sync.Mutex
+	// judged holds, by target, the time of the newest result the latest
+	// round observed.
+	judged map[string]time.Time
+}
+
+func (s *collectorServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/agents/{name}/assignment", s.assignment)
+	mux.HandleFunc("POST /api/v1/agents/{name}/results", s.results)
+	mux.HandleFunc("GET /api/v1/status", s.status)
+	return requireToken(s.token, mux)
+}
+
+func (s *collectorServer) assignment(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, fleet.Assignment{
+		Targets:        s.fleet.Assign(r.PathValue("name"), time.Now()),
+		ReportInterval: s.reportInterval,
+	})
+}
+
+func (s *collectorServer) results(w http.ResponseWriter, r *http.Request) {
+	agent := r.PathValue("name")
+	var report fleet.Report
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 10<<20)).Decode(&report); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid results: %w", err))
+		return
+	}
+	ctx := r.Context()
+	accepted := s.fleet.Record(agent, report.Results, time.Now())
+	for _, res := range accepted {
+		if !res.Up {
+			l.WarnContext(ctx, "check failed from one agent", "target", res.Target, "agent", agent, "err", res.Err)
+		}
+	}
+	writeJSON(w, http.StatusAccepted, map[string]int{"accepted": len(accepted)})
+}
+
+// addRounds schedules a round per target on its interval or cron schedule,
+// each of which observes the agents' verdict on it.
+func (s *collectorServer) addRounds(sched *scheduler.Scheduler) error {
+	for _, t := range s.fleet.Targets() {
+		job := scheduler.Job{
+			Name:     "round " + t.Name,
+			Interval: t.Interval,
+			Run:      func(ctx context.Context) { s.round(ctx, t) },
+		}
+		if job.Interval <= 0 {
+			job.Interval = interval
+		}
+		if t.Cron != "" {
+			c, err := scheduler.ParseCron(t.Cron)
+			if err != nil {
+				return fmt.Errorf("target %q: %w", t.Name, err)
+			}
+			job.Cron = c
+		}
+		sched.Add(job)
+	}
+	return nil
+}
+
+// round observes the verdict of the agents on t once, as the result of the
+// newest check that agrees with it. Rounds without new results since the
+// previous one are skipped, so that a single report isn't counted twice.
+func (s *collectorServer) round(ctx context.Context, t config.Target) {
+	v := s.fleet.Verdict(t.Name, time.Now())
+	if v.State == status.Unknown {
+		return
+	}
+	var newest fleet.Result
+	for _, r := range s.fleet.Results(t.Name) {
+		if _, ok := agreedResult(r, v); ok && r.Time.After(newest.Time) {
+			newest = r
+		}
+	}
+	s.mu.Lock()
+	if s.judged == nil {
+		s.judged = make(map[string]time.Time)
+	}
+	fresh := newest.Time.After(s.judged[t.Name])
+	if fresh {
+		s.judged[t.Name] = newest.Time
+	}
+	s.mu.Unlock()
+	if !fresh {
+		return
+	}
+	cr, _ := agreedResult(newest, v)
+	s.obs.observe(ctx, t, cr, s.obs.maintenance(t))
+}
+
+// agreedResult returns the result of a check by one agent as the collector
+// sees it given the verdict v of all agents. Failures that don't reach the
+// quorum are left out, so they don't change the target's state.
+func agreedResult(r fleet.Result, v fleet.Verdict) (checkResult, bool) {
+	res := checkResult{
+		URL:        r.URL,
+		CheckedAt:  r.Time,
+		Up:         r.Up,
+		StatusCode: r.StatusCode,
+		Latency:    r.Latency,
+		Attempts:   1,
+	}
+	if v.State == status.Down {
+		res.Up = false
+		res.Err = errors.New(v.Reason)
+		return res, true
+	}
+	return res, r.Up
+}
+
+// collectorTarget is a target as served by the collector's status endpoint.
+type collectorTarget struct {
+	Name    string                  `json:"name"`
+	URL     string                  `json:"url"`
+	State   status.State            `json:"state"`
+	Verdict fleet.Verdict           `json:"verdict"`
+	Results map[string]fleet.Result `json:"results"`
+}
+
+func (s *collectorServer) status(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	var targets []collectorTarget
+	for _, t := range s.fleet.Targets() {
+		targets = append(targets, collectorTarget{
+			Name:    t.Name,
+			URL:     t.URL,
+			State:   s.obs.tracker.State(t.Name),
+			Verdict: s.fleet.Verdict(t.Name, now),
+			Results: s.fleet.Results(t.Name),
+		})
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Agents  []fleet.Agent     `json:"agents"`
+		Targets []collectorTarget `json:"targets"`
+	}{s.fleet.Agents(now), targets})
+}
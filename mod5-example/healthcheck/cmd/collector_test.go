@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/fleet"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/maintenance"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/scheduler"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgreedResult(t *testing.T) {
+	up := fleet.Result{Target: "api", Time: time.Now(), Up: true, StatusCode: 200, Latency: time.Millisecond}
+	down := fleet.Result{Target: "api", Time: time.Now(), Err: "connection refused"}
+	tests := []struct {
+		name     string
+		result   fleet.Result
+		verdict  fleet.Verdict
+		ok       bool
+		expected status.State
+	}{
+		{"Up", up, fleet.Verdict{State: status.Up}, true, status.Up},
+		{"Failure below the quorum", down, fleet.Verdict{State: status.Up, Reason: "down from [b] only"}, false, ""},
+		{"Down", down, fleet.Verdict{State: status.Down, Reason: "down from 2 of 3 agents"}, true, status.Down},
+		{"Up from an agent outvoted", up, fleet.Verdict{State: status.Down, Reason: "down from 2 of 3 agents"}, true, status.Down},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, ok := agreedResult(tc.result, tc.verdict)
+			assert.Equal(t, tc.ok, ok)
+			if ok {
+				state, reason := resultState(r)
+				assert.Equal(t, tc.expected, state)
+				if state == status.Down {
+					assert.Equal(t, tc.verdict.Reason, reason)
+				}
+			}
+		})
+	}
+}
+
+// TestCollector runs a collector and three agents, which agree that one of
+// two targets is down.
+func TestCollector(t *testing.T) {
+	_, sess := newTestAPI(t)
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer broken.Close()
+	defer func(d time.Duration) { refresh = d }(refresh)
+	refresh = 20 * time.Millisecond
+
+	cs := &collectorServer{
+		fleet: fleet.NewCollector([]config.Target{
+			{Name: "healthy", URL: healthy.URL, Interval: 20 * time.Millisecond},
+			{Name: "broken", URL: broken.URL, Interval: 20 * time.Millisecond},
+			{Name: "elsewhere", URL: healthy.URL, Interval: 20 * time.Millisecond, Agents: []string{"agent-9"}},
+		}, 0, time.Minute),
+		obs:            &observer{tracker: status.NewTracker(status.Options{}), windows: maintenance.NewRegistry(nil, "")},
+		token:          "secret",
+		reportInterval: 20 * time.Millisecond,
+	}
+	cs.obs.recorder = sess.obs.recorder
+	srv := httptest.NewServer(cs.handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/v1/status")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	sched := scheduler.New(0)
+	assert.NoError(t, cs.addRounds(sched))
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sched.Run(ctx)
+	}()
+	for i := 1; i <= 3; i++ {
+		a := newAgent(&fleet.Client{URL: srv.URL, Agent: fmt.Sprintf("agent-%d", i), Token: "secret"})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.run(ctx)
+		}()
+	}
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	var st struct {
+		Agents  []fleet.Agent
+		Targets []collectorTarget
+	}
+	assert.Eventually(t, func() bool {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/status", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		json.NewDecoder(resp.Body).Decode(&st)
+		return len(st.Targets) == 3 && len(st.Targets[0].Results) == 3 && len(st.Targets[1].Results) == 3 &&
+			st.Targets[0].State == status.Up && st.Targets[1].State == status.Down
+	}, 2*time.Second, 20*time.Millisecond)
+
+	assert.Len(t, st.Agents, 3)
+	assert.Equal(t, status.Up, st.Targets[0].State)
+	assert.Equal(t, status.Down, st.Targets[1].State)
+	assert.Equal(t, "down from 3 of 3 agents: unexpected status code 502", st.Targets[1].Verdict.Reason)
+	assert.Equal(t, status.Unknown, st.Targets[2].Verdict.State, "no agent is assigned the target")
+}
+
+func TestCollector_DownAfter(t *testing.T) {
+	l = slog.New(slog.NewTextHandler(io.Discard, nil))
+	target := config.Target{Name: "api", URL: "http://api.test", Interval: time.Minute}
+	cs := &collectorServer{
+		fleet: fleet.NewCollector([]config.Target{target}, 2, time.Minute),
+		obs: &observer{
+			tracker: status.NewTracker(status.Options{DownAfter: 2}),
+			windows: maintenance.NewRegistry(nil, ""),
+		},
+	}
+	ctx := context.Background()
+	start := time.Now()
+	report := func(round int, up bool) {
+		for _, agent := range []string{"agent-1", "agent-2", "agent-3"} {
+			cs.fleet.Record(agent, []fleet.Result{{Target: "api", URL: target.URL, Time: start.Add(time.Duration(round) * time.Second), Up: up}}, time.Now())
+		}
+	}
+
+	report(0, true)
+	cs.round(ctx, target)
+	assert.Equal(t, status.Up, cs.obs.tracker.State("api"))
+
+	report(1, false)
+	cs.round(ctx, target)
+	assert.Equal(t, status.Up, cs.obs.tracker.State("api"), "one bad round reported by every agent is a single down verdict")
+	cs.round(ctx, target)
+	assert.Equal(t, status.Up, cs.obs.tracker.State("api"), "rounds without new results shouldn't count")
+
+	report(2, false)
+	cs.round(ctx, target)
+	assert.Equal(t, status.Down, cs.obs.tracker.State("api"))
+}
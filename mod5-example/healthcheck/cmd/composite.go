@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/composite"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+)
+
+// health is how a composite expression sees a target in state s: up and
+// degraded targets are healthy, targets not checked yet are unknown and all
+// others are not healthy.
+func health(s status.State) composite.Value {
+	switch s {
+	case status.Up, status.Degraded:
+		return composite.True
+	case status.Unknown:
+		return composite.Unknown
+	default:
+		return composite.False
+	}
+}
+
+// evaluate works out the result of the composite target t from state, the
+// current state of every target. It returns false while too many of the
+// targets t uses are unknown to tell.
+func evaluate(t config.Target, e composite.Expr, state func(name string) status.State) (checkResult, bool) {
+	r := checkResult{URL: t.URL, CheckedAt: time.Now()}
+	switch e.Eval(func(name string) composite.Value { return health(state(name)) }) {
+	case composite.Unknown:
+		return r, false
+	case composite.True:
+		r.Up = true
+	default:
+		var unhealthy []string
+		for _, name := range e.Targets() {
+			if s := state(name); health(s) == composite.False {
+				unhealthy = append(unhealthy, fmt.Sprintf("%s is %s", name, s))
+			}
+		}
+		r.Err = fmt.Errorf("%s failed: %s", e, strings.Join(unhealthy, ", "))
+	}
+	return r, true
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/composite"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluate(t *testing.T) {
+	states := map[string]status.State{"api-1": status.Up, "api-2": status.Degraded, "api-3": status.Down, "api-4": status.Unreachable}
+	tests := []struct {
+		expr     string
+		ok       bool
+		up       bool
+		expected string
+	}{
+		{"quorum(2, api-1, api-2, api-3)", true, true, ""},
+		{"quorum(3, api-1, api-2, api-3)", true, false, "quorum(3, api-1, api-2, api-3) failed: api-3 is down"},
+		{"api-1 && (api-3 || api-4)", true, false, "api-1 && (api-3 || api-4) failed: api-3 is down, api-4 is unreachable"},
+		{"api-3 || new", false, false, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			e, err := composite.Parse(tc.expr)
+			assert.NoError(t, err)
+			target := config.Target{Name: "api", URL: "composite:api"}
+			r, ok := evaluate(target, e, func(name string) status.State {
+				if s, ok := states[name]; ok {
+					return s
+				}
+				return status.Unknown
+			})
+			assert.Equal(t, tc.ok, ok)
+			if !ok {
+				return
+			}
+			assert.Equal(t, "composite:api", r.URL)
+			assert.Equal(t, tc.up, r.Up)
+			if tc.expected == "" {
+				assert.NoError(t, r.Err)
+			} else {
+				assert.EqualError(t, r.Err, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSession_Composite(t *testing.T) {
+	defer func(r int) { retries = r }(retries)
+	retries = 0
+	var healthy [3]atomic.Bool
+	var servers [3]*httptest.Server
+	for i := range servers {
+		servers[i] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !healthy[i].Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer servers[i].Close()
+		healthy[i].Store(true)
+	}
+
+	_, sess := newTestAPI(t)
+	var mu sync.Mutex
+	var results []string
+	sess.onResult = func(t config.Target, r checkResult) {
+		if t.Name == "replicas" {
+			mu.Lock()
+			results = append(results, string(r.State))
+			mu.Unlock()
+		}
+	}
+	evaluated := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(results)
+	}
+	evaluations := func(n int) func() bool {
+		return func() bool { return len(evaluated()) == n }
+	}
+	replicas := config.Target{Name: "replicas", Expr: "quorum(2, api-1, api-2, api-3)"}
+	assert.NoError(t, sess.add(replicas))
+	var members []config.Target
+	for i, srv := range servers {
+		m := config.Target{Name: []string{"api-1", "api-2", "api-3"}[i], URL: srv.URL}
+		assert.NoError(t, sess.add(m))
+		members = append(members, m)
+	}
+	ctx := context.Background()
+	replicas, _ = sess.target("replicas")
+	assert.Equal(t, "composite:replicas", replicas.URL)
+	runTriggered(t, sess)
+	const wait, tick = time.Second, 5 * time.Millisecond
+
+	sess.check(ctx, replicas)
+	assert.Empty(t, evaluated(), "replicas shouldn't be evaluated before its members are checked")
+	sess.check(ctx, members[0])
+	assert.Never(t, evaluations(1), 50*time.Millisecond, tick, "one healthy member shouldn't be enough to tell")
+	sess.check(ctx, members[1])
+	assert.Eventually(t, evaluations(1), wait, tick, "replicas should be evaluated once a member changes state")
+	assert.Equal(t, []string{"up"}, evaluated())
+	assert.Equal(t, status.Up, sess.obs.tracker.State("replicas"))
+
+	healthy[0].Store(false)
+	sess.check(ctx, members[0])
+	assert.Never(t, evaluations(2), 50*time.Millisecond, tick, "api-3 could still make the quorum")
+	assert.Equal(t, status.Up, sess.obs.tracker.State("replicas"))
+	healthy[1].Store(false)
+	sess.check(ctx, members[1])
+	assert.Eventually(t, evaluations(2), wait, tick)
+	assert.Equal(t, status.Down, sess.obs.tracker.State("replicas"))
+	assert.Equal(t, []string{"up", "down"}, evaluated())
+	assert.Equal(t, "quorum(2, api-1, api-2, api-3) failed: api-1 is down, api-2 is down", sess.results()[0].Err.Error())
+}
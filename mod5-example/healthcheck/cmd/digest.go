@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/alert"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/report"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/scheduler"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+)
+
+// digestSchedules maps the digest shorthands to a cron expression and the
+// period they cover.
+var digestSchedules = map[string]struct {
+	cron   string
+	period time.Duration
+}{
+	"daily":  {"0 8 * * *", 24 * time.Hour},
+	"weekly": {"0 8 * * 1", 7 * 24 * time.Hour},
+}
+
+// addDigest schedules the digest email from the config file, if there is
+// one.
+func addDigest(sched *scheduler.Scheduler, obs *observer) error {
+	if cfg == nil || cfg.Alerts.Digest == nil || obs.notifier == nil || obs.recorder == nil {
+		return nil
+	}
+	d := cfg.Alerts.Digest
+	expr, period := d.Schedule, d.Period
+	if s, ok := digestSchedules[d.Schedule]; ok {
+		expr = s.cron
+		if period == 0 {
+			period = s.period
+		}
+	}
+	c, err := scheduler.ParseCron(expr)
+	if err != nil {
+		return fmt.Errorf("digest: %w", err)
+	}
+	sched.Add(scheduler.Job{
+		Name:       "digest",
+		Background: true,
+		Cron:       c,
+		Run: func(ctx context.Context) {
+			sendDigest(ctx, obs.recorder.st, obs.notifier, d.Emails, period)
+		},
+	})
+	return nil
+}
+
+// sendDigest emails a summary of the results stored over the last period.
+func sendDigest(ctx context.Context, st *store.Store, n *alert.Notifier, emails []string, period time.Duration) {
+	to := time.Now()
+	from := to.Add(-period)
+	summaries, err := summarize(st, from, to)
+	if err != nil {
+		l.ErrorContext(ctx, "failed to read history for digest", "err", err)
+		return
+	}
+	var body strings.Builder
+	report.WriteDigest(&body, summaries, from, to)
+	subject := fmt.Sprintf("[healthcheck] Digest for %s - %s", from.Format("01/02/2006"), to.Format("01/02/2006"))
+	for _, name := range emails {
+		n.Email(ctx, name, subject, body.String(), alert.Event{Rule: "digest"})
+	}
+	l.InfoContext(ctx, "digest sent", "from", from, "to", to, "targets", len(summaries))
+}
+
+// summarize summarizes the stored results between from and to. Periods
+// reaching back further than raw results are kept are summarized from the
+// hourly rollups, which don't record individual incidents.
+func summarize(st *store.Store, from, to time.Time) ([]report.Summary, error) {
+	if keep := st.Retention().Raw; keep <= 0 || !from.Before(time.Now().Add(-keep)) {
+		results, err := st.Query(store.Query{From: from, To: to})
+		if err != nil {
+			return nil, err
+		}
+		return report.Summarize(results), nil
+	}
+	urls, err := st.URLs()
+	if err != nil {
+		return nil, err
+	}
+	var summaries []report.Summary
+	for _, url := range urls {
+		rollups, err := st.Rollups(url, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if len(rollups) > 0 {
+			summaries = append(summaries, report.SummarizeRollups(url, rollups))
+		}
+	}
+	return summaries, nil
+}
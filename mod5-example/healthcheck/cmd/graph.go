@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/composite"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/graph"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/maintenance"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/spf13/cobra"
+)
+
+var graphDOT bool
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Show the dependency tree of the targets with their live status",
+	Long: `Checks every target in the --config file and prints the tree of their
+dependencies, as declared with depends_on, with the state of each target:
+
+  targets:
+    - name: lb
+      url: https://lb.example.com/health
+    - name: api
+      url: https://api.example.com/health
+      depends_on: [lb]
+
+Targets are checked parents first. A target that fails while one of its
+parents is down is unreachable rather than down, like monitor reports it
+without alerting on it. Composite targets appear under the targets their
+expression uses, and targets with several parents appear under each.
+
+With --dot, the graph is written in the Graphviz DOT language instead, for
+example to render it with "dot -Tsvg".`,
+	Example: `  healthcheck graph --config healthcheck.yaml
+  healthcheck graph --config healthcheck.yaml --dot | dot -Tsvg > graph.svg`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		if cfg == nil || len(cfg.Targets) == 0 {
+			return errors.New("no targets to graph: list them under targets in the --config file")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		targets := resolveTargets(nil)
+		g := graph.New(targets)
+		return printGraph(cmd.OutOrStdout(), g, targets, checkGraph(cmd.Context(), g, targets))
+	},
+}
+
+func init() {
+	graphCmd.Flags().BoolVar(&graphDOT, "dot", false, "Write the graph in the Graphviz DOT language")
+	rootCmd.AddCommand(graphCmd)
+}
+
+// graphNode is a target of the dependency graph with the outcome of its
+// check.
+type graphNode struct {
+	Name      string        `json:"name"`
+	URL       string        `json:"url"`
+	DependsOn []string      `json:"depends_on,omitempty"`
+	State     status.State  `json:"state"`
+	Latency   time.Duration `json:"latency"`
+	Reason    string        `json:"reason,omitempty"`
+}
+
+// checkGraph checks the targets of g level by level, so that parents are
+// checked before their children, and returns the outcomes by target name.
+// Targets of the same level are checked concurrently.
+func checkGraph(ctx context.Context, g *graph.Graph, targets []config.Target) map[string]graphNode {
+	byName := make(map[string]config.Target, len(targets))
+	for _, t := range targets {
+		byName[t.Name] = t
+	}
+	obs := &observer{
+		tracker: status.NewTracker(status.Options{}),
+		windows: maintenance.NewRegistry(cfg.Maintenance, silences),
+	}
+
+	var mu sync.Mutex
+	nodes := make(map[string]graphNode, len(targets))
+	for _, level := range g.Levels() {
+		var wg sync.WaitGroup
+		for _, name := range level {
+			t := byName[name]
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				n := checkNode(ctx, obs, t)
+				mu.Lock()
+				nodes[t.Name] = n
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+	}
+	return nodes
+}
+
+// checkNode checks t, or evaluates it if it is a composite target, and
+// observes the result with obs. Composite targets stay unknown while too
+// many of the targets they use are unknown to tell.
+func checkNode(ctx context.Context, obs *observer, t config.Target) graphNode {
+	n := graphNode{Name: t.Name, URL: t.URL, DependsOn: t.DependsOn, State: status.Unknown}
+	w := obs.maintenance(t)
+	var r checkResult
+	if t.Expr != "" {
+		e, err := composite.Parse(t.Expr)
+		if err != nil {
+			n.Reason = err.Error()
+			return n
+		}
+		var ok bool
+		if r, ok = evaluate(t, e, obs.tracker.State); !ok {
+			return n
+		}
+	} else {
+		r = runCheck(ctx, t.URL, threshold, retries)
+	}
+	n.Latency = r.Latency
+	n.State = obs.observe(ctx, t, r, w)
+	if n.State == status.Unreachable {
+		n.Reason = obs.downParent(t)
+	} else {
+		_, n.Reason = checkState(r, w)
+	}
+	return n
+}
+
+// printGraph writes g as a tree, or in DOT with --dot, or the nodes in
+// target order with -o json.
+func printGraph(w io.Writer, g *graph.Graph, targets []config.Target, nodes map[string]graphNode) error {
+	if output == "json" {
+		list := make([]graphNode, 0, len(targets))
+		for _, t := range targets {
+			list = append(list, nodes[t.Name])
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+	if graphDOT {
+		return g.WriteDOT(w, func(name string) status.State { return nodes[name].State })
+	}
+	return g.WriteTree(w, func(name string) string {
+		n := nodes[name]
+		detail := n.Reason
+		if detail == "" {
+			detail = n.Latency.Round(time.Millisecond).String()
+		}
+		return fmt.Sprintf("%s  %s  %s", n.Name, stateText(n.State), detail)
+	})
+}
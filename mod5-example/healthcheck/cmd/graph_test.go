@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserve_Unreachable(t *testing.T) {
+	l = slog.New(slog.NewTextHandler(io.Discard, nil))
+	obs := &observer{tracker: status.NewTracker(status.Options{DownAfter: 2})}
+	lb := config.Target{Name: "lb", URL: "http://lb"}
+	api := config.Target{Name: "api", URL: "http://api", DependsOn: []string{"lb"}}
+	worker := config.Target{Name: "worker", URL: "http://worker", DependsOn: []string{"api"}}
+	ctx := context.Background()
+	failed := checkResult{Err: errors.New("connection refused"), CheckedAt: time.Now()}
+	ok := checkResult{Up: true, CheckedAt: time.Now()}
+
+	assert.Equal(t, status.Up, obs.observe(ctx, api, ok, nil))
+	assert.Equal(t, status.Unknown, obs.observe(ctx, lb, failed, nil), "a single failure shouldn't make lb down")
+	assert.Equal(t, status.Unreachable, obs.observe(ctx, api, failed, nil), "a failing parent should make api unreachable right away")
+	assert.Equal(t, "parent lb is down", obs.downParent(api))
+	assert.Equal(t, status.Unreachable, obs.observe(ctx, worker, failed, nil))
+	assert.Equal(t, "parent api is unreachable", obs.downParent(worker))
+
+	assert.Equal(t, status.Up, obs.observe(ctx, lb, ok, nil))
+	assert.Equal(t, status.Unreachable, obs.observe(ctx, api, failed, nil), "api should stay unreachable until it reaches down-after")
+	assert.Equal(t, status.Down, obs.observe(ctx, api, failed, nil), "api should be down once its parent is up")
+}
+
+func TestSuppressed(t *testing.T) {
+	tests := []struct {
+		from, to   status.State
+		suppressed bool
+	}{
+		{status.Up, status.Down, false},
+		{status.Up, status.Unreachable, true},
+		{status.Down, status.Unreachable, true},
+		{status.Unreachable, status.Up, true},
+		{status.Unreachable, status.Down, false},
+		{status.Down, status.Up, false},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.from)+" to "+string(tc.to), func(t *testing.T) {
+			assert.Equal(t, tc.suppressed, suppressed(status.Transition{From: tc.from, To: tc.to}))
+		})
+	}
+}
+
+func TestSession_ChecksParents(t *testing.T) {
+	defer func(r int) { retries = r }(retries)
+	retries = 0
+	var lbChecks, dbChecks atomic.Int32
+	lb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lbChecks.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer lb.Close()
+	db := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dbChecks.Add(1)
+	}))
+	defer db.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer api.Close()
+
+	_, sess := newTestAPI(t)
+	for _, target := range []config.Target{
+		{Name: "lb", URL: lb.URL},
+		{Name: "db", URL: db.URL},
+		{Name: "web", URL: api.URL, DependsOn: []string{"db", "lb"}},
+	} {
+		assert.NoError(t, sess.add(target))
+	}
+	web, _ := sess.target("web")
+	runTriggered(t, sess)
+	unreachable := func() bool { return sess.obs.tracker.State("web") == status.Unreachable }
+
+	sess.check(context.Background(), web)
+	assert.Equal(t, status.Unknown, sess.obs.tracker.State("web"), "the failure should wait for the parents")
+	assert.Eventually(t, unreachable, time.Second, 5*time.Millisecond, "web should be checked again after its parents")
+	assert.EqualValues(t, 1, lbChecks.Load(), "the parents of a failing target should be checked first")
+	assert.EqualValues(t, 1, dbChecks.Load())
+
+	sess.check(context.Background(), web)
+	assert.Eventually(t, func() bool { return dbChecks.Load() == 2 }, time.Second, 5*time.Millisecond)
+	assert.EqualValues(t, 1, lbChecks.Load(), "a parent already failing shouldn't be checked again")
+	assert.True(t, unreachable())
+}
+
+func TestRun_Graph(t *testing.T) {
+	defer func() { output, cfg, configFile, graphDOT, retries = "", nil, "", false, 3 }()
+	l = slog.New(slog.NewTextHandler(io.Discard, nil))
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer up.Close()
+	file := filepath.Join(t.TempDir(), "healthcheck.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte(`
+targets:
+  - name: lb
+    url: `+down.URL+`/lb
+  - name: api
+    url: `+down.URL+`/api
+    depends_on: [lb]
+  - name: db
+    url: `+up.URL+`/db
+  - name: worker
+    url: `+up.URL+`/worker
+    depends_on: [db, api]
+`), 0o644))
+
+	out, err := executeCommandC(rootCmd, "graph", "--config", file, "--retries", "0", "-o", "json")
+	assert.NoError(t, err)
+	var nodes []graphNode
+	assert.NoError(t, json.NewDecoder(strings.NewReader(out)).Decode(&nodes))
+	states := make(map[string]status.State)
+	for _, n := range nodes {
+		states[n.Name] = n.State
+	}
+	assert.Equal(t, map[string]status.State{"lb": status.Down, "api": status.Unreachable, "db": status.Up, "worker": status.Up}, states)
+	assert.Equal(t, "parent lb is down", nodes[1].Reason)
+
+	output = ""
+	out, err = executeCommandC(rootCmd, "graph", "--config", file, "--retries", "0", "--no-color")
+	assert.NoError(t, err)
+	lines := strings.Split(out, "\n")
+	assert.Equal(t, "lb  Down  unexpected status code 503", lines[0])
+	assert.Equal(t, "└── api  Unreachable  parent lb is down", lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], "    └── worker  Up  "), lines[2])
+
+	out, err = executeCommandC(rootCmd, "graph", "--config", file, "--retries", "0", "--dot")
+	assert.NoError(t, err)
+	assert.Contains(t, out, `"lb" -> "api";`)
+	assert.Contains(t, out, `"api" [label="api\nunreachable", fillcolor="#6e7681"];`)
+}
+
+func TestGraph_NoTargets(t *testing.T) {
+	defer func() { cfg, configFile = nil, "" }()
+	_, err := executeCommandC(rootCmd, "graph")
+	assert.ErrorContains(t, err, "no targets to graph")
+}
@@ -1,95 +1,301 @@
 package cmd
 
 import (
-	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"io"
 	"time"
 
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/history"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/latency"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
 
 var (
 	startDate string
+	endDate   string
+	states    []string
+	stats     bool
 )
 
-type LogEntry struct {
-	Time       time.Time `json:"time"`
-	URL        string    `json:"url"`
-	StatusCode int       `json:"statusCode"`
-	Duration   int64     `json:"duration"`
-}
+var historyColumns = []string{"checked", "url", "status", "code", "latency"}
 
 type DateParseError struct {
-	Date string
+	// Flag is the flag holding the date. It defaults to startDate.
+	Flag   string
+	Date   string
 	Detail string
 }
 
-func (e* DateParseError) Error() string {
-	return 	fmt.Sprintf("Failed to parse 'startDate' flag value, '%s' as 'MM/DD/YYYY'.  Example of a valid date: '01/31/2024'.  Error details: %v\n", e.Date, e.Detail)
+func (e *DateParseError) Error() string {
+	flag := e.Flag
+	if flag == "" {
+		flag = "startDate"
+	}
+	return fmt.Sprintf("Failed to parse '%s' flag value, '%s' as 'MM/DD/YYYY'.  Example of a valid date: '01/31/2024'.  Error details: %v\n", flag, e.Date, e.Detail)
 
 }
+
 // historyCmd represents the history command
 var historyCmd = &cobra.Command{
 	Use:   "history [urls]",
 	Short: "Displays the history of health checks for specified URL(s)",
-	Long: `The history command parses a log file for historical data related to specific URL checks.
-The --startDate flag can be used to specify the UTC start date of the history period.`,
+	Long: `The history command queries the recorded check results for the given URL(s),
+or for every URL when none are given. The --startDate flag can be used to
+specify the UTC start date of the history period, --endDate its last day and
+--state limits the results to checks in the given states.
+
+With --stats it shows the latency percentiles and the Apdex score of each URL
+over the period instead, scoring responses against --threshold. Periods
+older than the raw results are kept are summarized from hourly rollups,
+with percentiles rounded up to the rollups' latency buckets.`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		_, err := time.Parse("01/02/2006", startDate)
-		if err != nil {
-			return &DateParseError {
-				Date: startDate,
+		if err := validateTableFlags(); err != nil {
+			return err
+		}
+		if _, err := time.Parse("01/02/2006", startDate); err != nil {
+			return &DateParseError{
+				Date:   startDate,
 				Detail: err.Error(),
 			}
 		}
+		if endDate != "" {
+			if _, err := time.Parse("01/02/2006", endDate); err != nil {
+				return &DateParseError{
+					Flag:   "endDate",
+					Date:   endDate,
+					Detail: err.Error(),
+				}
+			}
+		}
+		if stats && len(states) > 0 {
+			return errors.New("--state can't be combined with --stats")
+		}
+		for _, s := range states {
+			switch status.State(s) {
+			case status.Up, status.Down, status.Degraded, status.Maintenance, status.Unreachable:
+			default:
+				return fmt.Errorf("unknown state %q, valid states are: up, down, degraded, maintenance, unreachable", s)
+			}
+		}
 		return nil
 	},
-	Run: func(cmd *cobra.Command, args []string) {
-		displayHistory(args)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		if stats {
+			return displayStats(cmd.OutOrStdout(), args)
+		}
+		return displayHistory(cmd.OutOrStdout(), args)
+	},
+}
+
+var historyImportCmd = &cobra.Command{
+	Use:   "import [logfile]",
+	Short: "Record the check results found in a log file in the store",
+	Long: `Reads the check results logged before results were recorded in the store,
+from the given log file or --logfile, and adds them to the store.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := logFile
+		if len(args) == 1 {
+			path = args[0]
+		}
+		samples, err := history.ReadLog(path, time.Time{})
+		if err != nil {
+			return err
+		}
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		st, err := openStore()
+		if err != nil {
+			return err
+		}
+		results := make([]store.Result, len(samples))
+		for i, s := range samples {
+			results[i] = importedResult(s)
+		}
+		if err := st.Write(results...); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Imported %d results from %s\n", len(results), path)
+		return nil
 	},
 }
 
 func init() {
 	historyCmd.Flags().StringVar(&startDate, "startDate", "", "The start date for displaying history (format: MM/DD/YYYY)")
+	historyCmd.Flags().StringVar(&endDate, "endDate", "", "The last date for displaying history (format: MM/DD/YYYY)")
+	historyCmd.Flags().StringSliceVar(&states, "state", nil, "Only show checks in these states (up, down, degraded, maintenance, unreachable)")
+	historyCmd.Flags().BoolVar(&stats, "stats", false, "Show latency percentiles and Apdex scores instead of individual checks")
+	historyCmd.AddCommand(historyImportCmd)
 	rootCmd.AddCommand(historyCmd)
 }
 
-func displayHistory(urls []string) {
-	urlMap := toURLMap(urls)
-	file, err := os.Open(logFile)
+// historyQuery returns the query selecting urls in the period and states
+// set by the flags.
+func historyQuery(urls []string) store.Query {
+	q := store.Query{URLs: urls}
+	q.From, _ = time.Parse("01/02/2006", startDate)
+	if endDate != "" {
+		end, _ := time.Parse("01/02/2006", endDate)
+		q.To = end.AddDate(0, 0, 1)
+	}
+	for _, s := range states {
+		q.States = append(q.States, status.State(s))
+	}
+	return q
+}
+
+func displayHistory(w io.Writer, urls []string) error {
+	q := historyQuery(urls)
+	st, err := openStore()
 	if err != nil {
-		fmt.Printf("Error opening log file: %v\n", err)
-		return
+		return err
+	}
+	results, err := st.Query(q)
+	if err != nil {
+		return err
+	}
+
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		for _, r := range results {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(results) == 0 {
+		fmt.Fprintln(w, "No checks were recorded in this period.")
+		return nil
 	}
-	defer file.Close()
+	rows := make([]checkResult, len(results))
+	for i, r := range results {
+		rows[i] = checkResult{URL: r.URL, Up: r.Up, StatusCode: r.StatusCode, Latency: r.Latency, CheckedAt: r.Time, State: r.State}
+		if r.Err != "" {
+			rows[i].Err = errors.New(r.Err)
+		}
+	}
+	renderTable(w, rows, historyColumns)
+	return nil
+}
 
-	startDateParsed, err := time.Parse("01/02/2006", startDate)
+// importedResult converts a check result read from the log file.
+func importedResult(s history.Sample) store.Result {
+	r := store.Result{
+		Time:        s.Time,
+		Target:      s.URL,
+		URL:         s.URL,
+		Up:          s.Up,
+		State:       status.Up,
+		StatusCode:  s.StatusCode,
+		Latency:     s.Latency,
+		Err:         s.Err,
+		Maintenance: s.Maintenance,
+	}
+	switch {
+	case !s.Up && s.Maintenance != "":
+		r.State = status.Maintenance
+	case !s.Up:
+		r.State = status.Down
+	case s.Latency.Seconds() > threshold:
+		r.State = status.Degraded
+	}
+	return r
+}
+
+// urlStats is the latency of one URL over the history period.
+type urlStats struct {
+	URL string `json:"url"`
+	latency.Stats
+}
+
+func displayStats(w io.Writer, urls []string) error {
+	q := historyQuery(urls)
+	st, err := openStore()
 	if err != nil {
-		fmt.Printf("Error parsing startDate: %v\n", err)
-		return
+		return err
+	}
+	if len(q.URLs) == 0 {
+		if q.URLs, err = st.URLs(); err != nil {
+			return err
+		}
+	}
+	var all []urlStats
+	for _, url := range q.URLs {
+		s, err := periodStats(st, url, q.From, q.To)
+		if err != nil {
+			return err
+		}
+		if s.Checks > 0 {
+			all = append(all, urlStats{URL: url, Stats: s})
+		}
 	}
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		var entry LogEntry
-		if err := json.Unmarshal([]byte(line), &entry); err != nil {
-			fmt.Printf("Error unmarshalling log line: %v\n", err)
-			continue
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(all)
+	}
+	if len(all) == 0 {
+		fmt.Fprintln(w, "No checks were recorded in this period.")
+		return nil
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"URL", "Checks", "Min", "Mean", "P50", "P90", "P95", "P99", "Max", "Apdex"})
+	approximate := false
+	for _, s := range all {
+		row := []string{s.URL, fmt.Sprint(s.Checks)}
+		for _, d := range []time.Duration{s.Min, s.Mean, s.P50, s.P90, s.P95, s.P99, s.Max} {
+			row = append(row, formatLatency(d))
 		}
+		table.Append(append(row, fmt.Sprintf("%.2f", s.Apdex)))
+		approximate = approximate || s.Approximate
+	}
+	table.Render()
+	if approximate {
+		fmt.Fprintln(w, "Figures reaching back beyond the raw results were estimated from hourly rollups.")
+	}
+	return nil
+}
 
-		if urlMap[entry.URL] && entry.Time.After(startDateParsed) {
-			fmt.Println(line)
+// periodStats summarizes the latency of url between from and to, from the
+// raw results while they are kept and from the hourly rollups otherwise.
+// Failures during maintenance are left out.
+func periodStats(st *store.Store, url string, from, to time.Time) (latency.Stats, error) {
+	if keep := st.Retention().Raw; keep > 0 && from.Before(time.Now().Add(-keep)) {
+		rollups, err := st.Rollups(url, from, to)
+		if err != nil {
+			return latency.Stats{}, err
+		}
+		return latency.SummarizeRollups(rollups, thresholdDuration()), nil
+	}
+	results, err := st.Query(store.Query{URLs: []string{url}, From: from, To: to})
+	if err != nil {
+		return latency.Stats{}, err
+	}
+	samples := make([]latency.Sample, 0, len(results))
+	for _, r := range results {
+		if r.Maintenance != "" && !r.Up {
+			continue
 		}
+		samples = append(samples, latency.Sample{Up: r.Up, Latency: r.Latency})
 	}
+	return latency.Summarize(samples, thresholdDuration()), nil
 }
 
-func toURLMap(urls []string) map[string]bool {
-	urlMap := make(map[string]bool)
-	for _, url := range urls {
-		urlMap[url] = true
+func formatLatency(d time.Duration) string {
+	if d == 0 {
+		return "-"
 	}
-	return urlMap
+	return d.Round(time.Millisecond).String()
 }
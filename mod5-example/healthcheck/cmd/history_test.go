@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMain keeps the databases and logs written by the commands under test
+// out of the source tree.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "healthcheck")
+	if err != nil {
+		panic(err)
+	}
+	storeFile = filepath.Join(dir, "healthcheck.db")
+	logFile = filepath.Join(dir, "healthcheck.log")
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestRun_History(t *testing.T) {
+	defer func() { output, states, endDate = "", nil, "" }()
+	day := time.Date(2024, 4, 19, 8, 0, 0, 0, time.UTC)
+	st, err := openStore()
+	assert.NoError(t, err)
+	assert.NoError(t, st.Write(
+		store.Result{Time: day.AddDate(0, 0, -1), URL: "http://history.test", Up: true, State: status.Up},
+		store.Result{Time: day, URL: "http://history.test", Up: true, State: status.Up, StatusCode: 200},
+		store.Result{Time: day.Add(time.Minute), URL: "http://history.test", State: status.Down, Err: "timeout"},
+		store.Result{Time: day.AddDate(0, 0, 1), URL: "http://history.test", Up: true, State: status.Up},
+	))
+
+	out, err := executeCommandC(rootCmd, "history", "--startDate", "04/19/2024", "--endDate", "04/19/2024", "-o", "json", "http://history.test")
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	assert.Len(t, lines, 2)
+	var r store.Result
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &r))
+	assert.Equal(t, 200, r.StatusCode)
+
+	out, err = executeCommandC(rootCmd, "history", "--startDate", "04/19/2024", "--state", "down", "-o", "json", "http://history.test")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(out, `"state":"down"`))
+	assert.NotContains(t, out, `"state":"up"`)
+}
+
+func TestRun_HistoryInvalidState(t *testing.T) {
+	defer func() { states = nil }()
+	_, err := executeCommandC(rootCmd, "history", "--startDate", "04/19/2024", "--state", "sideways")
+	assert.ErrorContains(t, err, "unknown state")
+}
+
+func TestRun_HistoryStats(t *testing.T) {
+	defer func() { output, stats, startDate = "", false, "" }()
+	now := time.Now().UTC()
+	st, err := openStore()
+	assert.NoError(t, err)
+	var results []store.Result
+	for i := 1; i <= 10; i++ {
+		results = append(results, store.Result{Time: now.Add(-time.Duration(i) * time.Minute), URL: "http://stats.test", Up: true, State: status.Up, Latency: time.Duration(i*100) * time.Millisecond})
+	}
+	results = append(results, store.Result{Time: now.Add(-time.Hour), URL: "http://stats.test", State: status.Down, Err: "timeout"})
+	assert.NoError(t, st.Write(results...))
+
+	out, err := executeCommandC(rootCmd, "history", "--startDate", now.Format("01/02/2006"), "--stats", "-o", "json", "http://stats.test")
+	assert.NoError(t, err)
+	var all []urlStats
+	assert.NoError(t, json.Unmarshal([]byte(out), &all))
+	if assert.Len(t, all, 1) {
+		s := all[0]
+		assert.Equal(t, 11, s.Checks)
+		assert.Equal(t, 100*time.Millisecond, s.Min)
+		assert.Equal(t, time.Second, s.Max)
+		assert.Equal(t, 500*time.Millisecond, s.P50)
+		assert.Equal(t, 900*time.Millisecond, s.P90)
+		// Five checks within the 0.5s threshold, five within 2s and a failure.
+		assert.InDelta(t, 7.5/11, s.Apdex, 0.0001)
+		assert.False(t, s.Approximate)
+	}
+
+	out, err = executeCommandC(rootCmd, "history", "--startDate", "01/01/2024", "--stats", "-o", "table", "http://stats.test")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "P99")
+	assert.Contains(t, out, "estimated from hourly rollups")
+}
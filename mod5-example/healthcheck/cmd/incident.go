@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/alert"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/statuspage"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	noteTitle      string
+	noteComponents []string
+	noteStatus     string
+)
+
+var incidentCmd = &cobra.Command{
+	Use:   "incident",
+	Short: "Post incident notes for the status page",
+	Long: `Incident notes explain problems to the readers of the status page built with
+"healthcheck statuspage build". Each incident has a title, the components it
+affects and the updates posted on it, and stays on the page until an update
+resolves it. Notes are kept in the --notes file.`,
+}
+
+var incidentNoteCmd = &cobra.Command{
+	Use:   "note [id] <message>",
+	Short: "Open an incident or post an update on one",
+	Long: `Without an ID, opens a new incident titled --title. With one, posts an update
+on that incident. The ID may also be that of an alert incident listed by
+"healthcheck alerts list", which opens a public incident for its target.
+
+Updates have a status of investigating, identified, monitoring or resolved.
+New incidents start as investigating and updates keep the current status
+unless --status is given.`,
+	Example: `  healthcheck incident note --title "Elevated API errors" --component api "We are investigating failed requests."
+  healthcheck incident note 3f2a9c1b --status resolved "A fix has been deployed."`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, message := "", args[0]
+		if len(args) == 2 {
+			id, message = args[0], args[1]
+		}
+		open := statuspage.Incident{Title: noteTitle, Components: noteComponents}
+		if id != "" && open.Title == "" {
+			var err error
+			if open, err = alertNote(id); err != nil {
+				return err
+			}
+		}
+		if id == "" && noteStatus == "" {
+			noteStatus = statuspage.Investigating
+		}
+		inc, err := statuspage.NewNotes(notesFile).Add(id, open, statuspage.Update{
+			Time:    time.Now(),
+			Status:  noteStatus,
+			Message: message,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Posted %s update on incident %s: %s\n", inc.Status(), inc.ID, inc.Title)
+		return nil
+	},
+}
+
+var incidentListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the incidents on the status page",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		list, err := statuspage.NewNotes(notesFile).List()
+		if err != nil {
+			return err
+		}
+		return printNotes(cmd.OutOrStdout(), list)
+	},
+}
+
+func init() {
+	incidentNoteCmd.Flags().StringVar(&noteTitle, "title", "", "Title of a new incident")
+	incidentNoteCmd.Flags().StringSliceVar(&noteComponents, "component", nil, "Names of the targets a new incident affects")
+	incidentNoteCmd.Flags().StringVar(&noteStatus, "status", "", "Status of the update (investigating, identified, monitoring, resolved)")
+	incidentCmd.AddCommand(incidentNoteCmd, incidentListCmd)
+	rootCmd.AddCommand(incidentCmd)
+}
+
+// alertNote returns the public incident to open for the alert incident with
+// the given ID, or one without a title if there is no such alert.
+func alertNote(id string) (statuspage.Incident, error) {
+	list, err := alert.NewIncidents(incidents).List()
+	if err != nil {
+		return statuspage.Incident{}, err
+	}
+	for _, inc := range list {
+		if inc.ID != id {
+			continue
+		}
+		title := "Outage of " + inc.Target
+		if inc.State == status.Degraded {
+			title = "Degraded performance of " + inc.Target
+		}
+		return statuspage.Incident{Title: title, Components: []string{inc.Target}}, nil
+	}
+	return statuspage.Incident{}, nil
+}
+
+func printNotes(w io.Writer, list []statuspage.Incident) error {
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+	if len(list) == 0 {
+		fmt.Fprintln(w, "No incident notes.")
+		return nil
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"ID", "Title", "Components", "Status", "Opened", "Updates"})
+	for _, inc := range list {
+		opened := inc.Opened().UTC().Format("01/02/2006 03:04PM")
+		table.Append([]string{inc.ID, inc.Title, strings.Join(inc.Components, ","), inc.Status(), opened, fmt.Sprint(len(inc.Updates))})
+	}
+	table.Render()
+	return nil
+}
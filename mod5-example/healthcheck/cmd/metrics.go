@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/metrics"
+)
+
+var metricsAddr string
+
+// startMetrics serves the Prometheus metrics of sess at /metrics on
+// --metrics until ctx is done.
+func startMetrics(ctx context.Context, sess *session) error {
+	if metricsAddr == "" {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", newMetrics(sess))
+	return serve(ctx, "metrics", metricsAddr, mux)
+}
+
+// newMetrics returns a registry of the metrics of sess, which it hooks into
+// to observe every check and removed target. It must be called before the
+// session's scheduler runs.
+func newMetrics(sess *session) *metrics.Registry {
+	reg := metrics.New()
+	onResult, onRemove := sess.onResult, sess.onRemove
+	sess.onResult = func(t config.Target, r checkResult) {
+		onResult(t, r)
+		reg.Observe(t.Name, t.URL, t.Tags, metricsCheck(r))
+	}
+	sess.onRemove = func(name string) {
+		if onRemove != nil {
+			onRemove(name)
+		}
+		reg.Remove(name)
+	}
+	return reg
+}
+
+func metricsCheck(r checkResult) metrics.Check {
+	c := metrics.Check{
+		Time:       r.CheckedAt,
+		Up:         r.Up,
+		State:      r.State,
+		StatusCode: r.StatusCode,
+		Latency:    r.Latency,
+		CertExpiry: r.CertExpiry,
+		Attempts:   r.Attempts,
+	}
+	if r.StatusCode != 0 {
+		c.Phases = r.Phases.ByName()
+	}
+	return c
+}
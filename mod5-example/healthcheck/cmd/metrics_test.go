@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/probe"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMetrics(t *testing.T) {
+	_, sess := newTestAPI(t)
+	var results, removed int
+	sess.onResult = func(config.Target, checkResult) { results++ }
+	sess.onRemove = func(string) { removed++ }
+	srv := httptest.NewServer(newMetrics(sess))
+	defer srv.Close()
+
+	sess.onResult(config.Target{Name: "api", URL: "http://api.test", Tags: map[string]string{"team": "payments"}}, checkResult{
+		URL: "http://api.test", Up: true, StatusCode: 200, Latency: 20 * time.Millisecond, Attempts: 1, State: status.Up,
+		Phases: probe.Phases{Processing: 15 * time.Millisecond},
+	})
+	body := scrape(t, srv.URL)
+	assert.Contains(t, body, `healthcheck_up{target="api",url="http://api.test",team="payments"} 1`)
+	assert.Contains(t, body, `healthcheck_phase_duration_seconds{target="api",url="http://api.test",team="payments",phase="processing"} 0.015`)
+	assert.Contains(t, body, `healthcheck_phase_duration_seconds{target="api",url="http://api.test",team="payments",phase="tls"} 0`)
+
+	assert.True(t, sess.remove("api"))
+	assert.NotContains(t, scrape(t, srv.URL), `target="api"`)
+	assert.Equal(t, 1, results, "the previous result hook still runs")
+	assert.Equal(t, 1, removed, "the previous remove hook still runs")
+}
+
+func TestRunCheck_Phases(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	r := runCheck(context.Background(), srv.URL, threshold, 0)
+	assert.True(t, r.Up)
+	assert.Greater(t, r.Phases.Connect, time.Duration(0))
+	assert.GreaterOrEqual(t, r.Phases.Processing, 20*time.Millisecond)
+	assert.Zero(t, r.Phases.TLS, "the target isn't served over TLS")
+	assert.Zero(t, r.Phases.DNS, "the target is an IP address")
+}
+
+func scrape(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
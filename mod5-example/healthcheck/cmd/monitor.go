@@ -2,31 +2,80 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
-	"os/exec"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/briandowns/spinner"
-	"github.com/fatih/color"
-	"github.com/olekukonko/tablewriter"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/breaker"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/composite"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/dashboard"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/logger"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/scheduler"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
 	"github.com/spf13/cobra"
 )
 
 var (
 	interval time.Duration
+	jitter   time.Duration
+	workers  int
+
+	downAfter     int
+	upAfter       int
+	flapWindow    time.Duration
+	flapThreshold int
+
+	statsWindow time.Duration
 )
 
 var monitorCmd = &cobra.Command{
 	Use:   "monitor [urls]",
 	Short: "Monitor the health of specified URL(s) over time",
-	Long:  `Continuously monitors the health of the specified URL(s) at the specified interval`,
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Continuously monitors the health of the specified URL(s), and the targets
+of the --config file, each on its own interval or cron schedule. Targets can
+depend on each other or combine others into composite targets, and their
+results can be served as a JSON API (--listen), a live web dashboard (--web)
+and Prometheus metrics (--metrics), or pushed to StatsD and InfluxDB sinks.
+See docs/monitor.md for the API, metrics and config file reference.`,
+	Example: `  healthcheck monitor --interval 30s https://example.com https://api.example.com
+  healthcheck monitor --config healthcheck.yaml --workers 128 --timeout 5s
+  HEALTHCHECK_API_TOKEN=secret healthcheck monitor --config healthcheck.yaml --listen localhost:8080 --web :8081 --metrics :9115`,
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := cmd.Context()
-		monitorURLs(ctx, args)
+		monitorTargets(ctx, resolveTargets(args))
 	},
 	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateTableFlags(); err != nil {
+			return err
+		}
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		if len(args) == 0 && (cfg == nil || len(cfg.Targets) == 0) {
+			return fmt.Errorf("requires at least 1 url, either as an argument or in the --config file")
+		}
+		if interval <= 0 {
+			return fmt.Errorf("--interval must be greater than zero")
+		}
+		if anomalySensitivity <= 0 {
+			return fmt.Errorf("--anomaly-sensitivity must be greater than zero")
+		}
+		if err := validateAPIFlags(); err != nil {
+			return err
+		}
+		if err := validateBreakerFlags(); err != nil {
+			return err
+		}
+		if statsWindow <= 0 {
+			return fmt.Errorf("--stats-window must be greater than zero")
+		}
 		for _, url := range args {
 			err := isValidURL(url)
 			if err != nil {
@@ -38,46 +87,559 @@ var monitorCmd = &cobra.Command{
 }
 
 func init() {
-	monitorCmd.Flags().DurationVar(&interval, "interval", 2*time.Second, "Interval between healthchecks")
+	monitorCmd.Flags().DurationVar(&interval, "interval", 2*time.Second, "Interval between healthchecks, for targets that don't set their own")
+	monitorCmd.Flags().DurationVar(&jitter, "jitter", 0, "Maximum random delay added to each target's first check")
+	monitorCmd.Flags().IntVar(&workers, "workers", scheduler.DefaultWorkers, "Maximum number of checks running at once")
+	monitorCmd.Flags().IntVar(&downAfter, "down-after", 1, "Consecutive failed checks before a target is considered down")
+	monitorCmd.Flags().IntVar(&upAfter, "up-after", 1, "Consecutive successful checks before a down target is considered up again")
+	monitorCmd.Flags().DurationVar(&flapWindow, "flap-window", 0, "Window in which state changes are counted for flap detection (0 disables it)")
+	monitorCmd.Flags().IntVar(&flapThreshold, "flap-threshold", 5, "State changes within --flap-window that mark a target as flapping")
+	monitorCmd.Flags().DurationVar(&recoveryInterval, "recovery-interval", 0, "Interval between healthchecks of down targets (default a quarter of the target's interval)")
+	monitorCmd.Flags().DurationVar(&breakerAfter, "breaker-after", time.Hour, "How long a target must be down before its circuit opens and checks back off (0 disables it)")
+	monitorCmd.Flags().DurationVar(&breakerBackoff, "breaker-backoff", 5*time.Minute, "How long an open circuit waits before a half-open check, doubling after every failed one")
+	monitorCmd.Flags().DurationVar(&breakerMaxBackoff, "breaker-max-backoff", time.Hour, "Longest an open circuit waits before a half-open check")
+	monitorCmd.Flags().BoolVar(&anomalies, "anomalies", false, "Learn each target's normal latency and error rate by hour of day and mark anomalous targets as degraded")
+	monitorCmd.Flags().Float64Var(&anomalySensitivity, "anomaly-sensitivity", 3, "Standard deviations from the baseline that make latency or error rates anomalous")
+	monitorCmd.Flags().StringVar(&baselineFile, "baseline", "healthcheck.baseline.json", "File the baselines learned with --anomalies are kept in between runs")
+	monitorCmd.Flags().StringVar(&listenAddr, "listen", "", "Address to serve the monitor API on, such as localhost:8080")
+	monitorCmd.Flags().StringVar(&apiToken, "api-token", "", "Bearer token required by the API (default $"+apiTokenEnv+")")
+	monitorCmd.Flags().StringVar(&webAddr, "web", "", "Address to serve the web dashboard on, such as :8080")
+	monitorCmd.Flags().StringVar(&metricsAddr, "metrics", "", "Address to serve Prometheus metrics on, such as :9115")
+	monitorCmd.Flags().StringVar(&tlsCert, "tls-cert", "", "Certificate file to serve the API, web dashboard and metrics over TLS")
+	monitorCmd.Flags().StringVar(&tlsKey, "tls-key", "", "Private key file of --tls-cert")
+	monitorCmd.Flags().DurationVar(&statsWindow, "stats-window", 5*time.Minute, "Period covered by the latency percentiles and Apdex scores in the dashboard")
 	rootCmd.AddCommand(monitorCmd)
 }
 
-func monitorURLs(ctx context.Context, urls []string) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-	s := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-	if output == "table" {
-		s.Start()
-	}
-	for range ticker.C {
-		if output == "table" {
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetHeader([]string{"URL", "Status", "Last Time Checked"})
-
-			upColor := color.New(color.FgGreen).SprintFunc()
-			downColor := color.New(color.FgRed).SprintFunc()
-			for _, url := range urls {
-				status := downColor("Down")
-				if checkURL(ctx, url, threshold, retries) {
-					status = upColor("Up")
-				}
-				currentTime := time.Now()
-				formattedTime := currentTime.Format("01/02/2006 03:04PM")
-				table.Append([]string{url, status, formattedTime})
+// resolveTargets combines the URLs given as arguments with the targets in
+// the config file, filling in the default interval.
+func resolveTargets(urls []string) []config.Target {
+	var targets []config.Target
+	for _, url := range urls {
+		targets = append(targets, config.Target{Name: url, URL: url})
+	}
+	if cfg != nil {
+		targets = append(targets, cfg.Targets...)
+	}
+	for i := range targets {
+		if targets[i].Interval == 0 {
+			targets[i].Interval = interval
+		}
+	}
+	return targets
+}
+
+var errDuplicateTarget = errors.New("duplicate target name")
+
+// session is a running monitor: its targets, their latest results and the
+// scheduler checking them. Targets can be added, removed and paused while
+// it runs.
+type session struct {
+	sched    *scheduler.Scheduler
+	obs      *observer
+	breakers *breaker.Breakers
+	onResult func(config.Target, checkResult)
+	// onRemove, if set, is called with the name of every removed target.
+	onRemove func(name string)
+
+	mu      sync.Mutex
+	targets []config.Target
+	// byName indexes targets, so that checks don't scan all of them.
+	byName map[string]config.Target
+	latest map[string]checkResult
+	paused map[string]bool
+	// exprs holds the expressions of composite targets, and usedBy the
+	// names of the composite targets using each target.
+	exprs  map[string]composite.Expr
+	usedBy map[string][]string
+	// awaiting counts, for every failed target waiting to be checked again,
+	// the parents it waits for. waiters lists those targets by parent.
+	awaiting map[string]int
+	waiters  map[string][]string
+}
+
+// newSession returns a session with a scheduler job per target. Every check
+// result is passed to obs and then to onResult.
+func newSession(targets []config.Target, obs *observer, onResult func(config.Target, checkResult)) (*session, error) {
+	s := &session{
+		sched:    scheduler.New(jitter),
+		obs:      obs,
+		breakers: newBreakers(),
+		onResult: onResult,
+		byName:   make(map[string]config.Target),
+		latest:   make(map[string]checkResult),
+		paused:   make(map[string]bool),
+		exprs:    make(map[string]composite.Expr),
+		usedBy:   make(map[string][]string),
+		awaiting: make(map[string]int),
+		waiters:  make(map[string][]string),
+	}
+	s.sched.Workers = workers
+	for _, t := range targets {
+		if err := s.add(t); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// add starts checking t, on the --interval if it doesn't set its own. Its
+// circuit breaker checks it more often while it is down and backs off once
+// it has been down for long. Composite targets are evaluated on their
+// interval and whenever a target they use changes state.
+func (s *session) add(t config.Target) error {
+	if t.Interval == 0 {
+		t.Interval = interval
+	}
+	var expr composite.Expr
+	if t.Expr != "" {
+		e, err := composite.Parse(t.Expr)
+		if err != nil {
+			return fmt.Errorf("target %q: %w", t.Name, err)
+		}
+		expr = e
+		if t.URL == "" {
+			t.URL = config.CompositeURL(t.Name)
+		}
+	}
+	job := scheduler.Job{
+		Name:     checkJob(t.Name),
+		Interval: t.Interval,
+		Run:      func(ctx context.Context) { s.check(ctx, t) },
+	}
+	if t.Expr == "" {
+		job.NextInterval = func() time.Duration { return s.nextInterval(t) }
+	}
+	if t.Cron != "" {
+		c, err := scheduler.ParseCron(t.Cron)
+		if err != nil {
+			return fmt.Errorf("target %q: %w", t.Name, err)
+		}
+		job.Cron = c
+	}
+
+	s.mu.Lock()
+	if _, ok := s.byName[t.Name]; ok {
+		s.mu.Unlock()
+		return fmt.Errorf("%w %q", errDuplicateTarget, t.Name)
+	}
+	s.targets = append(s.targets, t)
+	s.byName[t.Name] = t
+	if t.Expr != "" {
+		s.exprs[t.Name] = expr
+		for _, name := range expr.Targets() {
+			s.usedBy[name] = append(s.usedBy[name], t.Name)
+		}
+	}
+	s.mu.Unlock()
+	s.sched.Add(job)
+	return nil
+}
+
+// nextInterval returns the time until t should be checked again, as its
+// circuit breaker says. Unreachable targets keep their interval while
+// their circuit is closed, since checking them more often won't bring them
+// back before their parent.
+func (s *session) nextInterval(t config.Target) time.Duration {
+	next := s.breakers.Interval(t.Name, t.Interval)
+	if s.obs.tracker.State(t.Name) == status.Unreachable {
+		next = max(next, t.Interval)
+	}
+	return next
+}
+
+// checkJob returns the name of the scheduler job checking the target called
+// name, which can't clash with the names of background jobs such as
+// "flush".
+func checkJob(name string) string {
+	return "check " + name
+}
+
+// check runs a check of t, or evaluates it if it is a composite target, and
+// observes its result. When the check fails, the targets t depends on are
+// checked first unless their latest checks failed too, and t is checked
+// again after them, so that a failure caused by a parent is seen as such.
+// The half-open check of an open circuit is made without retries. When t
+// changes state, the composite targets using it are evaluated again. Other
+// targets are only ever checked by their own scheduler jobs, which never
+// overlap.
+func (s *session) check(ctx context.Context, t config.Target) {
+	defer s.release(t.Name)
+	if s.isPaused(t.Name) {
+		return
+	}
+	w := s.obs.maintenance(t)
+	if w != nil {
+		ctx = logger.WithAttrs(ctx, slog.String("maintenance", w.ID))
+	}
+	var r checkResult
+	if e, ok := s.expr(t.Name); ok {
+		if r, ok = evaluate(t, e, s.obs.tracker.State); !ok {
+			return
+		}
+	} else {
+		n := retries
+		if tr, changed := s.breakers.Begin(t.Name, time.Now()); changed {
+			logCircuit(ctx, t, tr)
+			n = 0
+		}
+		r = runCheck(ctx, t.URL, threshold, n)
+		if ctx.Err() != nil {
+			// Monitor is stopping or t was removed, which says nothing
+			// about its health.
+			return
+		}
+	}
+	if state, _ := checkState(r, w); state == status.Down && s.awaitParents(t) {
+		return
+	}
+	before := s.obs.tracker.State(t.Name)
+	r.State = s.obs.observe(ctx, t, r, w)
+	if t.Expr == "" {
+		if tr, changed := s.breakers.Record(t.Name, isDownState(r.State), r.CheckedAt); changed {
+			logCircuit(ctx, t, tr)
+		}
+		r.Circuit = s.breakers.State(t.Name)
+	}
+	s.mu.Lock()
+	delete(s.awaiting, t.Name)
+	// Drop the result if the target was removed meanwhile.
+	_, current := s.byName[t.Name]
+	if current {
+		s.latest[t.Name] = r
+	}
+	s.mu.Unlock()
+	if !current {
+		return
+	}
+	s.onResult(t, r)
+	if r.State != before {
+		for _, name := range s.users(t.Name) {
+			s.sched.Trigger(checkJob(name))
+		}
+	}
+}
+
+// awaitParents has the parents of the failed target t checked, unless they
+// are known to be down already or t is being checked again after them. It
+// reports whether t waits for them, in which case its failure isn't
+// observed yet: t is checked again once they are.
+func (s *session) awaitParents(t config.Target) bool {
+	s.mu.Lock()
+	if _, ok := s.awaiting[t.Name]; ok {
+		s.mu.Unlock()
+		return false
+	}
+	var parents []string
+	for _, name := range t.DependsOn {
+		if _, ok := s.byName[name]; ok && !s.obs.isDown(name) {
+			parents = append(parents, name)
+			s.waiters[name] = append(s.waiters[name], t.Name)
+		}
+	}
+	if len(parents) > 0 {
+		s.awaiting[t.Name] = len(parents)
+	}
+	s.mu.Unlock()
+	for _, name := range parents {
+		s.sched.Trigger(checkJob(name))
+	}
+	return len(parents) > 0
+}
+
+// release checks again the targets that were waiting for name to be
+// checked, once all the parents they wait for have been.
+func (s *session) release(name string) {
+	s.mu.Lock()
+	var ready []string
+	for _, c := range s.waiters[name] {
+		if n, ok := s.awaiting[c]; ok && n > 0 {
+			if s.awaiting[c] = n - 1; n == 1 {
+				ready = append(ready, c)
 			}
-			// Clear the screen
-			cmd := exec.Command("clear")
-			cmd.Stdout = os.Stdout
-			err := cmd.Run()
-			if err != nil {
-				fmt.Println("Unable to clear the screen: ", err)
+		}
+	}
+	delete(s.waiters, name)
+	s.mu.Unlock()
+	for _, c := range ready {
+		s.sched.Trigger(checkJob(c))
+	}
+}
+
+// expr returns the expression of the composite target called name.
+func (s *session) expr(name string) (composite.Expr, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.exprs[name]
+	return e, ok
+}
+
+// users returns the names of the composite targets whose expressions use
+// the target called name.
+func (s *session) users(name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.usedBy[name])
+}
+
+// target returns the target called name.
+func (s *session) target(name string) (config.Target, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.byName[name]
+	return t, ok
+}
+
+// remove stops checking the target called name and reports whether it
+// existed.
+func (s *session) remove(name string) bool {
+	s.mu.Lock()
+	_, ok := s.byName[name]
+	if ok {
+		s.targets = slices.DeleteFunc(s.targets, func(t config.Target) bool { return t.Name == name })
+		delete(s.byName, name)
+		delete(s.awaiting, name)
+		delete(s.latest, name)
+		delete(s.paused, name)
+		if e, composite := s.exprs[name]; composite {
+			for _, used := range e.Targets() {
+				s.usedBy[used] = slices.DeleteFunc(s.usedBy[used], func(u string) bool { return u == name })
+				if len(s.usedBy[used]) == 0 {
+					delete(s.usedBy, used)
+				}
 			}
-			s.Disable()
-			table.Render()
-		} else {
-			for _, url := range urls {
-				checkURL(ctx, url, threshold, retries)
+			delete(s.exprs, name)
+		}
+	}
+	s.mu.Unlock()
+	if !ok {
+		return false
+	}
+	s.sched.Remove(checkJob(name))
+	s.breakers.Remove(name)
+	if s.obs.notifier != nil {
+		// Nobody will see it recover anymore.
+		s.obs.notifier.Resolve(context.Background(), name, time.Now())
+	}
+	// Targets waiting for it to be checked won't hear from it anymore.
+	s.release(name)
+	if s.onRemove != nil {
+		s.onRemove(name)
+	}
+	return true
+}
+
+// pause stops or resumes checking the target called name and reports
+// whether it exists.
+func (s *session) pause(name string, paused bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byName[name]; !ok {
+		return false
+	}
+	if paused {
+		s.paused[name] = true
+	} else {
+		delete(s.paused, name)
+	}
+	return true
+}
+
+// pauseAll stops or resumes checking every target. Background jobs, such as
+// escalations and store flushes, keep running.
+func (s *session) pauseAll(paused bool) {
+	s.mu.Lock()
+	names := make([]string, len(s.targets))
+	for i, t := range s.targets {
+		names[i] = t.Name
+	}
+	s.mu.Unlock()
+	for _, name := range names {
+		s.pause(name, paused)
+	}
+}
+
+func (s *session) isPaused(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused[name]
+}
+
+// results returns the latest result of every target checked so far, in
+// target order.
+func (s *session) results() []checkResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := make([]checkResult, 0, len(s.latest))
+	for _, t := range s.targets {
+		if r, ok := s.latest[t.Name]; ok {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+func monitorTargets(ctx context.Context, targets []config.Target) {
+	if tableOutput() && !accessible && isTerminal(os.Stdout) && isTerminal(os.Stdin) {
+		monitorDashboard(ctx, targets)
+		return
+	}
+
+	obs, err := newObserver()
+	if err != nil {
+		l.ErrorContext(ctx, "failed to set up monitor", "err", err)
+		return
+	}
+	defer obs.wait()
+
+	sess, err := newSession(targets, obs, func(config.Target, checkResult) {})
+	if err == nil {
+		err = startSession(ctx, sess)
+	}
+	if err != nil {
+		l.ErrorContext(ctx, "failed to schedule checks", "err", err)
+		return
+	}
+	if !tableOutput() {
+		sess.sched.Run(ctx)
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sess.sched.Run(ctx)
+	}()
+	// Let checks in flight finish before obs.wait flushes their results.
+	defer func() { <-done }()
+	names := make([]string, len(targets))
+	for i, t := range targets {
+		names[i] = t.Name
+	}
+	s := newProgress(strings.Join(names, ", "))
+	s.Start()
+	defer s.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		results := sess.results()
+		if len(results) == 0 {
+			continue
+		}
+		s.Stop()
+		renderTable(os.Stdout, results, monitorColumns)
+	}
+}
+
+// monitorDashboard runs the checks in the background and shows their
+// results in the full-screen dashboard until the user quits.
+func monitorDashboard(ctx context.Context, targets []config.Target) {
+	// Quitting the dashboard stops the checks and the API and web servers.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	names := make([]string, len(targets))
+	for i, t := range targets {
+		names[i] = t.Name
+	}
+	d := dashboard.New(os.Stdout, names, interval)
+	d.SetStats(statsWindow, thresholdDuration())
+
+	obs, err := newObserver()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	defer obs.wait()
+	recordRecent(d, obs.recorder.st, targets)
+	sess, err := newSession(targets, obs, func(t config.Target, r checkResult) {
+		d.Record(t.Name, dashboardSample(r))
+	})
+	if err == nil {
+		sess.onRemove = d.Remove
+		err = startSession(ctx, sess)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	sched := sess.sched
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sched.Run(ctx)
+	}()
+	// Let checks in flight finish before obs.wait flushes their results.
+	defer func() {
+		cancel()
+		<-done
+	}()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case paused := <-d.PauseToggled():
+				sess.pauseAll(paused)
+			case <-d.Recheck():
+				sched.RunNow()
 			}
 		}
+	}()
+
+	if err := d.Run(ctx, os.Stdin); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// startSession adds the background jobs of a monitor session to its
+// scheduler, pushes its results to the configured sinks and starts the API,
+// web dashboard and metrics if --listen, --web and --metrics are set.
+func startSession(ctx context.Context, sess *session) error {
+	sched, obs := sess.sched, sess.obs
+	if err := addDigest(sched, obs); err != nil {
+		return err
+	}
+	addEscalation(sched, obs, sess.target)
+	addStoreJobs(sched, obs)
+	addSLOAlerts(sched, obs)
+	addBaselineJob(sched, obs)
+	addSinks(sess)
+	if err := startAPI(ctx, sess); err != nil {
+		return err
+	}
+	if err := startMetrics(ctx, sess); err != nil {
+		return err
+	}
+	return startWeb(ctx, sess)
+}
+
+// recordRecent shows the results stored for targets in the last hour, so
+// the dashboard's trends carry over from earlier sessions.
+func recordRecent(d *dashboard.Dashboard, st *store.Store, targets []config.Target) {
+	byURL := make(map[string]string)
+	urls := make([]string, 0, len(targets))
+	for _, t := range targets {
+		byURL[t.URL] = t.Name
+		urls = append(urls, t.URL)
+	}
+	recent, err := st.Query(store.Query{URLs: urls, From: time.Now().Add(-time.Hour)})
+	if err != nil {
+		return
+	}
+	for _, r := range recent {
+		d.Record(byURL[r.URL], dashboard.Sample{Time: r.Time, Up: r.Up, Latency: r.Latency, Err: r.Err, State: string(r.State)})
+	}
+}
+
+func dashboardSample(r checkResult) dashboard.Sample {
+	s := dashboard.Sample{Time: r.CheckedAt, Up: r.Up, Latency: r.Latency, State: string(r.State)}
+	if state, reason := resultState(r); state == status.Down && r.State != status.Maintenance {
+		s.Err = reason
 	}
+	return s
 }
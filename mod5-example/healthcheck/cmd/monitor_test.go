@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/maintenance"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/scheduler"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession_Remove(t *testing.T) {
+	_, sess := newTestAPI(t)
+	assert.NoError(t, sess.add(config.Target{Name: "web", URL: "http://web.test"}))
+	assert.NoError(t, sess.add(config.Target{Name: "site", Expr: "api && web"}))
+	assert.ErrorIs(t, sess.add(config.Target{Name: "web", URL: "http://other.test"}), errDuplicateTarget)
+
+	assert.Equal(t, []string{"site"}, sess.users("api"))
+	assert.True(t, sess.remove("site"))
+	assert.False(t, sess.remove("site"))
+	assert.Empty(t, sess.users("api"), "removed composite targets don't use their targets anymore")
+	_, ok := sess.target("site")
+	assert.False(t, ok)
+	assert.False(t, sess.pause("site", true))
+	assert.True(t, sess.pause("web", true))
+}
+
+func TestSession_RemoveReservedName(t *testing.T) {
+	_, sess := newTestAPI(t)
+	sess.sched.Add(scheduler.Job{Name: "prune", Interval: time.Hour, Background: true, Run: func(context.Context) {}})
+	assert.NoError(t, sess.add(config.Target{Name: "prune", URL: "http://prune.test"}))
+
+	assert.True(t, sess.remove("prune"))
+	assert.False(t, sess.sched.Trigger(checkJob("prune")), "the target's job is gone")
+	assert.True(t, sess.sched.Trigger("prune"), "the store's prune job is left alone")
+}
+
+func TestSession_PauseAll(t *testing.T) {
+	_, sess := newTestAPI(t)
+	assert.NoError(t, sess.add(config.Target{Name: "web", URL: "http://web.test"}))
+	var ran atomic.Int32
+	sess.sched.Add(scheduler.Job{Name: "escalation", Interval: time.Hour, Background: true, Run: func(context.Context) { ran.Add(1) }})
+
+	sess.pauseAll(true)
+	assert.True(t, sess.isPaused("api"))
+	assert.True(t, sess.isPaused("web"))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	sess.sched.Run(ctx)
+	assert.Empty(t, sess.results(), "paused targets aren't checked")
+	assert.EqualValues(t, 1, ran.Load(), "background jobs keep running")
+
+	sess.pauseAll(false)
+	assert.False(t, sess.isPaused("api"))
+	assert.False(t, sess.isPaused("web"))
+}
+
+// runTriggered runs the scheduler of sess paused until the test ends, so
+// that only the jobs it triggers itself run.
+func runTriggered(t *testing.T, sess *session) {
+	sess.sched.Pause()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sess.sched.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// BenchmarkSession measures the checks per second and memory of a monitor
+// session with 10k targets spread over a few local servers. The targets are
+// due far more often than they can be checked, so the workers never idle.
+// Results aren't stored.
+func BenchmarkSession(b *testing.B) {
+	const targets = 10_000
+	defer func(r int, lg *slog.Logger) { retries, l = r, lg }(retries, l)
+	retries = 0
+	l = slog.New(slog.NewTextHandler(io.Discard, nil))
+	var servers [8]*httptest.Server
+	for i := range servers {
+		servers[i] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "OK")
+		}))
+		defer servers[i].Close()
+	}
+
+	ts := make([]config.Target, targets)
+	for i := range ts {
+		ts[i] = config.Target{
+			Name:     fmt.Sprintf("target-%d", i),
+			URL:      fmt.Sprintf("%s/%d", servers[i%len(servers)].URL, i),
+			Interval: 100 * time.Millisecond,
+		}
+	}
+	obs := &observer{
+		tracker: status.NewTracker(status.Options{}),
+		windows: maintenance.NewRegistry(nil, ""),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var checks atomic.Int64
+	n := int64(b.N)
+	sess, err := newSession(ts, obs, func(config.Target, checkResult) {
+		if checks.Add(1) == n {
+			cancel()
+		}
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	start := time.Now()
+	sess.sched.Run(ctx)
+	elapsed := time.Since(start)
+	b.StopTimer()
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	b.ReportMetric(float64(checks.Load())/elapsed.Seconds(), "checks/s")
+	b.ReportMetric(float64(mem.HeapInuse)/(1<<20), "heap-MiB")
+	b.ReportMetric(float64(runtime.NumGoroutine()), "goroutines")
+}
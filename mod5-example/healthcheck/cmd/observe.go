@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/alert"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/baseline"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/maintenance"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+)
+
+// observer turns the check results of a monitor session into state
+// transitions, logs them and sends alerts for them. It also records every
+// result in the store and, with --anomalies, learns each target's normal
+// latency and error rate.
+type observer struct {
+	tracker   *status.Tracker
+	notifier  *alert.Notifier
+	windows   *maintenance.Registry
+	recorder  *recorder
+	baselines *baseline.Baselines
+	// onChange, if set, is called after every state transition.
+	onChange func(config.Target, status.Transition)
+
+	mu sync.Mutex
+	// failed holds the targets whose latest check failed, before the
+	// tracker settles on Down.
+	failed map[string]bool
+}
+
+func newObserver() (*observer, error) {
+	o := &observer{tracker: status.NewTracker(status.Options{
+		DownAfter:     downAfter,
+		UpAfter:       upAfter,
+		FlapWindow:    flapWindow,
+		FlapThreshold: flapThreshold,
+	})}
+	var static []maintenance.Window
+	if cfg != nil {
+		static = cfg.Maintenance
+	}
+	o.windows = maintenance.NewRegistry(static, silences)
+	st, err := openStore()
+	if err != nil {
+		return nil, err
+	}
+	o.recorder = &recorder{st: st}
+	if anomalies {
+		if o.baselines, err = loadBaselines(); err != nil {
+			return nil, err
+		}
+	}
+	if cfg != nil {
+		n, err := alert.New(cfg.Alerts, l)
+		if err != nil {
+			return nil, err
+		}
+		n.Incidents = alert.NewIncidents(incidents)
+		o.notifier = n
+	}
+	return o, nil
+}
+
+// maintenance returns the maintenance window t is in right now, or nil.
+func (o *observer) maintenance(t config.Target) *maintenance.Window {
+	return o.windows.Active(t.Name, t.Tags, time.Now())
+}
+
+// observe records the result of a check of t and returns the target's state.
+// Failures during the maintenance window w count as Maintenance rather than
+// Down, and no alerts are sent for t while w is open. Failures while a
+// target t depends on is down count as Unreachable, which isn't alerted on
+// either. Outside maintenance, checks that look anomalous against t's
+// baseline make an up target Degraded.
+func (o *observer) observe(ctx context.Context, t config.Target, r checkResult, w *maintenance.Window) status.State {
+	state, reason := checkState(r, w)
+	o.setFailed(t.Name, state == status.Down)
+	if state == status.Down {
+		if parent := o.downParent(t); parent != "" {
+			state, reason = status.Unreachable, parent
+		}
+	}
+	if o.recorder != nil {
+		res := storeResult(t, r, w)
+		if state == status.Unreachable {
+			res.State, res.Err = state, reason
+		}
+		o.recorder.record(res)
+	}
+	if o.baselines != nil && w == nil {
+		anomaly := o.baselines.Observe(t.URL, r.CheckedAt, r.Up, r.Latency)
+		if anomaly != "" && state == status.Up {
+			state, reason = status.Degraded, anomaly
+		}
+	}
+	tr, changed := o.tracker.Observe(t.Name, state, r.CheckedAt, reason)
+	if changed {
+		l.InfoContext(ctx, "state changed", "target", t.Name, "url", t.URL, "from", tr.From, "to", tr.To, "reason", tr.Reason)
+		if w != nil || suppressed(tr) {
+			l.InfoContext(ctx, "alert suppressed", "target", t.Name, "to", tr.To)
+			if o.notifier != nil && (tr.To == status.Up || tr.To == status.Degraded) {
+				// A target that recovers without an alert still ends its
+				// incidents, or they would escalate once the window ends.
+				o.notifier.Resolve(ctx, t.Name, tr.Time)
+			}
+		} else if o.notifier != nil {
+			o.notifier.Notify(ctx, tr, t.URL)
+		}
+		if o.onChange != nil {
+			o.onChange(t, tr)
+		}
+	}
+	return o.tracker.State(t.Name)
+}
+
+func (o *observer) setFailed(name string, failed bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.failed == nil {
+		o.failed = make(map[string]bool)
+	}
+	o.failed[name] = failed
+}
+
+// isDown reports whether the target called name is down or unreachable, or
+// its latest check failed.
+func (o *observer) isDown(name string) bool {
+	switch o.tracker.State(name) {
+	case status.Down, status.Unreachable:
+		return true
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.failed[name]
+}
+
+// downParent explains why t is unreachable if one of the targets it depends
+// on is down, or returns "".
+func (o *observer) downParent(t config.Target) string {
+	for _, p := range t.DependsOn {
+		if !o.isDown(p) {
+			continue
+		}
+		if o.tracker.State(p) == status.Unreachable {
+			return fmt.Sprintf("parent %s is unreachable", p)
+		}
+		return fmt.Sprintf("parent %s is down", p)
+	}
+	return ""
+}
+
+// suppressed reports whether tr is left out of alerts because it involves
+// the Unreachable state: becoming unreachable is blamed on the parent,
+// which alerts by itself, and so is recovering from it. Going from
+// Unreachable to Down is alerted, since the target stayed down after its
+// parent recovered.
+func suppressed(tr status.Transition) bool {
+	return tr.To == status.Unreachable || (tr.From == status.Unreachable && tr.To != status.Down)
+}
+
+// wait lets alert deliveries in flight finish and writes the results not
+// stored yet and the learned baselines.
+func (o *observer) wait() {
+	if o.recorder != nil {
+		o.recorder.flush(context.Background())
+	}
+	if o.baselines != nil {
+		saveBaselines(context.Background(), o)
+	}
+	if o.notifier != nil {
+		o.notifier.Wait()
+	}
+}
+
+// checkState is resultState, except that failures during the maintenance
+// window w, if any, are Maintenance rather than Down.
+func checkState(r checkResult, w *maintenance.Window) (status.State, string) {
+	state, reason := resultState(r)
+	if w != nil && state == status.Down {
+		return status.Maintenance, maintenanceReason(w)
+	}
+	return state, reason
+}
+
+func maintenanceReason(w *maintenance.Window) string {
+	if w.Comment == "" {
+		return "maintenance window " + w.ID
+	}
+	return fmt.Sprintf("maintenance window %s: %s", w.ID, w.Comment)
+}
+
+// resultState derives a target's state from a single check: Down when the
+// request failed or didn't return 200 OK, Degraded when it was slower than
+// --threshold and Up otherwise.
+func resultState(r checkResult) (status.State, string) {
+	switch {
+	case r.Err != nil:
+		return status.Down, r.Err.Error()
+	case !r.Up:
+		return status.Down, fmt.Sprintf("unexpected status code %d", r.StatusCode)
+	case r.Latency.Seconds() > threshold:
+		return status.Degraded, fmt.Sprintf("response time %s exceeded threshold %s", r.Latency.Round(time.Millisecond), thresholdDuration())
+	default:
+		return status.Up, ""
+	}
+}
+
+// thresholdDuration returns --threshold as a duration. It also serves as the
+// Apdex threshold in latency stats.
+func thresholdDuration() time.Duration {
+	return time.Duration(threshold * float64(time.Second))
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"golang.org/x/term"
+)
+
+var (
+	noColor    bool
+	accessible bool
+)
+
+// accessibleInterval is how often accessible mode reports that a check is
+// still running.
+var accessibleInterval = 3 * time.Second
+
+// progress indicates that a check is in flight.
+type progress interface {
+	Start()
+	Stop()
+}
+
+// newProgress returns the progress indicator for checking target: periodic
+// status lines in accessible mode, a spinner when stdout is a terminal and
+// nothing at all otherwise, so no control characters end up in logs.
+func newProgress(target string) progress {
+	return progressFor(target, isTerminal(os.Stdout), os.Stderr)
+}
+
+// progressFor returns the progress indicator for checking target, writing
+// accessible status lines to w.
+func progressFor(target string, terminal bool, w io.Writer) progress {
+	if accessible {
+		return &statusLines{target: target, w: w}
+	}
+	if !terminal {
+		return noProgress{}
+	}
+	return spinner.New(spinner.CharSets[14], 100*time.Millisecond)
+}
+
+type noProgress struct{}
+
+func (noProgress) Start() {}
+func (noProgress) Stop()  {}
+
+// statusLines prints a plain "still checking" line every
+// accessibleInterval, which screen readers announce as ordinary text.
+type statusLines struct {
+	target string
+	w      io.Writer
+
+	once sync.Once
+	done chan struct{}
+}
+
+func (p *statusLines) Start() {
+	// A stopped indicator can be started again.
+	p.once = sync.Once{}
+	done := make(chan struct{})
+	p.done = done
+	start := time.Now()
+	go func() {
+		ticker := time.NewTicker(accessibleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				fmt.Fprintf(p.w, "still checking %s (%s)\n", p.target, time.Since(start).Round(time.Second))
+			}
+		}
+	}()
+}
+
+func (p *statusLines) Stop() {
+	if p.done == nil {
+		return
+	}
+	p.once.Do(func() { close(p.done) })
+}
+
+// configureColor turns colors off when NO_COLOR is set, --no-color or
+// --accessible is passed, or stdout is not a terminal.
+func configureColor() {
+	if !useColor(isTerminal(os.Stdout)) {
+		color.NoColor = true
+	}
+}
+
+// useColor reports whether output may be colored, given whether stdout is
+// a terminal.
+func useColor(terminal bool) bool {
+	return terminal && !noColor && !accessible && os.Getenv("NO_COLOR") == ""
+}
+
+// statusText renders an Up or Down result for tables.
+func statusText(up bool) string {
+	if up {
+		return stateText(status.Up)
+	}
+	return stateText(status.Down)
+}
+
+// stateStyles holds the color and the accessible symbol of each state.
+var stateStyles = map[status.State]struct {
+	color  color.Attribute
+	symbol string
+}{
+	status.Up:          {color.FgGreen, "✓"},
+	status.Down:        {color.FgRed, "✗"},
+	status.Degraded:    {color.FgYellow, "!"},
+	status.Flapping:    {color.FgMagenta, "~"},
+	status.Maintenance: {color.FgBlue, "#"},
+	status.Unreachable: {color.FgHiBlack, "-"},
+	status.Unknown:     {color.FgWhite, "?"},
+}
+
+// stateText renders a target's state for tables. Accessible mode spells
+// the state out with a symbol instead of relying on color.
+func stateText(s status.State) string {
+	style := stateStyles[s]
+	label := strings.ToUpper(string(s[:1])) + string(s[1:])
+	if accessible {
+		return style.symbol + " " + label
+	}
+	return color.New(style.color).Sprint(label)
+}
+
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUseColor(t *testing.T) {
+	defer func(n, a bool) { noColor, accessible = n, a }(noColor, accessible)
+	tests := []struct {
+		name       string
+		terminal   bool
+		noColor    bool
+		accessible bool
+		env        string
+		expected   bool
+	}{
+		{name: "Terminal", terminal: true, expected: true},
+		{name: "Piped", terminal: false, expected: false},
+		{name: "--no-color", terminal: true, noColor: true, expected: false},
+		{name: "NO_COLOR", terminal: true, env: "1", expected: false},
+		{name: "--accessible", terminal: true, accessible: true, expected: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			noColor, accessible = tc.noColor, tc.accessible
+			t.Setenv("NO_COLOR", tc.env)
+			assert.Equal(t, tc.expected, useColor(tc.terminal))
+		})
+	}
+}
+
+func TestConfigureColor(t *testing.T) {
+	defer func(c bool) { color.NoColor = c }(color.NoColor)
+	color.NoColor = false
+	// Test output is never a terminal.
+	configureColor()
+	assert.True(t, color.NoColor)
+}
+
+func TestStateText(t *testing.T) {
+	defer func(c, a bool) { color.NoColor, accessible = c, a }(color.NoColor, accessible)
+	tests := []struct {
+		mode       string
+		noColor    bool
+		accessible bool
+		expected   map[status.State]string
+	}{
+		{
+			mode: "Color",
+			expected: map[status.State]string{
+				status.Up:          "\x1b[32mUp\x1b[0m",
+				status.Down:        "\x1b[31mDown\x1b[0m",
+				status.Degraded:    "\x1b[33mDegraded\x1b[0m",
+				status.Maintenance: "\x1b[34mMaintenance\x1b[0m",
+			},
+		},
+		{
+			mode:    "No color",
+			noColor: true,
+			expected: map[status.State]string{
+				status.Up:          "Up",
+				status.Down:        "Down",
+				status.Degraded:    "Degraded",
+				status.Maintenance: "Maintenance",
+			},
+		},
+		{
+			mode:       "Accessible",
+			accessible: true,
+			expected: map[status.State]string{
+				status.Up:          "✓ Up",
+				status.Down:        "✗ Down",
+				status.Degraded:    "! Degraded",
+				status.Flapping:    "~ Flapping",
+				status.Maintenance: "# Maintenance",
+				status.Unreachable: "- Unreachable",
+				status.Unknown:     "? Unknown",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.mode, func(t *testing.T) {
+			color.NoColor, accessible = tc.noColor, tc.accessible
+			for state, expected := range tc.expected {
+				assert.Equal(t, expected, stateText(state), state)
+			}
+			assert.Equal(t, tc.expected[status.Up], statusText(true))
+			assert.Equal(t, tc.expected[status.Down], statusText(false))
+		})
+	}
+}
+
+func TestProgressFor(t *testing.T) {
+	defer func(a bool) { accessible = a }(accessible)
+	tests := []struct {
+		name       string
+		terminal   bool
+		accessible bool
+		expected   progress
+	}{
+		{name: "Terminal", terminal: true, expected: &spinner.Spinner{}},
+		{name: "Piped", terminal: false, expected: noProgress{}},
+		{name: "Accessible terminal", terminal: true, accessible: true, expected: &statusLines{}},
+		{name: "Accessible piped", terminal: false, accessible: true, expected: &statusLines{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			accessible = tc.accessible
+			assert.IsType(t, tc.expected, progressFor("api", tc.terminal, &bytes.Buffer{}))
+		})
+	}
+}
+
+// lockedBuffer is a bytes.Buffer that status lines can write to while the
+// test reads it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.buf.Len() == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(b.buf.String(), "\n"), "\n")
+}
+
+func TestStatusLines(t *testing.T) {
+	defer func(d time.Duration) { accessibleInterval = d }(accessibleInterval)
+	accessibleInterval = 10 * time.Millisecond
+	var buf lockedBuffer
+	p := &statusLines{target: "api", w: &buf}
+	p.Stop() // stopping before starting is a no-op
+
+	// A stopped indicator keeps reporting when it is started again.
+	for run := 1; run <= 2; run++ {
+		before := len(buf.lines())
+		p.Start()
+		assert.Eventually(t, func() bool { return len(buf.lines()) >= before+2 }, time.Second, time.Millisecond, "run %d", run)
+		p.Stop()
+		p.Stop()
+	}
+
+	time.Sleep(3 * accessibleInterval)
+	lines := buf.lines()
+	time.Sleep(3 * accessibleInterval)
+	assert.Equal(t, lines, buf.lines(), "no lines once stopped")
+	for _, line := range lines {
+		assert.Equal(t, "still checking api (0s)", line)
+	}
+}
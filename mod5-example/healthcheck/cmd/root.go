@@ -6,16 +6,24 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
 	"github.com/marianina8/gocodecli/mod5-example/healthcheck/logger"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/tracing"
 	"github.com/spf13/cobra"
 )
 
 var (
-	logFile string
-	l       *slog.Logger
+	logFile    string
+	configFile string
+	silences   string
+	incidents  string
+	notesFile  string
+	cfg        *config.Config
+	l          *slog.Logger
 
 	threshold float64
 	retries   int
@@ -46,15 +54,18 @@ analysis."`,
 			os.Exit(0)
 		}
 	},
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		time.Local = time.UTC
 		actualSilent := silent
 		actualVerbose := verbose
-		if output == "table" {
+		if tableOutput() {
 			actualSilent = true
 			actualVerbose = false
 		}
-		l = logger.New(logFile, actualVerbose, actualSilent, output)
+		// Records logged with the context of a check carry its trace.
+		l = slog.New(tracing.NewHandler(logger.New(logFile, actualVerbose, actualSilent, output).Handler()))
+		configureColor()
+		return setupTracing(cmd.Context())
 	},
 }
 
@@ -62,19 +73,45 @@ func Execute() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	if err := rootCmd.ExecuteContext(ctx); err != nil {
+	err := rootCmd.ExecuteContext(ctx)
+	shutdownTracing()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
+// loadConfig reads the --config file, if one was given, into cfg.
+func loadConfig() error {
+	if configFile == "" {
+		return nil
+	}
+	c, err := config.Load(configFile)
+	if err != nil {
+		return err
+	}
+	cfg = c
+	return nil
+}
+
 func init() {
 	rootCmd.PersistentFlags().StringVar(&logFile, "logfile", "healthcheck.log", "File to log output to")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "YAML file describing targets and their settings")
+	rootCmd.PersistentFlags().StringVar(&silences, "silences", "healthcheck.silences.json", "File the silence command saves maintenance windows to")
+	rootCmd.PersistentFlags().StringVar(&storeFile, "store", "healthcheck.db", "Database check results are recorded in")
+	rootCmd.PersistentFlags().StringVar(&incidents, "incidents", "healthcheck.incidents.json", "File open alert incidents are kept in")
+	rootCmd.PersistentFlags().StringVar(&notesFile, "notes", "healthcheck.notes.json", "File status page incident notes are kept in")
 	rootCmd.PersistentFlags().Float64Var(&threshold, "threshold", 0.5, "Threshold value for considering a response to be too slow (in seconds)")
 	rootCmd.PersistentFlags().IntVar(&retries, "retries", 3, "Number of retries for a failed request")
+	rootCmd.PersistentFlags().DurationVar(&requestTimeout, "timeout", 10*time.Second, "Maximum time a single request may take (0 disables it)")
 	rootCmd.PersistentFlags().BoolVar(&silent, "silent", false, "Run in silent mode without stdout output")
+	rootCmd.PersistentFlags().StringVar(&otlpEndpoint, "otlp-endpoint", "", "OTLP/HTTP collector to export check traces to, such as http://localhost:4318 (default $OTEL_EXPORTER_OTLP_ENDPOINT)")
 	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Run in verbose mode.  Overrides silent mode")
 	rootCmd.Flags().BoolVar(&versionFlag, "version", false, "Print version")
 
-	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "", "Output format (json/text/table)")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "", "Output format (json/text/table/wide)")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output (also honors the NO_COLOR environment variable)")
+	rootCmd.PersistentFlags().BoolVar(&accessible, "accessible", false, "Plain output for screen readers: no colors or spinners, states spelled out")
+	rootCmd.PersistentFlags().StringSliceVar(&columns, "columns", nil, "Table columns to show ("+strings.Join(allColumns, ",")+")")
+	rootCmd.PersistentFlags().StringVar(&sortBy, "sort-by", "", "Sort table rows by latency (slowest first), status (down first) or url")
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/marianina8/gocodecli/mod5-example/healthcheck/logger"
@@ -11,8 +12,12 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func setupTestLogger() {
-	l = logger.New(logFile, false, true, output)
+// setupTestLogger logs to a file in a temporary directory of t, so test
+// runs don't write into the source tree, until t ends.
+func setupTestLogger(t *testing.T) {
+	prev := l
+	t.Cleanup(func() { l = prev })
+	l = logger.New(filepath.Join(t.TempDir(), "healthcheck.log"), false, true, output)
 }
 
 func executeCommandC(cmd *cobra.Command, args ...string) (string, error) {
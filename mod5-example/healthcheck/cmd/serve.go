@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/metrics"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/probe"
+	"github.com/spf13/cobra"
+)
+
+var probeAddr string
+
+const (
+	// defaultModule is the module of /probe requests that don't name one.
+	defaultModule = "http_2xx"
+	// scrapeTimeoutOffset is kept free of the scraper's timeout, so a
+	// probe that times out still gets its metrics back in time.
+	scrapeTimeoutOffset = 500 * time.Millisecond
+)
+
+// builtinModules are available unless the config file defines modules of
+// the same name.
+var builtinModules = map[string]config.Module{
+	"http_2xx":    {Prober: "http", Timeout: 5 * time.Second},
+	"tcp_connect": {Prober: "tcp", Timeout: 5 * time.Second},
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve on-demand probes for Prometheus at /probe",
+	Long: `Serves /probe?target=...&module=..., which runs a single check of the target
+with the named module and returns its outcome as Prometheus metrics. The
+endpoint and the metrics are compatible with blackbox_exporter, so existing
+scrape configs, dashboards and alerts keep working.
+
+Modules are listed under modules in the --config file, written like
+blackbox_exporter modules with an http, tcp or dns prober:
+
+  modules:
+    http_2xx:
+      prober: http
+      timeout: 5s
+      http:
+        valid_status_codes: [200]
+        fail_if_not_ssl: true
+        fail_if_body_not_matches_regexp: ["ok"]
+    tls_connect:
+      prober: tcp
+      tcp:
+        tls: true
+    dns_example:
+      prober: dns
+      dns:
+        query_name: example.com
+        query_type: A
+
+http_2xx and tcp_connect are built in. Requests without a module use
+http_2xx. Probes are cut short to fit the timeout Prometheus sends in
+X-Prometheus-Scrape-Timeout-Seconds.`,
+	Example: `  healthcheck serve --config blackbox.yaml
+  curl 'localhost:9115/probe?target=https://example.com&module=http_2xx'`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if (tlsCert == "") != (tlsKey == "") {
+			return fmt.Errorf("--tls-cert and --tls-key must be set together")
+		}
+		return loadConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /probe", probeHandler(probeModules()))
+		if err := serve(ctx, "probe", probeAddr, mux); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Serving probes on %s\n", probeAddr)
+		<-ctx.Done()
+		return nil
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&probeAddr, "listen", ":9115", "Address to serve probes on")
+	serveCmd.Flags().StringVar(&tlsCert, "tls-cert", "", "Certificate file to serve probes over TLS")
+	serveCmd.Flags().StringVar(&tlsKey, "tls-key", "", "Private key file of --tls-cert")
+	rootCmd.AddCommand(serveCmd)
+}
+
+// probeModules returns the built-in modules and those of the config file.
+func probeModules() map[string]config.Module {
+	modules := make(map[string]config.Module)
+	for name, m := range builtinModules {
+		modules[name] = m
+	}
+	if cfg != nil {
+		for name, m := range cfg.Modules {
+			modules[name] = m
+		}
+	}
+	return modules
+}
+
+// probeHandler runs the probes requested at /probe with modules.
+func probeHandler(modules map[string]config.Module) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("target")
+		if target == "" {
+			http.Error(w, "Target parameter is missing", http.StatusBadRequest)
+			return
+		}
+		name := r.URL.Query().Get("module")
+		if name == "" {
+			name = defaultModule
+		}
+		m, ok := modules[name]
+		if !ok {
+			http.Error(w, fmt.Sprintf("Unknown module %q", name), http.StatusBadRequest)
+			return
+		}
+		if v := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); v != "" {
+			seconds, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid scrape timeout %q", v), http.StatusBadRequest)
+				return
+			}
+			if scrape := time.Duration(seconds*float64(time.Second)) - scrapeTimeoutOffset; scrape > 0 && scrape < m.Timeout {
+				m.Timeout = scrape
+			}
+		}
+
+		res := probe.Run(r.Context(), target, m)
+		if res.Success {
+			l.InfoContext(r.Context(), "probe succeeded", "target", target, "module", name, "duration", res.Duration)
+		} else {
+			l.WarnContext(r.Context(), "probe failed", "target", target, "module", name, "duration", res.Duration, "err", res.Err)
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		metrics.WriteGauges(w, res.Gauges)
+	}
+}
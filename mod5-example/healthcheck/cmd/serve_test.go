@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeHandler(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second)
+	}))
+	defer target.Close()
+	modules := probeModules()
+	modules["http_slow"] = config.Module{Prober: "http", Timeout: 5 * time.Second}
+	srv := httptest.NewServer(probeHandler(modules))
+	defer srv.Close()
+
+	tests := []struct {
+		name          string
+		query         url.Values
+		scrapeTimeout string
+		code          int
+		expected      string
+	}{
+		{"Missing target", url.Values{}, "", http.StatusBadRequest, "Target parameter is missing"},
+		{"Unknown module", url.Values{"target": {target.URL}, "module": {"icmp"}}, "", http.StatusBadRequest, `Unknown module "icmp"`},
+		{"Invalid scrape timeout", url.Values{"target": {target.URL}}, "soon", http.StatusBadRequest, `Invalid scrape timeout "soon"`},
+		{"Default module", url.Values{"target": {target.URL}}, "", http.StatusOK, "probe_success 1"},
+		{"Scrape timeout", url.Values{"target": {target.URL}, "module": {"http_slow"}}, "1.2", http.StatusOK, "probe_success 0"},
+		{"Builtin tcp", url.Values{"target": {target.Listener.Addr().String()}, "module": {"tcp_connect"}}, "", http.StatusOK, "probe_success 1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"?"+tc.query.Encode(), nil)
+			if tc.scrapeTimeout != "" {
+				req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", tc.scrapeTimeout)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tc.code, resp.StatusCode)
+			assert.Contains(t, string(body), tc.expected)
+		})
+	}
+}
+
+func TestProbeModules(t *testing.T) {
+	defer func(c *config.Config) { cfg = c }(cfg)
+	cfg = &config.Config{Modules: map[string]config.Module{
+		"http_2xx": {Prober: "http", HTTP: config.HTTPProbe{FailIfNotSSL: true}},
+		"dns_test": {Prober: "dns"},
+	}}
+	modules := probeModules()
+	assert.True(t, modules["http_2xx"].HTTP.FailIfNotSSL, "the config file overrides builtin modules")
+	assert.Contains(t, modules, "tcp_connect")
+	assert.Contains(t, modules, "dns_test")
+}
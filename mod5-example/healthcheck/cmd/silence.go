@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/maintenance"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	silenceSelector string
+	silenceTargets  []string
+	silenceFor      time.Duration
+	silenceStart    string
+	silenceCron     string
+	silenceComment  string
+)
+
+var silenceCmd = &cobra.Command{
+	Use:   "silence",
+	Short: "Manage maintenance windows that silence alerts",
+	Long: `Maintenance windows silence alerts for matching targets. Failures during a
+window are recorded as maintenance instead of down and don't count against
+uptime. Windows added here are saved to the --silences file, which a running
+monitor picks up within seconds. Planned windows can also be listed under
+maintenance in the --config file.`,
+}
+
+var silenceAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a maintenance window",
+	Example: `  healthcheck silence add --selector team=payments --for 30m --comment "deploy"
+  healthcheck silence add --target api --start 2024-05-01T22:00:00Z --for 2h
+  healthcheck silence add --selector env=staging --cron "0 2 * * 0" --for 1h`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w, err := newWindow(time.Now())
+		if err != nil {
+			return err
+		}
+		if err := updateSilences(func(windows []maintenance.Window) []maintenance.Window {
+			return append(windows, w)
+		}); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Added silence %s\n", w.ID)
+		return nil
+	},
+}
+
+var silenceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List maintenance windows",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		windows, err := maintenance.Load(silences)
+		if err != nil {
+			return err
+		}
+		if cfg != nil {
+			windows = append(append([]maintenance.Window{}, cfg.Maintenance...), windows...)
+		}
+		return printWindows(cmd.OutOrStdout(), windows, time.Now())
+	},
+}
+
+var silenceRemoveCmd = &cobra.Command{
+	Use:     "remove <id>...",
+	Aliases: []string{"rm"},
+	Short:   "Remove maintenance windows by ID",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ids := make(map[string]bool)
+		for _, id := range args {
+			ids[id] = true
+		}
+		var removed int
+		err := updateSilences(func(windows []maintenance.Window) []maintenance.Window {
+			kept := windows[:0]
+			for _, w := range windows {
+				if ids[w.ID] {
+					removed++
+					continue
+				}
+				kept = append(kept, w)
+			}
+			return kept
+		})
+		if err != nil {
+			return err
+		}
+		if removed < len(ids) {
+			return fmt.Errorf("removed %d of %d silences; the others were not found in %s", removed, len(ids), silences)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Removed %d silence(s)\n", removed)
+		return nil
+	},
+}
+
+func init() {
+	silenceAddCmd.Flags().StringVar(&silenceSelector, "selector", "", "Tags a target must have, as key=value pairs separated by commas")
+	silenceAddCmd.Flags().StringSliceVar(&silenceTargets, "target", nil, "Names of targets to silence")
+	silenceAddCmd.Flags().DurationVar(&silenceFor, "for", time.Hour, "How long the window lasts")
+	silenceAddCmd.Flags().StringVar(&silenceStart, "start", "", "When the window starts, in RFC 3339 format (default now)")
+	silenceAddCmd.Flags().StringVar(&silenceCron, "cron", "", "Cron expression that makes the window recur, lasting --for each time")
+	silenceAddCmd.Flags().StringVar(&silenceComment, "comment", "", "Why the targets are silenced")
+	silenceCmd.AddCommand(silenceAddCmd, silenceListCmd, silenceRemoveCmd)
+	rootCmd.AddCommand(silenceCmd)
+}
+
+// newWindow builds the window described by the silence add flags.
+func newWindow(now time.Time) (maintenance.Window, error) {
+	w := maintenance.Window{
+		ID:       maintenance.NewID(),
+		Targets:  silenceTargets,
+		Selector: silenceSelector,
+		Cron:     silenceCron,
+		Comment:  silenceComment,
+	}
+	start := now
+	if silenceStart != "" {
+		t, err := time.Parse(time.RFC3339, silenceStart)
+		if err != nil {
+			return w, fmt.Errorf("invalid --start %q: expected a time such as 2024-05-01T22:00:00Z", silenceStart)
+		}
+		start = t
+	}
+	if w.Cron != "" {
+		w.Duration = silenceFor
+		if silenceStart != "" {
+			w.Start = start
+		}
+	} else {
+		w.Start, w.End = start, start.Add(silenceFor)
+	}
+	if err := w.Validate(); err != nil {
+		return w, err
+	}
+	return w, nil
+}
+
+// updateSilences applies change to the windows in the --silences file,
+// dropping those that have already ended.
+func updateSilences(change func([]maintenance.Window) []maintenance.Window) error {
+	windows, err := maintenance.Load(silences)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	current := windows[:0]
+	for _, w := range windows {
+		if !w.Expired(now) {
+			current = append(current, w)
+		}
+	}
+	return maintenance.Save(silences, change(current))
+}
+
+func printWindows(w io.Writer, windows []maintenance.Window, now time.Time) error {
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(windows)
+	}
+	if len(windows) == 0 {
+		fmt.Fprintln(w, "No maintenance windows.")
+		return nil
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"ID", "Scope", "When", "Active", "Comment"})
+	for _, mw := range windows {
+		var scope []string
+		if len(mw.Targets) > 0 {
+			scope = append(scope, strings.Join(mw.Targets, ","))
+		}
+		if mw.Selector != "" {
+			scope = append(scope, mw.Selector)
+		}
+		active := "no"
+		if mw.Active(now) {
+			active = "yes"
+		}
+		table.Append([]string{mw.ID, strings.Join(scope, " "), windowSchedule(mw), active, mw.Comment})
+	}
+	table.Render()
+	return nil
+}
+
+// windowSchedule describes when a window is open.
+func windowSchedule(w maintenance.Window) string {
+	const layout = "01/02/2006 03:04PM"
+	if w.Cron != "" {
+		return fmt.Sprintf("%q for %s", w.Cron, w.Duration)
+	}
+	return fmt.Sprintf("%s - %s", w.Start.UTC().Format(layout), w.End.UTC().Format(layout))
+}
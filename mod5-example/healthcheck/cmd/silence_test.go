@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/maintenance"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWindow(t *testing.T) {
+	now := time.Date(2024, 5, 1, 22, 0, 0, 0, time.UTC)
+	silenceSelector, silenceFor, silenceComment = "team=payments", 30*time.Minute, "deploy"
+	defer func() { silenceSelector, silenceFor, silenceComment = "", time.Hour, "" }()
+
+	w, err := newWindow(now)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, w.ID)
+	assert.Equal(t, now, w.Start)
+	assert.Equal(t, now.Add(30*time.Minute), w.End)
+	assert.Equal(t, "deploy", w.Comment)
+
+	silenceSelector = ""
+	_, err = newWindow(now)
+	assert.ErrorContains(t, err, "needs targets or a selector")
+}
+
+func TestUpdateSilences(t *testing.T) {
+	silences = filepath.Join(t.TempDir(), "silences.json")
+	defer func() { silences = "healthcheck.silences.json" }()
+
+	now := time.Now()
+	assert.NoError(t, maintenance.Save(silences, []maintenance.Window{
+		{ID: "old", Targets: []string{"api"}, Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour)},
+	}))
+	assert.NoError(t, updateSilences(func(ws []maintenance.Window) []maintenance.Window {
+		return append(ws, maintenance.Window{ID: "new", Targets: []string{"api"}, Start: now, End: now.Add(time.Hour)})
+	}))
+
+	windows, err := maintenance.Load(silences)
+	assert.NoError(t, err)
+	assert.Len(t, windows, 1, "expired windows should be dropped")
+	assert.Equal(t, "new", windows[0].ID)
+}
+
+func TestObserve_Maintenance(t *testing.T) {
+	l = slog.New(slog.NewTextHandler(io.Discard, nil))
+	obs := &observer{tracker: status.NewTracker(status.Options{})}
+	target := config.Target{Name: "api", URL: "http://api"}
+	w := &maintenance.Window{ID: "deploy", Comment: "deploy"}
+	failed := checkResult{Err: errors.New("connection refused"), CheckedAt: time.Now()}
+
+	assert.Equal(t, status.Maintenance, obs.observe(context.Background(), target, failed, w))
+	assert.Equal(t, status.Down, obs.observe(context.Background(), target, failed, nil), "failures after the window count as down")
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/scheduler"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/sink"
+)
+
+// addSinks pushes the results of sess to the sinks in the config file, each
+// flushed by its own job. It must be called before the session's scheduler
+// runs.
+func addSinks(sess *session) {
+	if cfg == nil || len(cfg.Sinks) == 0 {
+		return
+	}
+	sinks := make([]*sink.Sink, len(cfg.Sinks))
+	for i, c := range cfg.Sinks {
+		s := sink.New(c)
+		sinks[i] = s
+		sess.sched.Add(scheduler.Job{
+			Name:       "sink " + s.Name(),
+			Background: true,
+			Interval:   c.FlushInterval,
+			Run: func(ctx context.Context) {
+				if err := s.Flush(ctx); err != nil {
+					l.WarnContext(ctx, "failed to push results", "sink", s.Name(), "err", err)
+				}
+			},
+		})
+	}
+	onResult := sess.onResult
+	sess.onResult = func(t config.Target, r checkResult) {
+		onResult(t, r)
+		res := sinkResult(t, r)
+		for _, s := range sinks {
+			s.Push(res)
+		}
+	}
+}
+
+func sinkResult(t config.Target, r checkResult) sink.Result {
+	return sink.Result{
+		Time:       r.CheckedAt,
+		Target:     t.Name,
+		URL:        r.URL,
+		Tags:       t.Tags,
+		Up:         r.Up,
+		StatusCode: r.StatusCode,
+		Latency:    r.Latency,
+	}
+}
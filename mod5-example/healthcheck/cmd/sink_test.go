@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddSinks(t *testing.T) {
+	httpmock.ActivateNonDefault(checkClient)
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(http.MethodGet, "http://api.test", httpmock.NewStringResponder(200, "OK"))
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	defer func(c *config.Config) { cfg = c }(cfg)
+	cfg = &config.Config{Sinks: []config.Sink{{
+		Name: "statsd", Type: "statsd", Address: conn.LocalAddr().String(), Prefix: "healthcheck",
+		FlushInterval: 20 * time.Millisecond, BufferSize: 10,
+	}}}
+
+	_, sess := newTestAPI(t)
+	var results atomic.Int32
+	sess.onResult = func(config.Target, checkResult) { results.Add(1) }
+	addSinks(sess)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sess.sched.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	buf := make([]byte, 1500)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(buf[:n]), "\n")
+	assert.Equal(t, "healthcheck.api.up:1|g", lines[0])
+	assert.Equal(t, "healthcheck.api.status.200:1|c", lines[2])
+	assert.Positive(t, results.Load(), "the previous result hook still runs")
+}
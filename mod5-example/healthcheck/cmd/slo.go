@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/scheduler"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/slo"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// sloInterval is how often monitor checks the burn rate of every SLO.
+const sloInterval = time.Minute
+
+var (
+	sloObjective float64
+	sloLatency   time.Duration
+	sloWindow    time.Duration
+)
+
+var sloCmd = &cobra.Command{
+	Use:   "slo [urls]",
+	Short: "Report attainment, error budget and burn rate of service level objectives",
+	Long: `Computes how well targets meet the service level objectives listed under
+slos in the --config file, from the recorded check results. For each SLO and
+target it reports the attainment over the SLO's rolling window, how much of
+the error budget is left and how fast it burned over the burn window.
+
+URLs given as arguments are reported against an ad-hoc objective set with
+--objective, --latency and --window.`,
+	Example: `  healthcheck slo --config healthcheck.yaml
+  healthcheck slo --objective 99.9 --window 720h https://api.example.com`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		if len(args) == 0 && (cfg == nil || len(cfg.SLOs) == 0) {
+			return errors.New("no SLOs to report: list them under slos in the --config file or pass URLs with --objective")
+		}
+		if sloObjective <= 0 || sloObjective >= 100 {
+			return errors.New("--objective must be between 0 and 100")
+		}
+		for _, url := range args {
+			if err := isValidURL(url); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := openStore()
+		if err != nil {
+			return err
+		}
+		var reports []slo.Report
+		for _, p := range sloPairs(args) {
+			r, err := slo.Evaluate(st, p.def, p.target, time.Now())
+			if err != nil {
+				return err
+			}
+			reports = append(reports, r)
+		}
+		return printSLOs(cmd.OutOrStdout(), reports)
+	},
+}
+
+func init() {
+	sloCmd.Flags().Float64Var(&sloObjective, "objective", 99.9, "Percentage of good checks required for URLs given as arguments")
+	sloCmd.Flags().DurationVar(&sloLatency, "latency", 0, "Slowest response that still counts as good for URLs given as arguments")
+	sloCmd.Flags().DurationVar(&sloWindow, "window", 30*24*time.Hour, "Rolling window of the objective for URLs given as arguments")
+	rootCmd.AddCommand(sloCmd)
+}
+
+// sloPair is an SLO applied to one of its targets.
+type sloPair struct {
+	def    config.SLO
+	target config.Target
+}
+
+// sloPairs lists the SLOs of the config file for each of their targets,
+// followed by the ad-hoc objective for urls.
+func sloPairs(urls []string) []sloPair {
+	var pairs []sloPair
+	if cfg != nil {
+		for _, def := range cfg.SLOs {
+			for _, t := range cfg.Targets {
+				if len(def.Targets) == 0 || contains(def.Targets, t.Name) {
+					pairs = append(pairs, sloPair{def, t})
+				}
+			}
+		}
+	}
+	adhoc := config.SLO{
+		Name:       "adhoc",
+		Objective:  sloObjective,
+		Latency:    sloLatency,
+		Window:     sloWindow,
+		BurnWindow: time.Hour,
+		BurnRate:   14.4,
+	}
+	for _, url := range urls {
+		pairs = append(pairs, sloPair{adhoc, config.Target{Name: url, URL: url}})
+	}
+	return pairs
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+var sloStatusColors = map[string]color.Attribute{
+	"ok":       color.FgGreen,
+	"burning":  color.FgYellow,
+	"breached": color.FgRed,
+	"no data":  color.FgWhite,
+}
+
+func printSLOs(w io.Writer, reports []slo.Report) error {
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reports)
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"SLO", "Target", "Objective", "Window", "Attainment", "Budget Left", "Burn Rate", "Status"})
+	for _, r := range reports {
+		objective := fmt.Sprintf("%g%%", r.Objective)
+		if r.Latency > 0 {
+			objective += " < " + r.Latency.String()
+		}
+		attainment, budget, burn := "-", "-", "-"
+		if r.Checks > 0 {
+			attainment = fmt.Sprintf("%.3f%%", r.Attainment)
+			budget = fmt.Sprintf("%.1f%%", r.Budget)
+			burn = fmt.Sprintf("%.1fx (%s)", r.BurnRate, formatWindow(r.BurnWindow))
+		}
+		st := r.Status()
+		table.Append([]string{r.SLO, r.Target, objective, formatWindow(r.Window), attainment, budget, burn, color.New(sloStatusColors[st]).Sprint(st)})
+	}
+	table.Render()
+	return nil
+}
+
+// formatWindow writes whole days as such, since SLO windows usually are.
+func formatWindow(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d >= day && d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.String()
+}
+
+// addSLOAlerts checks the burn rate of every SLO in the config file once a
+// minute and alerts when an SLO starts or stops burning. SLOs appear in
+// alerts as targets named "slo/<slo>/<target>", in state "burning" or "up".
+func addSLOAlerts(sched *scheduler.Scheduler, obs *observer) {
+	if cfg == nil || len(cfg.SLOs) == 0 || obs.notifier == nil || obs.recorder == nil {
+		return
+	}
+	tracker := status.NewTracker(status.Options{})
+	pairs := sloPairs(nil)
+	sched.Add(scheduler.Job{
+		Name:       "slo",
+		Background: true,
+		Interval:   sloInterval,
+		Run: func(ctx context.Context) {
+			checkBurnRates(ctx, obs.recorder.st, tracker, obs, pairs)
+		},
+	})
+}
+
+func checkBurnRates(ctx context.Context, st *store.Store, tracker *status.Tracker, obs *observer, pairs []sloPair) {
+	now := time.Now()
+	for _, p := range pairs {
+		r, err := slo.Evaluate(st, p.def, p.target, now)
+		if err != nil {
+			l.WarnContext(ctx, "failed to evaluate slo", "slo", p.def.Name, "target", p.target.Name, "err", err)
+			continue
+		}
+		state, reason := status.Up, ""
+		if r.Burning() {
+			state = status.Burning
+			reason = fmt.Sprintf("error budget burning %.1fx faster than allowed over the last %s, %.1f%% left", r.BurnRate, formatWindow(r.BurnWindow), r.Budget)
+		}
+		name := fmt.Sprintf("slo/%s/%s", p.def.Name, p.target.Name)
+		tr, changed := tracker.Observe(name, state, now, reason)
+		if changed {
+			l.InfoContext(ctx, "slo state changed", "slo", p.def.Name, "target", p.target.Name, "from", tr.From, "to", tr.To, "burnRate", r.BurnRate)
+			obs.notifier.Notify(ctx, tr, p.target.URL)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/slo"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun_SLO(t *testing.T) {
+	defer func() { output = "" }()
+	st, err := openStore()
+	assert.NoError(t, err)
+	now := time.Now()
+	var results []store.Result
+	for i := 0; i < 100; i++ {
+		r := store.Result{Time: now.Add(-time.Duration(i) * time.Minute), URL: "http://slo.test", Up: true, State: status.Up, Latency: time.Millisecond}
+		if i < 2 {
+			r.Up, r.State = false, status.Down
+		}
+		results = append(results, r)
+	}
+	assert.NoError(t, st.Write(results...))
+
+	out, err := executeCommandC(rootCmd, "slo", "--objective", "99", "--window", "24h", "-o", "json", "http://slo.test")
+	assert.NoError(t, err)
+	var reports []slo.Report
+	assert.NoError(t, json.Unmarshal([]byte(out), &reports))
+	assert.Len(t, reports, 1)
+	assert.Equal(t, 100, reports[0].Checks)
+	assert.InDelta(t, 98, reports[0].Attainment, 0.0001)
+	assert.InDelta(t, -100, reports[0].Budget, 0.0001)
+	assert.Equal(t, "breached", reports[0].Status())
+}
+
+func TestFormatWindow(t *testing.T) {
+	assert.Equal(t, "30d", formatWindow(30*24*time.Hour))
+	assert.Equal(t, "1h0m0s", formatWindow(time.Hour))
+}
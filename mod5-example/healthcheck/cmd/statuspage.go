@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/statuspage"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+	"github.com/spf13/cobra"
+)
+
+// statusFreshness is how recent the latest check of a component must be for
+// the status page to show its state rather than unknown.
+const statusFreshness = time.Hour
+
+var (
+	pageOut   string
+	pageTheme string
+	pageTitle string
+)
+
+var statuspageCmd = &cobra.Command{
+	Use:   "statuspage",
+	Short: "Generate a public status page",
+}
+
+var statuspageBuildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Write a static status page from the recorded results",
+	Long: `Writes a static HTML status page to --out from the recorded check results:
+the current state of each component group, daily uptime bars for the last
+90 days and the incident notes added with "healthcheck incident note". The
+page has no scripts and loads nothing from elsewhere, so it can be
+published on any static host.
+
+Components are the targets listed under status_page groups in the --config
+file, shown by name, so URLs stay private. Without groups every target is
+shown. --theme points at a directory of templates to use instead of the
+built-in ones: its index.html is rendered with html/template, other .html
+files are available to it as partial templates and anything else, such as
+stylesheets, is copied to --out.`,
+	Example: `  healthcheck statuspage build --config healthcheck.yaml --out ./public
+  healthcheck statuspage build --out ./public --theme ./theme --title "Acme status"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		now := time.Now()
+		page, err := buildStatusPage(now)
+		if err != nil {
+			return err
+		}
+		if err := statuspage.Write(pageOut, page, pageTheme); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Wrote status page to %s: %s\n", pageOut, page.Summary())
+		return nil
+	},
+}
+
+func init() {
+	statuspageBuildCmd.Flags().StringVar(&pageOut, "out", "public", "Directory to write the status page to")
+	statuspageBuildCmd.Flags().StringVar(&pageTheme, "theme", "", "Directory of templates and assets replacing the built-in theme")
+	statuspageBuildCmd.Flags().StringVar(&pageTitle, "title", "", "Title of the page (default the status_page title in the config file, or \"Status\")")
+	statuspageCmd.AddCommand(statuspageBuildCmd)
+	rootCmd.AddCommand(statuspageCmd)
+}
+
+// buildStatusPage collects the state and history of the status page
+// components from the store, and the incidents from the --notes file.
+func buildStatusPage(now time.Time) (statuspage.Page, error) {
+	title := pageTitle
+	if title == "" && cfg != nil {
+		title = cfg.StatusPage.Title
+	}
+	if title == "" {
+		title = "Status"
+	}
+	notes, err := statuspage.NewNotes(notesFile).List()
+	if err != nil {
+		return statuspage.Page{}, err
+	}
+	st, err := openStore()
+	if err != nil {
+		return statuspage.Page{}, err
+	}
+	defs, err := componentGroups(st)
+	if err != nil {
+		return statuspage.Page{}, err
+	}
+
+	from := now.AddDate(0, 0, -statuspage.Days)
+	groups := make([]statuspage.Group, 0, len(defs))
+	for _, def := range defs {
+		g := statuspage.Group{Name: def.name}
+		for _, t := range def.targets {
+			rollups, err := st.Rollups(t.URL, from, time.Time{})
+			if err != nil {
+				return statuspage.Page{}, err
+			}
+			state, err := currentState(st, t.URL, now)
+			if err != nil {
+				return statuspage.Page{}, err
+			}
+			g.Components = append(g.Components, statuspage.NewComponent(t.Name, state, rollups, now))
+		}
+		groups = append(groups, g)
+	}
+	return statuspage.NewPage(title, groups, notes, now), nil
+}
+
+// componentGroup is a group of targets on the status page.
+type componentGroup struct {
+	name    string
+	targets []config.Target
+}
+
+// componentGroups returns the status_page groups of the config file. Without
+// any, every target of the config file, or every URL in st when there is
+// no config file, is put in a single group.
+func componentGroups(st *store.Store) ([]componentGroup, error) {
+	if cfg != nil && len(cfg.StatusPage.Groups) > 0 {
+		byName := make(map[string]config.Target, len(cfg.Targets))
+		for _, t := range cfg.Targets {
+			byName[t.Name] = t
+		}
+		groups := make([]componentGroup, len(cfg.StatusPage.Groups))
+		for i, g := range cfg.StatusPage.Groups {
+			groups[i].name = g.Name
+			for _, name := range g.Targets {
+				groups[i].targets = append(groups[i].targets, byName[name])
+			}
+		}
+		return groups, nil
+	}
+	all := componentGroup{name: "Services"}
+	if cfg != nil {
+		all.targets = cfg.Targets
+	} else {
+		urls, err := st.URLs()
+		if err != nil {
+			return nil, err
+		}
+		for _, url := range urls {
+			all.targets = append(all.targets, config.Target{Name: url, URL: url})
+		}
+	}
+	return []componentGroup{all}, nil
+}
+
+// currentState returns the state of the latest check of url, or unknown if
+// it wasn't checked in the last statusFreshness.
+func currentState(st *store.Store, url string, now time.Time) (status.State, error) {
+	results, err := st.Query(store.Query{URLs: []string{url}, From: now.Add(-statusFreshness)})
+	if err != nil || len(results) == 0 {
+		return status.Unknown, err
+	}
+	return results[len(results)-1].State, nil
+}
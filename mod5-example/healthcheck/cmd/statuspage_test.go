@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/statuspage"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun_StatusPage(t *testing.T) {
+	dir := t.TempDir()
+	defer func(c *config.Config, file string) { cfg, configFile = c, file }(cfg, configFile)
+	defer func(o, notes string) { output, notesFile = o, notes }(output, notesFile)
+	defer func(s, title string, components []string) {
+		noteStatus, noteTitle, noteComponents = s, title, components
+	}(noteStatus, noteTitle, noteComponents)
+	file := filepath.Join(dir, "healthcheck.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte(`
+targets:
+  - name: api
+    url: http://statuspage.test/api
+  - name: internal
+    url: http://statuspage.test/internal
+status_page:
+  title: Acme status
+  groups:
+    - name: Public API
+      targets: [api]
+`), 0o644))
+
+	st, err := openStore()
+	assert.NoError(t, err)
+	now := time.Now()
+	assert.NoError(t, st.Write(
+		store.Result{Time: now.Add(-2 * time.Minute), URL: "http://statuspage.test/api", Up: true, State: status.Up},
+		store.Result{Time: now.Add(-time.Minute), URL: "http://statuspage.test/api", State: status.Down, Err: "timeout"},
+		store.Result{Time: now.Add(-time.Minute), URL: "http://statuspage.test/internal", Up: true, State: status.Up},
+	))
+
+	notes := filepath.Join(dir, "notes.json")
+	out, err := executeCommandC(rootCmd, "incident", "note", "--notes", notes, "--title", "API errors", "--component", "api", "Requests are failing.")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Posted investigating update on incident")
+	list, err := statuspage.NewNotes(notes).List()
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	_, err = executeCommandC(rootCmd, "incident", "note", "--notes", notes, "--title", "", "--status", "identified", list[0].ID, "A bad deploy.")
+	assert.NoError(t, err)
+	_, err = executeCommandC(rootCmd, "incident", "note", "--notes", notes, "--status", "", "missing", "Hello?")
+	assert.ErrorContains(t, err, "no incident with id missing")
+
+	public := filepath.Join(dir, "public")
+	out, err = executeCommandC(rootCmd, "statuspage", "build", "--config", file, "--notes", notes, "--out", public)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Partial outage")
+	page, err := os.ReadFile(filepath.Join(public, "index.html"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(page), "Acme status")
+	assert.Contains(t, string(page), "Public API")
+	assert.Contains(t, string(page), "A bad deploy.")
+	assert.NotContains(t, string(page), "internal", "targets in no group are left off the page")
+	assert.NotContains(t, string(page), "statuspage.test", "urls are not published")
+}
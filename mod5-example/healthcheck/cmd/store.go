@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/maintenance"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/scheduler"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+)
+
+var storeFile string
+
+const (
+	// flushInterval is how often monitor writes buffered results to the
+	// store, so the database isn't opened for every single check.
+	flushInterval = 5 * time.Second
+	// pruneInterval is how often monitor applies the retention policy.
+	pruneInterval = time.Hour
+	// maxPending bounds the results buffered between flushes, so that a
+	// store that can't be written for long doesn't grow monitor's memory
+	// without limit. Results beyond it are dropped.
+	maxPending = 100_000
+)
+
+// openStore opens the --store database with the retention policy from the
+// config file.
+func openStore() (*store.Store, error) {
+	retention := store.DefaultRetention
+	if cfg != nil {
+		if r := cfg.Store.Retention; r.Raw > 0 {
+			retention.Raw = r.Raw
+		}
+		if r := cfg.Store.Retention; r.Rollups > 0 {
+			retention.Rollups = r.Rollups
+		}
+	}
+	return store.Open(storeFile, retention)
+}
+
+// storeResult converts a check of t during the maintenance window w, which
+// may be nil, into a result to store.
+func storeResult(t config.Target, r checkResult, w *maintenance.Window) store.Result {
+	state, reason := checkState(r, w)
+	res := store.Result{
+		Time:       r.CheckedAt,
+		Target:     t.Name,
+		URL:        r.URL,
+		Up:         r.Up,
+		State:      state,
+		StatusCode: r.StatusCode,
+		Latency:    r.Latency,
+	}
+	if state == status.Down || state == status.Maintenance {
+		res.Err = reason
+	}
+	if w != nil {
+		res.Maintenance = w.ID
+	}
+	return res
+}
+
+// recordChecks writes the results of one-off checks to the store. Failing
+// to do so doesn't fail the checks.
+func recordChecks(ctx context.Context, results []checkResult) {
+	st, err := openStore()
+	if err == nil {
+		batch := make([]store.Result, len(results))
+		for i, r := range results {
+			batch[i] = storeResult(config.Target{Name: r.URL, URL: r.URL}, r, nil)
+		}
+		err = st.Write(batch...)
+	}
+	if err != nil {
+		l.WarnContext(ctx, "failed to record results", "err", err)
+	}
+}
+
+// recorder buffers the results of a monitor session and writes them to the
+// store in batches.
+type recorder struct {
+	st *store.Store
+
+	mu      sync.Mutex
+	pending []store.Result
+	dropped int
+}
+
+// record buffers res for the next flush, or drops it if maxPending results
+// are buffered already.
+func (r *recorder) record(res store.Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pending) >= maxPending {
+		r.dropped++
+		return
+	}
+	r.pending = append(r.pending, res)
+}
+
+// flush writes the buffered results. They are kept for the next flush if
+// the store can't be written, for example while another process holds it,
+// up to maxPending of them.
+func (r *recorder) flush(ctx context.Context) {
+	r.mu.Lock()
+	batch, dropped := r.pending, r.dropped
+	r.pending, r.dropped = nil, 0
+	r.mu.Unlock()
+	if dropped > 0 {
+		l.WarnContext(ctx, "dropped results while the store fell behind", "results", dropped)
+	}
+	if err := r.st.Write(batch...); err != nil {
+		l.WarnContext(ctx, "failed to record results", "results", len(batch), "err", err)
+		r.mu.Lock()
+		r.pending = append(batch, r.pending...)
+		if n := len(r.pending) - maxPending; n > 0 {
+			r.pending = r.pending[:maxPending]
+			r.dropped += n
+		}
+		r.mu.Unlock()
+	}
+}
+
+// addStoreJobs schedules flushing the recorded results and pruning the
+// store.
+func addStoreJobs(sched *scheduler.Scheduler, obs *observer) {
+	if obs.recorder == nil {
+		return
+	}
+	sched.Add(scheduler.Job{
+		Name:       "flush",
+		Background: true,
+		Interval:   flushInterval,
+		Run:        obs.recorder.flush,
+	})
+	sched.Add(scheduler.Job{
+		Name:       "prune",
+		Background: true,
+		Interval:   pruneInterval,
+		Run: func(ctx context.Context) {
+			if err := obs.recorder.st.Prune(time.Now()); err != nil {
+				l.WarnContext(ctx, "failed to prune store", "err", err)
+			}
+		},
+	})
+}
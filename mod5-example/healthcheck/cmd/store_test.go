@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder_Bounded(t *testing.T) {
+	_, sess := newTestAPI(t)
+	rec := sess.obs.recorder
+	for range maxPending + 10 {
+		rec.record(store.Result{URL: "http://api.test"})
+	}
+	assert.Len(t, rec.pending, maxPending)
+	assert.Equal(t, 10, rec.dropped)
+
+	rec.pending = rec.pending[:1]
+	rec.flush(context.Background())
+	assert.Empty(t, rec.pending)
+	assert.Zero(t, rec.dropped)
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"golang.org/x/term"
+)
+
+var (
+	columns []string
+	sortBy  string
+)
+
+// column describes a single table column and how to render a result in it.
+type column struct {
+	header string
+	value  func(r checkResult) string
+}
+
+var tableColumns = map[string]column{
+	"url": {"URL", func(r checkResult) string { return r.URL }},
+	"status": {"Status", func(r checkResult) string {
+		if r.State != "" {
+			return stateText(r.State)
+		}
+		return statusText(r.Up)
+	}},
+	"code": {"Code", func(r checkResult) string {
+		if r.StatusCode == 0 {
+			return "-"
+		}
+		return strconv.Itoa(r.StatusCode)
+	}},
+	"latency": {"Latency", func(r checkResult) string {
+		if r.Latency == 0 {
+			return "-"
+		}
+		return r.Latency.Round(time.Millisecond).String()
+	}},
+	"cert_expiry": {"Cert Expiry", func(r checkResult) string {
+		if r.CertExpiry.IsZero() {
+			return "-"
+		}
+		return r.CertExpiry.Format("01/02/2006")
+	}},
+	"attempts": {"Attempts", func(r checkResult) string { return strconv.Itoa(r.Attempts) }},
+	"circuit": {"Circuit", func(r checkResult) string {
+		if r.Circuit == "" {
+			return "-"
+		}
+		return string(r.Circuit)
+	}},
+	"checked": {"Last Time Checked", func(r checkResult) string {
+		return r.CheckedAt.Format("01/02/2006 03:04PM")
+	}},
+}
+
+// allColumns lists every column in the order used by the wide output.
+var allColumns = []string{"url", "status", "code", "latency", "cert_expiry", "attempts", "circuit", "checked"}
+
+var (
+	checkColumns   = []string{"url", "status"}
+	monitorColumns = []string{"url", "status", "circuit", "checked"}
+)
+
+var sortKeys = map[string]func(a, b checkResult) bool{
+	"url": func(a, b checkResult) bool { return a.URL < b.URL },
+	// Down targets sort first so they are easy to spot.
+	"status": func(a, b checkResult) bool { return !a.Up && b.Up },
+	// The slowest targets sort first.
+	"latency": func(a, b checkResult) bool { return a.Latency > b.Latency },
+}
+
+// minURLWidth is the narrowest the URL column is truncated to, however
+// small the terminal is.
+const minURLWidth = 16
+
+func tableOutput() bool {
+	return output == "table" || output == "wide"
+}
+
+func validateTableFlags() error {
+	for _, c := range columns {
+		if _, ok := tableColumns[c]; !ok {
+			return fmt.Errorf("unknown column %q, valid columns are: %s", c, strings.Join(allColumns, ","))
+		}
+	}
+	if sortBy != "" && sortKeys[sortBy] == nil {
+		return fmt.Errorf("unknown sort key %q, valid keys are: latency, status, url", sortBy)
+	}
+	return nil
+}
+
+// selectedColumns returns the columns to render: everything in wide mode,
+// the --columns flag when set, and the command's defaults otherwise.
+func selectedColumns(defaults []string) []string {
+	if output == "wide" {
+		return allColumns
+	}
+	if len(columns) > 0 {
+		return columns
+	}
+	return defaults
+}
+
+func sortResults(results []checkResult, key string) {
+	less := sortKeys[key]
+	if less == nil {
+		return
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return less(results[i], results[j])
+	})
+}
+
+func renderTable(w io.Writer, results []checkResult, defaults []string) {
+	cols := selectedColumns(defaults)
+	sortResults(results, sortBy)
+
+	headers := make([]string, len(cols))
+	widths := make([]int, len(cols))
+	rows := make([][]string, len(results))
+	for i, c := range cols {
+		headers[i] = tableColumns[c].header
+		widths[i] = tablewriter.DisplayWidth(headers[i])
+	}
+	for r, res := range results {
+		rows[r] = make([]string, len(cols))
+		for i, c := range cols {
+			rows[r][i] = tableColumns[c].value(res)
+			widths[i] = max(widths[i], tablewriter.DisplayWidth(rows[r][i]))
+		}
+	}
+
+	fitURLColumn(cols, rows, widths, terminalWidth(w))
+
+	table := tablewriter.NewWriter(w)
+	table.SetAutoWrapText(false)
+	table.SetHeader(headers)
+	table.AppendBulk(rows)
+	table.Render()
+}
+
+// fitURLColumn shrinks the URL column so the table fits in termWidth,
+// truncating long URLs in the middle. A termWidth of 0 disables fitting.
+func fitURLColumn(cols []string, rows [][]string, widths []int, termWidth int) {
+	if termWidth <= 0 {
+		return
+	}
+	urlCol := -1
+	// Each column is padded by a space on both sides and followed by a
+	// separator, plus the leading border.
+	total := 1
+	for i, c := range cols {
+		if c == "url" {
+			urlCol = i
+		}
+		total += widths[i] + 3
+	}
+	if urlCol < 0 || total <= termWidth {
+		return
+	}
+	limit := max(widths[urlCol]-(total-termWidth), minURLWidth)
+	for _, row := range rows {
+		row[urlCol] = truncateMiddle(row[urlCol], limit)
+	}
+}
+
+// truncateMiddle shortens s to at most n runes by replacing its middle with
+// an ellipsis, so both the host and the end of the path stay visible.
+func truncateMiddle(s string, n int) string {
+	rs := []rune(s)
+	if len(rs) <= n {
+		return s
+	}
+	if n <= 1 {
+		return string(rs[:n])
+	}
+	head := (n - 1) / 2
+	tail := n - 1 - head
+	return string(rs[:head]) + "…" + string(rs[len(rs)-tail:])
+}
+
+func terminalWidth(w io.Writer) int {
+	f, ok := w.(*os.File)
+	if !ok || !isTerminal(f) {
+		return 0
+	}
+	width, _, err := term.GetSize(int(f.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
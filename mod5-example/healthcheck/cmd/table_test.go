@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateMiddle(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		n        int
+		expected string
+	}{
+		{"Short enough", "http://a.io", 20, "http://a.io"},
+		{"Exact length", "http://a.io", 11, "http://a.io"},
+		{"Truncated", "http://example.com/a/very/long/path", 15, "http://…ng/path"},
+		{"Single rune", "http://example.com", 1, "h"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, truncateMiddle(tc.input, tc.n))
+		})
+	}
+}
+
+func TestSortResults(t *testing.T) {
+	results := func() []checkResult {
+		return []checkResult{
+			{URL: "http://b.com", Up: true, Latency: 20 * time.Millisecond},
+			{URL: "http://c.com", Up: false},
+			{URL: "http://a.com", Up: true, Latency: 90 * time.Millisecond},
+		}
+	}
+	urls := func(rs []checkResult) []string {
+		var out []string
+		for _, r := range rs {
+			out = append(out, r.URL)
+		}
+		return out
+	}
+
+	tests := []struct {
+		key      string
+		expected []string
+	}{
+		{"", []string{"http://b.com", "http://c.com", "http://a.com"}},
+		{"url", []string{"http://a.com", "http://b.com", "http://c.com"}},
+		{"status", []string{"http://c.com", "http://b.com", "http://a.com"}},
+		{"latency", []string{"http://a.com", "http://b.com", "http://c.com"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.key, func(t *testing.T) {
+			rs := results()
+			sortResults(rs, tc.key)
+			assert.Equal(t, tc.expected, urls(rs))
+		})
+	}
+}
+
+func TestRun_OutputWide(t *testing.T) {
+	httpmock.ActivateNonDefault(checkClient)
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "http://example.com", httpmock.NewStringResponder(200, "OK"))
+	var buf bytes.Buffer
+	outputWriter = &buf
+	defer func() { outputWriter = os.Stdout }()
+	_, err := executeCommandC(rootCmd, "check", "--output", "wide", "http://example.com")
+	output := buf.String()
+
+	assert.NoError(t, err)
+	for _, header := range []string{"URL", "STATUS", "CODE", "LATENCY", "CERT EXPIRY", "ATTEMPTS"} {
+		assert.Contains(t, output, header)
+	}
+	assert.Contains(t, output, "200")
+}
+
+func TestRun_InvalidColumn(t *testing.T) {
+	defer func() { columns = nil }()
+	_, err := executeCommandC(rootCmd, "check", "--output", "table", "--columns", "url,bogus", "http://example.com")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown column")
+}
+
+func TestColumnsFlag_ListsEveryColumn(t *testing.T) {
+	assert.Len(t, allColumns, len(tableColumns))
+	usage := rootCmd.PersistentFlags().Lookup("columns").Usage
+	for name := range tableColumns {
+		assert.Contains(t, allColumns, name)
+		assert.Contains(t, usage, name)
+	}
+}
+
+func TestStatusText_Accessible(t *testing.T) {
+	defer func() { accessible = false }()
+	accessible = true
+	assert.Equal(t, "✓ Up", statusText(true))
+	assert.Equal(t, "✗ Down", statusText(false))
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/tracing"
+)
+
+var (
+	otlpEndpoint string
+	// flushTraces exports the spans not sent yet. It is set up by
+	// setupTracing.
+	flushTraces = func(context.Context) error { return nil }
+)
+
+// traceFlushTimeout caps how long exiting waits for spans to be exported.
+const traceFlushTimeout = 5 * time.Second
+
+// setupTracing exports check spans to --otlp-endpoint, if set.
+func setupTracing(ctx context.Context) error {
+	flush, err := tracing.Setup(ctx, otlpEndpoint, version)
+	if err != nil {
+		return err
+	}
+	flushTraces = flush
+	return nil
+}
+
+// shutdownTracing exports the remaining spans before healthcheck exits.
+func shutdownTracing() {
+	ctx, cancel := context.WithTimeout(context.Background(), traceFlushTimeout)
+	defer cancel()
+	if err := flushTraces(ctx); err != nil && l != nil {
+		l.Warn("failed to export traces", "err", err)
+	}
+}
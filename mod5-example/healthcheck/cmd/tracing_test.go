@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRunCheck_Trace(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	defer otel.SetTracerProvider(prev)
+	tracing.Setup(context.Background(), "", "test")
+
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	r := runCheck(context.Background(), srv.URL, threshold, 0)
+	assert.False(t, r.Up)
+
+	spans := sr.Ended()
+	var got []string
+	for _, s := range spans {
+		got = append(got, s.Name())
+	}
+	assert.Equal(t, []string{"connect", "GET", "check"}, got)
+	request, check := spans[1], spans[2]
+	assert.Equal(t, check.SpanContext().SpanID(), request.Parent().SpanID())
+	assert.Equal(t, "00-"+request.SpanContext().TraceID().String()+"-"+request.SpanContext().SpanID().String()+"-01", traceparent)
+	assert.Equal(t, codes.Error, check.Status().Code)
+	assert.Equal(t, "unexpected status code 503", check.Status().Description)
+}
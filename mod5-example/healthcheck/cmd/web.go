@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/report"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/web"
+)
+
+var webAddr string
+
+const (
+	// webResults is the number of recent results per target a browser gets
+	// when it connects, to draw its latency charts.
+	webResults = 120
+	// webIncidentPeriod is how far back the dashboard lists incidents.
+	webIncidentPeriod = 24 * time.Hour
+)
+
+// webIncident is a period during which a target was down.
+type webIncident struct {
+	Target string     `json:"target"`
+	URL    string     `json:"url"`
+	Start  time.Time  `json:"start"`
+	End    *time.Time `json:"end,omitempty"`
+	Reason string     `json:"reason,omitempty"`
+}
+
+// webSnapshot is the state a browser starts from.
+type webSnapshot struct {
+	Targets   []targetStatus `json:"targets"`
+	Results   []store.Result `json:"results"`
+	Incidents []webIncident  `json:"incidents"`
+}
+
+// startWeb serves the web dashboard on --web until ctx is done.
+func startWeb(ctx context.Context, sess *session) error {
+	if webAddr == "" {
+		return nil
+	}
+	return serve(ctx, "web dashboard", webAddr, newWeb(sess))
+}
+
+// newWeb returns the handler of the web dashboard of sess, which it hooks
+// into so browsers hear of every check, removed target and state change.
+// It must be called before the session's scheduler runs.
+func newWeb(sess *session) http.Handler {
+	b := web.NewBroker()
+	onResult, onRemove := sess.onResult, sess.onRemove
+	sess.onResult = func(t config.Target, r checkResult) {
+		onResult(t, r)
+		if ts, ok := sess.status(t.Name); ok {
+			b.Publish("target", ts)
+		}
+	}
+	sess.onRemove = func(name string) {
+		if onRemove != nil {
+			onRemove(name)
+		}
+		b.Publish("removed", map[string]string{"name": name})
+	}
+	// The incidents are read from the store once and then follow the
+	// transitions, so state changes don't query the store.
+	incidents := &webIncidentLog{incidents: webIncidents(sess)}
+	sess.obs.onChange = func(t config.Target, tr status.Transition) {
+		if incidents.observe(t, tr) {
+			b.Publish("incidents", incidents.list(time.Now()))
+		}
+	}
+	return web.Handler(b, func() any {
+		return webSnapshot{
+			Targets:   sess.statuses(),
+			Results:   webRecent(sess),
+			Incidents: incidents.list(time.Now()),
+		}
+	})
+}
+
+// webIncidentLog is the list of incidents the dashboard shows.
+type webIncidentLog struct {
+	mu        sync.Mutex
+	incidents []webIncident
+}
+
+// observe opens an incident when t goes down and ends it when t is up
+// again. It reports whether the list changed.
+func (il *webIncidentLog) observe(t config.Target, tr status.Transition) bool {
+	il.mu.Lock()
+	defer il.mu.Unlock()
+	open := -1
+	for i, inc := range il.incidents {
+		if inc.Target == t.Name && inc.End == nil {
+			open = i
+		}
+	}
+	switch {
+	case tr.To == status.Down && open < 0:
+		il.incidents = append(il.incidents, webIncident{Target: t.Name, URL: t.URL, Start: tr.Time, Reason: tr.Reason})
+	case tr.To == status.Up && open >= 0:
+		end := tr.Time
+		il.incidents[open].End = &end
+	default:
+		return false
+	}
+	return true
+}
+
+// list drops the incidents that ended more than webIncidentPeriod before
+// now and returns the rest, latest first.
+func (il *webIncidentLog) list(now time.Time) []webIncident {
+	il.mu.Lock()
+	defer il.mu.Unlock()
+	il.incidents = slices.DeleteFunc(il.incidents, func(inc webIncident) bool {
+		return inc.End != nil && inc.End.Before(now.Add(-webIncidentPeriod))
+	})
+	incidents := slices.Clone(il.incidents)
+	sort.SliceStable(incidents, func(i, j int) bool { return incidents[i].Start.After(incidents[j].Start) })
+	return incidents
+}
+
+// webTargets returns the names of the session's targets by URL.
+func webTargets(sess *session) map[string]string {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	names := make(map[string]string, len(sess.targets))
+	for _, t := range sess.targets {
+		names[t.URL] = t.Name
+	}
+	return names
+}
+
+// webQuery returns the stored results of the session's targets that match
+// q, with their Target set to the target's current name.
+func webQuery(sess *session, q store.Query) []store.Result {
+	rec := sess.obs.recorder
+	if rec == nil {
+		return nil
+	}
+	names := webTargets(sess)
+	urls := make([]string, 0, len(names))
+	for url := range names {
+		urls = append(urls, url)
+	}
+	rec.flush(context.Background())
+	q.URLs = urls
+	results, err := rec.st.Query(q)
+	if err != nil {
+		l.Warn("failed to query results for the web dashboard", "err", err)
+		return nil
+	}
+	for i := range results {
+		results[i].Target = names[results[i].URL]
+	}
+	return results
+}
+
+// webRecent returns the latest webResults results of each target of sess
+// in the last hour.
+func webRecent(sess *session) []store.Result {
+	return webQuery(sess, store.Query{From: time.Now().Add(-time.Hour), Last: webResults})
+}
+
+// webIncidents returns the incidents of the targets of sess in the last
+// webIncidentPeriod, latest first.
+func webIncidents(sess *session) []webIncident {
+	results := webQuery(sess, store.Query{From: time.Now().Add(-webIncidentPeriod)})
+	names := webTargets(sess)
+	incidents := []webIncident{}
+	for _, sum := range report.Summarize(results) {
+		for _, inc := range sum.Incidents {
+			wi := webIncident{Target: names[inc.URL], URL: inc.URL, Start: inc.Start, Reason: inc.Reason}
+			if !inc.End.IsZero() {
+				end := inc.End
+				wi.End = &end
+			}
+			incidents = append(incidents, wi)
+		}
+	}
+	sort.Slice(incidents, func(i, j int) bool { return incidents[i].Start.After(incidents[j].Start) })
+	return incidents
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebSnapshot(t *testing.T) {
+	_, sess := newTestAPI(t)
+	now := time.Now()
+	rec := sess.obs.recorder
+	rec.record(store.Result{Time: now.Add(-2 * time.Hour), URL: "http://api.test", Up: true, State: status.Up})
+	rec.record(store.Result{Time: now.Add(-10 * time.Minute), URL: "http://api.test", State: status.Down, Err: "timeout"})
+	rec.record(store.Result{Time: now.Add(-5 * time.Minute), URL: "http://api.test", Up: true, State: status.Up})
+	rec.record(store.Result{Time: now.Add(-time.Minute), URL: "http://api.test", State: status.Down, Err: "connection refused"})
+
+	recent := webRecent(sess)
+	assert.Len(t, recent, 3, "the last hour")
+	for _, r := range recent {
+		assert.Equal(t, "api", r.Target)
+	}
+
+	incidents := webIncidents(sess)
+	if assert.Len(t, incidents, 2) {
+		assert.Equal(t, "connection refused", incidents[0].Reason)
+		assert.Nil(t, incidents[0].End, "ongoing")
+		assert.Equal(t, "timeout", incidents[1].Reason)
+		assert.NotNil(t, incidents[1].End)
+	}
+}
+
+func TestWebRecent_Capped(t *testing.T) {
+	_, sess := newTestAPI(t)
+	now := time.Now()
+	for i := range webResults + 10 {
+		sess.obs.recorder.record(store.Result{Time: now.Add(time.Duration(i-webResults-10) * time.Second), URL: "http://api.test", Up: true, State: status.Up})
+	}
+
+	recent := webRecent(sess)
+	if assert.Len(t, recent, webResults, "only the latest results of each target") {
+		assert.True(t, recent[0].Time.Before(recent[len(recent)-1].Time), "oldest first")
+		assert.Equal(t, now.Add(-time.Second).Unix(), recent[len(recent)-1].Time.Unix())
+	}
+}
+
+func TestWebIncidentLog(t *testing.T) {
+	api := config.Target{Name: "api", URL: "http://api.test"}
+	now := time.Now()
+	ended := now.Add(-47 * time.Hour)
+	il := &webIncidentLog{incidents: []webIncident{
+		{Target: "web", Start: now.Add(-48 * time.Hour), End: &ended},
+	}}
+
+	assert.True(t, il.observe(api, status.Transition{Target: "api", From: status.Up, To: status.Down, Time: now.Add(-time.Hour), Reason: "timeout"}))
+	assert.False(t, il.observe(api, status.Transition{Target: "api", From: status.Down, To: status.Maintenance, Time: now.Add(-50 * time.Minute)}))
+	assert.False(t, il.observe(api, status.Transition{Target: "api", From: status.Maintenance, To: status.Down, Time: now.Add(-40 * time.Minute)}), "the incident is still open")
+	assert.True(t, il.observe(api, status.Transition{Target: "api", From: status.Down, To: status.Up, Time: now.Add(-30 * time.Minute)}))
+	assert.True(t, il.observe(api, status.Transition{Target: "api", From: status.Up, To: status.Down, Time: now, Reason: "connection refused"}))
+
+	incidents := il.list(now)
+	if assert.Len(t, incidents, 2, "incidents that ended a day ago are dropped") {
+		assert.Equal(t, "connection refused", incidents[0].Reason)
+		assert.Nil(t, incidents[0].End)
+		assert.Equal(t, "timeout", incidents[1].Reason)
+		assert.Equal(t, "http://api.test", incidents[1].URL)
+		assert.Equal(t, now.Add(-30*time.Minute), *incidents[1].End)
+	}
+}
+
+func TestNewWeb_Hooks(t *testing.T) {
+	_, sess := newTestAPI(t)
+	var results, removed int
+	sess.onResult = func(config.Target, checkResult) { results++ }
+	sess.onRemove = func(string) { removed++ }
+	srv := httptest.NewServer(newWeb(sess))
+	defer srv.Close()
+
+	assert.NotNil(t, sess.obs.onChange)
+	sess.onResult(config.Target{Name: "api"}, checkResult{})
+	assert.True(t, sess.remove("api"))
+	assert.Equal(t, 1, results, "the previous result hook still runs")
+	assert.Equal(t, 1, removed, "the previous remove hook still runs")
+}
@@ -0,0 +1,319 @@
+// Package composite evaluates boolean and quorum expressions over the
+// health of other targets, such as "quorum(2, api-1, api-2, api-3)" or
+// "db && (cache-a || cache-b)".
+package composite
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Value is the outcome of an expression. It is Unknown when it depends on
+// targets that haven't been checked yet.
+type Value int
+
+const (
+	Unknown Value = iota
+	False
+	True
+)
+
+// Expr is a parsed expression.
+type Expr struct {
+	root node
+	src  string
+}
+
+// String returns the expression as it was written.
+func (e Expr) String() string {
+	return e.src
+}
+
+// Eval returns the value of the expression given the health of every
+// target it refers to. The value is only Unknown if targets of unknown
+// health could still make it either True or False.
+func (e Expr) Eval(health func(name string) Value) Value {
+	return e.root.eval(health)
+}
+
+// Targets returns the names of the targets the expression refers to, once
+// each, in the order they first appear.
+func (e Expr) Targets() []string {
+	var names []string
+	seen := make(map[string]bool)
+	e.root.walk(func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	})
+	return names
+}
+
+type node interface {
+	eval(health func(string) Value) Value
+	walk(fn func(name string))
+}
+
+type target string
+
+func (t target) eval(health func(string) Value) Value { return health(string(t)) }
+func (t target) walk(fn func(string))                 { fn(string(t)) }
+
+type not struct{ x node }
+
+func (n not) eval(health func(string) Value) Value {
+	switch v := n.x.eval(health); v {
+	case True:
+		return False
+	case False:
+		return True
+	default:
+		return v
+	}
+}
+
+func (n not) walk(fn func(string)) { n.x.walk(fn) }
+
+// and is true when all of its operands are, and or when any is.
+type and []node
+type or []node
+
+func (a and) eval(health func(string) Value) Value {
+	v := True
+	for _, x := range a {
+		switch x.eval(health) {
+		case False:
+			return False
+		case Unknown:
+			v = Unknown
+		}
+	}
+	return v
+}
+
+func (a and) walk(fn func(string)) {
+	for _, x := range a {
+		x.walk(fn)
+	}
+}
+
+func (o or) eval(health func(string) Value) Value {
+	v := False
+	for _, x := range o {
+		switch x.eval(health) {
+		case True:
+			return True
+		case Unknown:
+			v = Unknown
+		}
+	}
+	return v
+}
+
+func (o or) walk(fn func(string)) {
+	for _, x := range o {
+		x.walk(fn)
+	}
+}
+
+// quorum is true when at least n of its operands are.
+type quorum struct {
+	n  int
+	xs []node
+}
+
+func (q quorum) eval(health func(string) Value) Value {
+	var yes, unknown int
+	for _, x := range q.xs {
+		switch x.eval(health) {
+		case True:
+			yes++
+		case Unknown:
+			unknown++
+		}
+	}
+	switch {
+	case yes >= q.n:
+		return True
+	case yes+unknown < q.n:
+		return False
+	default:
+		return Unknown
+	}
+}
+
+func (q quorum) walk(fn func(string)) {
+	for _, x := range q.xs {
+		x.walk(fn)
+	}
+}
+
+// Parse parses an expression made of target names, "!", "&&", "||",
+// parentheses and quorum(n, ...), which is true when at least n of the
+// expressions that follow are. Names are letters, digits and any of "-_.",
+// or double-quoted strings for names with other characters, such as URLs.
+// "!" binds tightest and "||" loosest.
+func Parse(s string) (Expr, error) {
+	p := &parser{src: s}
+	root, err := p.or()
+	if err == nil && p.skip() < len(s) {
+		err = p.errorf("unexpected %q", s[p.pos:])
+	}
+	if err != nil {
+		return Expr{}, fmt.Errorf("expression %q: %w", s, err)
+	}
+	return Expr{root: root, src: s}, nil
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// skip moves past spaces and returns the new position.
+func (p *parser) skip() int {
+	for p.pos < len(p.src) && strings.ContainsRune(" \t\n", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	return p.pos
+}
+
+// accept moves past tok if it comes next.
+func (p *parser) accept(tok string) bool {
+	p.skip()
+	if strings.HasPrefix(p.src[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(tok string) error {
+	if !p.accept(tok) {
+		return p.errorf("expected %q", tok)
+	}
+	return nil
+}
+
+func (p *parser) or() (node, error) {
+	x, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	xs := or{x}
+	for p.accept("||") {
+		if x, err = p.and(); err != nil {
+			return nil, err
+		}
+		xs = append(xs, x)
+	}
+	if len(xs) == 1 {
+		return x, nil
+	}
+	return xs, nil
+}
+
+func (p *parser) and() (node, error) {
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	xs := and{x}
+	for p.accept("&&") {
+		if x, err = p.unary(); err != nil {
+			return nil, err
+		}
+		xs = append(xs, x)
+	}
+	if len(xs) == 1 {
+		return x, nil
+	}
+	return xs, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.accept("!") {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return not{x}, nil
+	}
+	if p.accept("(") {
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if name == "quorum" && p.accept("(") {
+		return p.quorum()
+	}
+	return target(name), nil
+}
+
+// quorum parses the arguments of quorum(, which has been read.
+func (p *parser) quorum() (node, error) {
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+	n, err := strconv.Atoi(p.src[start:p.pos])
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("quorum needs a count first")
+	}
+	q := quorum{n: n}
+	for p.accept(",") {
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		q.xs = append(q.xs, x)
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if n < 1 || n > len(q.xs) {
+		return nil, p.errorf("quorum of %d out of %d", n, len(q.xs))
+	}
+	return q, nil
+}
+
+func (p *parser) name() (string, error) {
+	p.skip()
+	rest := p.src[p.pos:]
+	if strings.HasPrefix(rest, `"`) {
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return "", p.errorf("unterminated name")
+		}
+		p.pos += len(quoted)
+		return strconv.Unquote(quoted)
+	}
+	end := strings.IndexFunc(rest, func(r rune) bool {
+		return !(r == '-' || r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+	})
+	if end < 0 {
+		end = len(rest)
+	}
+	if end == 0 {
+		if rest == "" {
+			return "", p.errorf("expected a target name")
+		}
+		return "", p.errorf("unexpected %q", rest[:1])
+	}
+	p.pos += end
+	return rest[:end], nil
+}
@@ -0,0 +1,83 @@
+package composite
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr    string
+		targets []string
+	}{
+		{"api", []string{"api"}},
+		{"db && (cache-a || cache-b)", []string{"db", "cache-a", "cache-b"}},
+		{"quorum(2, api-1, api-2, api-3)", []string{"api-1", "api-2", "api-3"}},
+		{`!maint.v2 || "https://example.com/health"`, []string{"maint.v2", "https://example.com/health"}},
+		{"quorum(1, a && b, quorum(2, a, c, d))", []string{"a", "b", "c", "d"}},
+		{"quorum", []string{"quorum"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			e, err := Parse(tc.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.targets, e.Targets())
+			assert.Equal(t, tc.expr, e.String())
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"", "expected a target name"},
+		{"a &&", "expected a target name"},
+		{"a b", `unexpected "b"`},
+		{"(a || b", `expected ")"`},
+		{"a & b", `unexpected "& b"`},
+		{"quorum(a, b)", "quorum needs a count first"},
+		{"quorum(3, a, b)", "quorum of 3 out of 2"},
+		{"quorum(0, a)", "quorum of 0 out of 1"},
+		{`"a`, "unterminated name"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := Parse(tc.expr)
+			assert.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
+
+func TestEval(t *testing.T) {
+	health := map[string]Value{"up-1": True, "up-2": True, "down": False, "new": Unknown}
+	tests := []struct {
+		expr     string
+		expected Value
+	}{
+		{"up-1", True},
+		{"!down", True},
+		{"!new", Unknown},
+		{"up-1 && down", False},
+		{"up-1 && new", Unknown},
+		{"down && new", False},
+		{"down || new", Unknown},
+		{"up-1 || new", True},
+		{"down || up-1 && up-2", True},
+		{"(down || up-1) && !up-2", False},
+		{"quorum(2, up-1, up-2, down)", True},
+		{"quorum(2, up-1, down, down)", False},
+		{"quorum(2, up-1, new, down)", Unknown},
+		{"quorum(1, down, new)", Unknown},
+		{"quorum(2, up-1, new, up-2)", True},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			e, err := Parse(tc.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, e.Eval(func(name string) Value { return health[name] }))
+		})
+	}
+}
@@ -0,0 +1,634 @@
+// Package config loads the optional YAML configuration file that describes
+// monitored targets and their settings.
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/composite"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/maintenance"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the top-level structure of the configuration file.
+type Config struct {
+	Targets []Target `yaml:"targets"`
+	Alerts  Alerts   `yaml:"alerts"`
+	// Maintenance lists planned windows in which matching targets are
+	// not alerted on. Ad-hoc ones are added with "healthcheck silence".
+	Maintenance []maintenance.Window `yaml:"maintenance"`
+	Store       Store                `yaml:"store"`
+	SLOs        []SLO                `yaml:"slos"`
+	StatusPage  StatusPage           `yaml:"status_page"`
+	// Modules are the probes "healthcheck serve" runs on demand at
+	// /probe, by name. They are written like blackbox_exporter modules.
+	Modules map[string]Module `yaml:"modules"`
+	// Sinks are collectors every check result of monitor is pushed to.
+	Sinks []Sink `yaml:"sinks"`
+}
+
+// Sink is a StatsD or InfluxDB collector that check results are pushed to
+// in batches.
+type Sink struct {
+	// Name identifies the sink in logs. It defaults to the type.
+	Name string `yaml:"name"`
+	// Type is "statsd", sent over UDP to Address, or "influxdb", sent as
+	// line protocol over HTTP to URL.
+	Type    string `yaml:"type"`
+	Address string `yaml:"address"`
+	URL     string `yaml:"url"`
+	// Headers are added to InfluxDB writes, for example to authenticate.
+	Headers map[string]string `yaml:"headers"`
+	// Prefix starts every StatsD metric name and is the InfluxDB
+	// measurement. It defaults to "healthcheck".
+	Prefix string `yaml:"prefix"`
+	// Tags are added to those of every target.
+	Tags map[string]string `yaml:"tags"`
+	// FlushInterval is how often buffered results are sent. It defaults to
+	// 10s.
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	// BufferSize is how many results are kept between flushes. Further
+	// results are dropped until the next flush. It defaults to 1000.
+	BufferSize int `yaml:"buffer_size"`
+}
+
+// Module is a probe run on demand against the target of a /probe request.
+type Module struct {
+	// Prober is "http", "tcp" or "dns".
+	Prober string `yaml:"prober"`
+	// Timeout caps how long the probe takes. It defaults to 5s, and is
+	// shortened to fit the scraper's own timeout.
+	Timeout time.Duration `yaml:"timeout"`
+	HTTP    HTTPProbe     `yaml:"http"`
+	TCP     TCPProbe      `yaml:"tcp"`
+	DNS     DNSProbe      `yaml:"dns"`
+}
+
+// HTTPProbe configures an http module. The probe succeeds if the response
+// has a valid status code and passes the body and TLS requirements.
+type HTTPProbe struct {
+	// ValidStatusCodes defaults to any 2xx code.
+	ValidStatusCodes []int             `yaml:"valid_status_codes"`
+	Method           string            `yaml:"method"`
+	Headers          map[string]string `yaml:"headers"`
+	Body             string            `yaml:"body"`
+	// NoFollowRedirects makes the probe judge redirects themselves.
+	NoFollowRedirects bool `yaml:"no_follow_redirects"`
+	FailIfSSL         bool `yaml:"fail_if_ssl"`
+	FailIfNotSSL      bool `yaml:"fail_if_not_ssl"`
+	// FailIfBodyMatchesRegexp and FailIfBodyNotMatchesRegexp fail the
+	// probe if any of their expressions does, or doesn't, match the body.
+	FailIfBodyMatchesRegexp    []string  `yaml:"fail_if_body_matches_regexp"`
+	FailIfBodyNotMatchesRegexp []string  `yaml:"fail_if_body_not_matches_regexp"`
+	TLSConfig                  TLSConfig `yaml:"tls_config"`
+}
+
+// TCPProbe configures a tcp module, which succeeds if it can connect to the
+// target and, with TLS, complete a handshake.
+type TCPProbe struct {
+	TLS       bool      `yaml:"tls"`
+	TLSConfig TLSConfig `yaml:"tls_config"`
+}
+
+// DNSProbe configures a dns module, whose target is the DNS server to ask.
+// It succeeds if the server answers the query with at least one record.
+type DNSProbe struct {
+	QueryName string `yaml:"query_name"`
+	// QueryType is A (the default), AAAA, CNAME, MX, NS, PTR, SRV or TXT.
+	QueryType string `yaml:"query_type"`
+	// TransportProtocol is "udp" (the default) or "tcp".
+	TransportProtocol string `yaml:"transport_protocol"`
+}
+
+// TLSConfig adjusts how a probe verifies the target's certificate.
+type TLSConfig struct {
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	ServerName         string `yaml:"server_name"`
+	// CAFile is a PEM file of the certificate authorities to trust
+	// instead of the system ones.
+	CAFile string `yaml:"ca_file"`
+}
+
+// StatusPage configures the public page written by "healthcheck statuspage
+// build".
+type StatusPage struct {
+	Title string `yaml:"title"`
+	// Groups lists the components shown on the page by target name.
+	// Targets in no group are left off the page. Without groups, every
+	// target is shown in a single one.
+	Groups []ComponentGroup `yaml:"groups"`
+}
+
+// ComponentGroup is a set of targets shown together on the status page.
+type ComponentGroup struct {
+	Name    string   `yaml:"name"`
+	Targets []string `yaml:"targets"`
+}
+
+// SLO is a service level objective for one or more targets.
+type SLO struct {
+	Name string `yaml:"name"`
+	// Targets limits the SLO to the named targets. Empty means all.
+	Targets []string `yaml:"targets"`
+	// Objective is the percentage of checks that must be good, such as
+	// 99.9.
+	Objective float64 `yaml:"objective"`
+	// Latency, when set, makes a check only good if it was up and no
+	// slower than Latency.
+	Latency time.Duration `yaml:"latency"`
+	// Window is the rolling window the objective applies to. It defaults
+	// to 30 days.
+	Window time.Duration `yaml:"window"`
+	// BurnWindow is the recent period the burn rate is measured over and
+	// BurnRate the rate that fires "burning" alerts. They default to an
+	// hour and 14.4, which spends 2% of a 30 day budget in that hour.
+	BurnWindow time.Duration `yaml:"burn_window"`
+	BurnRate   float64       `yaml:"burn_rate"`
+}
+
+// Store configures the result database.
+type Store struct {
+	Retention Retention `yaml:"retention"`
+}
+
+// Retention overrides how long results are kept. Raw results default to a
+// week and hourly rollups to a year.
+type Retention struct {
+	Raw     time.Duration `yaml:"raw"`
+	Rollups time.Duration `yaml:"rollups"`
+}
+
+// Target is a single monitored endpoint.
+type Target struct {
+	// Name identifies the target in tables, logs and references from other
+	// settings. It defaults to the URL.
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Expr makes a composite target, which isn't checked itself but is up
+	// while the expression over other targets holds, such as
+	// "quorum(2, api-1, api-2, api-3)" or "db && (cache-a || cache-b)".
+	// Composite targets need a name and have no url; their results are
+	// stored under the url "composite:<name>".
+	Expr string `yaml:"expr"`
+	// Interval overrides the --interval flag for this target.
+	Interval time.Duration `yaml:"interval"`
+	// Cron is an optional five-field cron expression. When set it is used
+	// instead of Interval.
+	Cron string            `yaml:"cron"`
+	Tags map[string]string `yaml:"tags"`
+	// Agents limits which agents check the target for "healthcheck
+	// collector". Empty means all of them.
+	Agents []string `yaml:"agents"`
+	// DependsOn names the targets this one is reached through, such as a
+	// load balancer in front of it. While one of them is down, failures
+	// of this target count as Unreachable and aren't alerted on.
+	DependsOn []string `yaml:"depends_on"`
+}
+
+// Alerts configures where state changes are sent.
+type Alerts struct {
+	Webhooks []Webhook   `yaml:"webhooks"`
+	Emails   []Email     `yaml:"emails"`
+	SMTP     SMTP        `yaml:"smtp"`
+	Rules    []AlertRule `yaml:"rules"`
+	Digest   *Digest     `yaml:"digest"`
+	// Escalations are policies rules use to page further channels when
+	// an incident isn't acknowledged in time.
+	Escalations []Escalation `yaml:"escalations"`
+	// Retries is the number of times a failed delivery is retried. It
+	// defaults to 3.
+	Retries int `yaml:"retries"`
+}
+
+// Webhook is an HTTP endpoint that receives alerts as JSON.
+type Webhook struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Format selects a built-in payload shape: "generic" (the default) or
+	// "slack".
+	Format string `yaml:"format"`
+	// Template is a Go text/template that renders the JSON payload. It
+	// overrides Format.
+	Template string            `yaml:"template"`
+	Headers  map[string]string `yaml:"headers"`
+}
+
+// Email is a named list of recipients.
+type Email struct {
+	Name string   `yaml:"name"`
+	To   []string `yaml:"to"`
+}
+
+// SMTP is the mail server used for email alerts and digests.
+type SMTP struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// PasswordEnv names an environment variable holding the password, to
+	// keep it out of the file.
+	PasswordEnv string `yaml:"password_env"`
+	From        string `yaml:"from"`
+	// StartTLS upgrades the connection with STARTTLS before
+	// authenticating.
+	StartTLS bool `yaml:"starttls"`
+}
+
+// Digest schedules a periodic summary email.
+type Digest struct {
+	// Schedule is "daily" (08:00 UTC), "weekly" (Mondays at 08:00 UTC) or a
+	// five-field cron expression.
+	Schedule string `yaml:"schedule"`
+	// Period is how much history the digest covers. It defaults to a day
+	// for daily digests and a week for weekly ones.
+	Period time.Duration `yaml:"period"`
+	Emails []string      `yaml:"emails"`
+}
+
+// AlertRule sends matching state changes to webhooks.
+type AlertRule struct {
+	Name string `yaml:"name"`
+	// On lists the transitions that fire the rule, written "from->to" with
+	// * matching any state, or just a state as shorthand for "*->state".
+	On []string `yaml:"on"`
+	// Targets limits the rule to the named targets. Empty means all.
+	Targets  []string `yaml:"targets"`
+	Webhooks []string `yaml:"webhooks"`
+	Emails   []string `yaml:"emails"`
+	// Escalation names the policy followed after the rule's own webhooks
+	// and emails have been notified.
+	Escalation string `yaml:"escalation"`
+}
+
+// Escalation is a named list of steps taken while an incident stays
+// unacknowledged.
+type Escalation struct {
+	Name  string           `yaml:"name"`
+	Steps []EscalationStep `yaml:"steps"`
+}
+
+// EscalationStep notifies its webhooks and emails once an incident has been
+// open and unacknowledged for After.
+type EscalationStep struct {
+	After    time.Duration `yaml:"after"`
+	Webhooks []string      `yaml:"webhooks"`
+	Emails   []string      `yaml:"emails"`
+}
+
+// Load reads and validates the configuration file at path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config file: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("unable to parse config file %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	names := make(map[string]bool)
+	// uses holds the targets each composite target's expression refers to.
+	uses := make(map[string][]string)
+	for i := range c.Targets {
+		t := &c.Targets[i]
+		switch {
+		case t.Expr != "" && t.URL != "":
+			return fmt.Errorf("target %d can't have both a url and an expr", i+1)
+		case t.Expr != "" && t.Name == "":
+			return fmt.Errorf("composite target %d has no name", i+1)
+		case t.Expr != "":
+			e, err := composite.Parse(t.Expr)
+			if err != nil {
+				return fmt.Errorf("target %q: %w", t.Name, err)
+			}
+			uses[t.Name] = e.Targets()
+			t.URL = CompositeURL(t.Name)
+		case t.URL == "":
+			return fmt.Errorf("target %d has no url or expr", i+1)
+		default:
+			if u, err := url.Parse(t.URL); err != nil || u.Scheme == "" || u.Host == "" {
+				return fmt.Errorf("target %d has an invalid url %q", i+1, t.URL)
+			}
+		}
+		if t.Name == "" {
+			t.Name = t.URL
+		}
+		if names[t.Name] {
+			return fmt.Errorf("duplicate target name %q", t.Name)
+		}
+		names[t.Name] = true
+		if t.Interval < 0 {
+			return fmt.Errorf("target %q has a negative interval", t.Name)
+		}
+	}
+	for _, t := range c.Targets {
+		for _, name := range uses[t.Name] {
+			if !names[name] {
+				return fmt.Errorf("target %q refers to unknown target %q", t.Name, name)
+			}
+		}
+	}
+	if err := checkDependencies(c.Targets, uses); err != nil {
+		return err
+	}
+
+	if r := c.Store.Retention; r.Raw < 0 || r.Rollups < 0 {
+		return fmt.Errorf("store retention can't be negative")
+	}
+	slos := make(map[string]bool)
+	for i := range c.SLOs {
+		s := &c.SLOs[i]
+		if s.Name == "" {
+			return fmt.Errorf("slo %d has no name", i+1)
+		}
+		if slos[s.Name] {
+			return fmt.Errorf("duplicate slo name %q", s.Name)
+		}
+		slos[s.Name] = true
+		if s.Objective <= 0 || s.Objective >= 100 {
+			return fmt.Errorf("slo %q needs an objective between 0 and 100", s.Name)
+		}
+		for _, t := range s.Targets {
+			if !names[t] {
+				return fmt.Errorf("slo %q refers to unknown target %q", s.Name, t)
+			}
+		}
+		if s.Window == 0 {
+			s.Window = 30 * 24 * time.Hour
+		}
+		if s.BurnWindow == 0 {
+			s.BurnWindow = time.Hour
+		}
+		if s.BurnRate == 0 {
+			s.BurnRate = 14.4
+		}
+		if s.Window < 0 || s.BurnWindow < 0 || s.BurnRate < 0 || s.Latency < 0 {
+			return fmt.Errorf("slo %q has a negative setting", s.Name)
+		}
+	}
+	for i, g := range c.StatusPage.Groups {
+		if g.Name == "" || len(g.Targets) == 0 {
+			return fmt.Errorf("status page group %d needs a name and at least one target", i+1)
+		}
+		for _, t := range g.Targets {
+			if !names[t] {
+				return fmt.Errorf("status page group %q refers to unknown target %q", g.Name, t)
+			}
+		}
+	}
+	for name := range c.Modules {
+		m := c.Modules[name]
+		if err := m.validate(); err != nil {
+			return fmt.Errorf("module %q %w", name, err)
+		}
+		c.Modules[name] = m
+	}
+	sinks := make(map[string]bool)
+	for i := range c.Sinks {
+		s := &c.Sinks[i]
+		if s.Name == "" {
+			s.Name = s.Type
+		}
+		if sinks[s.Name] {
+			return fmt.Errorf("duplicate sink name %q", s.Name)
+		}
+		sinks[s.Name] = true
+		if err := s.validate(); err != nil {
+			return fmt.Errorf("sink %d %w", i+1, err)
+		}
+	}
+	for i := range c.Maintenance {
+		w := &c.Maintenance[i]
+		if w.ID == "" {
+			w.ID = fmt.Sprintf("maintenance-%d", i+1)
+		}
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("maintenance window %q: %w", w.ID, err)
+		}
+	}
+
+	webhooks := make(map[string]bool)
+	for i, w := range c.Alerts.Webhooks {
+		if w.Name == "" || w.URL == "" {
+			return fmt.Errorf("webhook %d needs a name and a url", i+1)
+		}
+		switch w.Format {
+		case "", "generic", "slack":
+		default:
+			return fmt.Errorf("webhook %q has an unknown format %q", w.Name, w.Format)
+		}
+		webhooks[w.Name] = true
+	}
+	emails := make(map[string]bool)
+	for i, e := range c.Alerts.Emails {
+		if e.Name == "" || len(e.To) == 0 {
+			return fmt.Errorf("email %d needs a name and at least one recipient", i+1)
+		}
+		emails[e.Name] = true
+	}
+	if len(emails) > 0 && (c.Alerts.SMTP.Host == "" || c.Alerts.SMTP.From == "") {
+		return fmt.Errorf("email alerts need an smtp host and from address")
+	}
+	checkEmails := func(what string, names []string) error {
+		for _, name := range names {
+			if !emails[name] {
+				return fmt.Errorf("%s refers to unknown email %q", what, name)
+			}
+		}
+		return nil
+	}
+	checkWebhooks := func(what string, names []string) error {
+		for _, name := range names {
+			if !webhooks[name] {
+				return fmt.Errorf("%s refers to unknown webhook %q", what, name)
+			}
+		}
+		return nil
+	}
+	escalations := make(map[string]bool)
+	for i, e := range c.Alerts.Escalations {
+		if e.Name == "" || len(e.Steps) == 0 {
+			return fmt.Errorf("escalation %d needs a name and at least one step", i+1)
+		}
+		for j, s := range e.Steps {
+			what := fmt.Sprintf("escalation %q step %d", e.Name, j+1)
+			if s.After <= 0 {
+				return fmt.Errorf("%s needs a positive 'after'", what)
+			}
+			if err := checkWebhooks(what, s.Webhooks); err != nil {
+				return err
+			}
+			if err := checkEmails(what, s.Emails); err != nil {
+				return err
+			}
+		}
+		escalations[e.Name] = true
+	}
+	for i, r := range c.Alerts.Rules {
+		what := fmt.Sprintf("alert rule %d", i+1)
+		if len(r.On) == 0 {
+			return fmt.Errorf("%s has no transitions in 'on'", what)
+		}
+		if err := checkWebhooks(what, r.Webhooks); err != nil {
+			return err
+		}
+		if err := checkEmails(what, r.Emails); err != nil {
+			return err
+		}
+		if r.Escalation != "" && !escalations[r.Escalation] {
+			return fmt.Errorf("%s refers to unknown escalation %q", what, r.Escalation)
+		}
+	}
+	if d := c.Alerts.Digest; d != nil {
+		if len(d.Emails) == 0 {
+			return fmt.Errorf("digest has no emails")
+		}
+		if err := checkEmails("digest", d.Emails); err != nil {
+			return err
+		}
+		switch d.Schedule {
+		case "daily", "weekly":
+		default:
+			if d.Period <= 0 {
+				return fmt.Errorf("digest with a cron schedule needs a period")
+			}
+		}
+	}
+	return nil
+}
+
+// CompositeURL is the url results of the composite target called name are
+// stored under.
+func CompositeURL(name string) string {
+	return "composite:" + name
+}
+
+// checkDependencies makes sure targets only depend on other known targets
+// and that neither their dependencies nor the targets composite targets
+// use, as listed in uses, form a cycle.
+func checkDependencies(targets []Target, uses map[string][]string) error {
+	parents := make(map[string][]string, len(targets))
+	for _, t := range targets {
+		parents[t.Name] = slices.Concat(t.DependsOn, uses[t.Name])
+	}
+	for _, t := range targets {
+		for _, p := range t.DependsOn {
+			if _, ok := parents[p]; !ok {
+				return fmt.Errorf("target %q depends on unknown target %q", t.Name, p)
+			}
+		}
+	}
+
+	// done holds the targets whose dependencies are known to be acyclic,
+	// path those being visited.
+	done := make(map[string]bool)
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		for i, n := range path {
+			if n == name {
+				return fmt.Errorf("dependency cycle %s", strings.Join(append(path[i:], name), " -> "))
+			}
+		}
+		if done[name] {
+			return nil
+		}
+		path = append(path, name)
+		for _, p := range parents[name] {
+			if err := visit(p); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		done[name] = true
+		return nil
+	}
+	for _, t := range targets {
+		if err := visit(t.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks the module and fills in its defaults.
+func (m *Module) validate() error {
+	if m.Timeout < 0 {
+		return fmt.Errorf("has a negative timeout")
+	}
+	if m.Timeout == 0 {
+		m.Timeout = 5 * time.Second
+	}
+	switch m.Prober {
+	case "http":
+		for _, exprs := range [][]string{m.HTTP.FailIfBodyMatchesRegexp, m.HTTP.FailIfBodyNotMatchesRegexp} {
+			for _, expr := range exprs {
+				if _, err := regexp.Compile(expr); err != nil {
+					return fmt.Errorf("has an invalid regexp: %w", err)
+				}
+			}
+		}
+	case "tcp":
+	case "dns":
+		if m.DNS.QueryName == "" {
+			return fmt.Errorf("needs a query_name")
+		}
+		if m.DNS.QueryType == "" {
+			m.DNS.QueryType = "A"
+		}
+		switch m.DNS.QueryType {
+		case "A", "AAAA", "CNAME", "MX", "NS", "PTR", "SRV", "TXT":
+		default:
+			return fmt.Errorf("has an unsupported query_type %q", m.DNS.QueryType)
+		}
+		if m.DNS.TransportProtocol == "" {
+			m.DNS.TransportProtocol = "udp"
+		}
+		if m.DNS.TransportProtocol != "udp" && m.DNS.TransportProtocol != "tcp" {
+			return fmt.Errorf("has an unknown transport_protocol %q", m.DNS.TransportProtocol)
+		}
+	default:
+		return fmt.Errorf("has an unknown prober %q, valid probers are: http, tcp, dns", m.Prober)
+	}
+	return nil
+}
+
+func (s *Sink) validate() error {
+	switch s.Type {
+	case "statsd":
+		if s.Address == "" {
+			return fmt.Errorf("needs an address")
+		}
+	case "influxdb":
+		if u, err := url.Parse(s.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("needs an http or https url")
+		}
+	default:
+		return fmt.Errorf("has an unknown type %q, valid types are: statsd, influxdb", s.Type)
+	}
+	if s.FlushInterval < 0 || s.BufferSize < 0 {
+		return fmt.Errorf("has a negative setting")
+	}
+	if s.Prefix == "" {
+		s.Prefix = "healthcheck"
+	}
+	if s.FlushInterval == 0 {
+		s.FlushInterval = 10 * time.Second
+	}
+	if s.BufferSize == 0 {
+		s.BufferSize = 1000
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "healthcheck.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `
+targets:
+  - name: api
+    url: https://api.example.com/health
+    interval: 5s
+    tags:
+      team: payments
+  - url: https://docs.example.com
+    cron: "*/5 * * * *"
+    agents: [eu-west]
+    depends_on: [api]
+`)
+	cfg, err := Load(path)
+	assert.NoError(t, err)
+	assert.Len(t, cfg.Targets, 2)
+	assert.Equal(t, "api", cfg.Targets[0].Name)
+	assert.Equal(t, 5*time.Second, cfg.Targets[0].Interval)
+	assert.Equal(t, "payments", cfg.Targets[0].Tags["team"])
+	assert.Equal(t, "https://docs.example.com", cfg.Targets[1].Name, "name should default to the url")
+	assert.Equal(t, "*/5 * * * *", cfg.Targets[1].Cron)
+	assert.Equal(t, []string{"eu-west"}, cfg.Targets[1].Agents)
+	assert.Equal(t, []string{"api"}, cfg.Targets[1].DependsOn)
+}
+
+func TestLoad_Composite(t *testing.T) {
+	path := writeConfig(t, `
+targets:
+  - name: api-1
+    url: http://api-1.example.com
+  - name: api-2
+    url: http://api-2.example.com
+  - name: api
+    expr: quorum(1, api-1, api-2)
+    interval: 1m
+`)
+	cfg, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "quorum(1, api-1, api-2)", cfg.Targets[2].Expr)
+	assert.Equal(t, "composite:api", cfg.Targets[2].URL)
+	assert.Equal(t, time.Minute, cfg.Targets[2].Interval)
+}
+
+func TestLoad_Maintenance(t *testing.T) {
+	path := writeConfig(t, `
+maintenance:
+  - selector: team=payments
+    cron: "0 2 * * 0"
+    duration: 2h
+    comment: weekly patching
+  - id: migration
+    targets: [api]
+    start: 2024-05-01T22:00:00Z
+    end: 2024-05-02T02:00:00Z
+`)
+	cfg, err := Load(path)
+	assert.NoError(t, err)
+	assert.Len(t, cfg.Maintenance, 2)
+	assert.Equal(t, "maintenance-1", cfg.Maintenance[0].ID, "id should default to the position")
+	assert.Equal(t, 2*time.Hour, cfg.Maintenance[0].Duration)
+	assert.Equal(t, "migration", cfg.Maintenance[1].ID)
+	assert.Equal(t, time.Date(2024, 5, 2, 2, 0, 0, 0, time.UTC), cfg.Maintenance[1].End)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		expected string
+	}{
+		{"missing url", "targets:\n  - name: api\n", "has no url"},
+		{"bad url", "targets:\n  - url: example.com\n", "invalid url"},
+		{"duplicate", "targets:\n  - url: http://a.com\n  - url: http://a.com\n", "duplicate target name"},
+		{"unknown dependency", "targets:\n  - url: http://a.com\n    depends_on: [lb]\n", `depends on unknown target "lb"`},
+		{"self dependency", "targets:\n  - name: a\n    url: http://a.com\n    depends_on: [a]\n", "dependency cycle a -> a"},
+		{"dependency cycle", "targets:\n  - name: a\n    url: http://a.com\n    depends_on: [b]\n  - name: b\n    url: http://b.com\n    depends_on: [c]\n  - name: c\n    url: http://c.com\n    depends_on: [a]\n", "dependency cycle a -> b -> c -> a"},
+		{"url and expr", "targets:\n  - name: a\n    url: http://a.com\n    expr: b\n", "both a url and an expr"},
+		{"unnamed composite", "targets:\n  - expr: a\n", "composite target 1 has no name"},
+		{"bad expr", "targets:\n  - name: a\n    expr: quorum(2, b)\n", "quorum of 2 out of 1"},
+		{"unknown expr target", "targets:\n  - name: a\n    expr: b || c\n", `refers to unknown target "b"`},
+		{"composite cycle", "targets:\n  - name: a\n    expr: b\n  - name: b\n    expr: \"!a\"\n", "dependency cycle a -> b -> a"},
+		{"bad yaml", "targets: [", "unable to parse"},
+		{"unknown webhook", "alerts:\n  rules:\n    - on: [down]\n      webhooks: [ops]\n", "unknown webhook"},
+		{"unknown format", "alerts:\n  webhooks:\n    - name: ops\n      url: http://x\n      format: teams\n", "unknown format"},
+		{"unknown escalation", "alerts:\n  rules:\n    - on: [down]\n      escalation: page\n", "unknown escalation"},
+		{"escalation without delay", "alerts:\n  escalations:\n    - name: page\n      steps:\n        - webhooks: []\n", "needs a positive 'after'"},
+		{"slo objective", "slos:\n  - name: availability\n    objective: 100\n", "objective between 0 and 100"},
+		{"slo target", "slos:\n  - name: availability\n    objective: 99.9\n    targets: [api]\n", "unknown target"},
+		{"empty status page group", "status_page:\n  groups:\n    - name: API\n", "needs a name and at least one target"},
+		{"status page target", "status_page:\n  groups:\n    - name: API\n      targets: [api]\n", "unknown target"},
+		{"unknown prober", "modules:\n  icmp:\n    prober: icmp\n", "unknown prober"},
+		{"dns without name", "modules:\n  dns:\n    prober: dns\n", "needs a query_name"},
+		{"dns query type", "modules:\n  dns:\n    prober: dns\n    dns:\n      query_name: example.com\n      query_type: ANY\n", "unsupported query_type"},
+		{"bad regexp", "modules:\n  http:\n    prober: http\n    http:\n      fail_if_body_matches_regexp: [\"(\"]\n", "invalid regexp"},
+		{"unknown sink type", "sinks:\n  - type: graphite\n    address: localhost:2003\n", "unknown type"},
+		{"statsd without address", "sinks:\n  - type: statsd\n", "needs an address"},
+		{"influxdb without url", "sinks:\n  - type: influxdb\n    url: localhost:8086\n", "needs an http or https url"},
+		{"duplicate sink", "sinks:\n  - type: statsd\n    address: a:8125\n  - type: statsd\n    address: b:8125\n", "duplicate sink name"},
+		{"unscoped window", "maintenance:\n  - cron: \"0 2 * * 0\"\n    duration: 1h\n", "needs targets or a selector"},
+		{"window without duration", "maintenance:\n  - selector: team=payments\n    cron: \"0 2 * * 0\"\n", "needs a duration"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, tc.contents))
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+func TestLoad_Modules(t *testing.T) {
+	path := writeConfig(t, `
+modules:
+  http_2xx:
+    prober: http
+    timeout: 3s
+    http:
+      valid_status_codes: [200, 204]
+      fail_if_not_ssl: true
+  dns_example:
+    prober: dns
+    dns:
+      query_name: example.com
+`)
+	cfg, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 3*time.Second, cfg.Modules["http_2xx"].Timeout)
+	assert.Equal(t, []int{200, 204}, cfg.Modules["http_2xx"].HTTP.ValidStatusCodes)
+	assert.True(t, cfg.Modules["http_2xx"].HTTP.FailIfNotSSL)
+	dns := cfg.Modules["dns_example"]
+	assert.Equal(t, 5*time.Second, dns.Timeout, "timeout should default to 5s")
+	assert.Equal(t, "A", dns.DNS.QueryType)
+	assert.Equal(t, "udp", dns.DNS.TransportProtocol)
+}
+
+func TestLoad_Sinks(t *testing.T) {
+	path := writeConfig(t, `
+sinks:
+  - type: statsd
+    address: localhost:8125
+  - name: influx
+    type: influxdb
+    url: http://localhost:8086/api/v2/write?org=ops&bucket=checks
+    prefix: checks
+    tags:
+      env: prod
+    flush_interval: 1s
+    buffer_size: 50
+`)
+	cfg, err := Load(path)
+	assert.NoError(t, err)
+	assert.Len(t, cfg.Sinks, 2)
+	statsd := cfg.Sinks[0]
+	assert.Equal(t, "statsd", statsd.Name, "name should default to the type")
+	assert.Equal(t, "healthcheck", statsd.Prefix)
+	assert.Equal(t, 10*time.Second, statsd.FlushInterval)
+	assert.Equal(t, 1000, statsd.BufferSize)
+	influx := cfg.Sinks[1]
+	assert.Equal(t, "checks", influx.Prefix)
+	assert.Equal(t, "prod", influx.Tags["env"])
+	assert.Equal(t, time.Second, influx.FlushInterval)
+	assert.Equal(t, 50, influx.BufferSize)
+}
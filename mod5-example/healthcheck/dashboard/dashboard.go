@@ -0,0 +1,623 @@
+// Package dashboard implements the full-screen terminal UI used by the
+// monitor command. It redraws in place with ANSI escape sequences instead of
+// clearing the screen, and reads single key presses from a raw terminal.
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/latency"
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+)
+
+// HistorySize is the number of recent results kept per target for the
+// sparkline and the result dots.
+const HistorySize = 20
+
+// maxErrors is the number of recent errors kept per target for the detail
+// pane.
+const maxErrors = 5
+
+const (
+	altScreenOn  = "\x1b[?1049h"
+	altScreenOff = "\x1b[?1049l"
+	cursorHide   = "\x1b[?25l"
+	cursorShow   = "\x1b[?25h"
+	cursorHome   = "\x1b[H"
+	clearLine    = "\x1b[K"
+	clearBelow   = "\x1b[J"
+)
+
+// Sample is the outcome of one check of a target.
+type Sample struct {
+	Time    time.Time
+	Up      bool
+	Latency time.Duration
+	Err     string
+	// State is the target's state after the check, such as "flapping". It
+	// defaults to up or down.
+	State string
+}
+
+type target struct {
+	name    string
+	samples []Sample
+	errors  []Sample
+	checks  int
+	ups     int
+	// recent holds the samples within the stats window, leaving out
+	// failures during maintenance.
+	recent []latency.Sample
+	times  []time.Time
+}
+
+// trim drops the recent samples older than since.
+func (t *target) trim(since time.Time) {
+	i := 0
+	for i < len(t.times) && t.times[i].Before(since) {
+		i++
+	}
+	t.recent, t.times = t.recent[i:], t.times[i:]
+}
+
+func (t *target) last() (Sample, bool) {
+	if len(t.samples) == 0 {
+		return Sample{}, false
+	}
+	return t.samples[len(t.samples)-1], true
+}
+
+func (t *target) uptime() float64 {
+	if t.checks == 0 {
+		return 0
+	}
+	return 100 * float64(t.ups) / float64(t.checks)
+}
+
+// sortModes are cycled through with the 's' key.
+var sortModes = []struct {
+	name string
+	less func(a, b *target) bool
+}{
+	{"name", func(a, b *target) bool { return a.name < b.name }},
+	{"status", func(a, b *target) bool {
+		sa, _ := a.last()
+		sb, _ := b.last()
+		return !sa.Up && sb.Up
+	}},
+	{"latency", func(a, b *target) bool {
+		sa, _ := a.last()
+		sb, _ := b.last()
+		return sa.Latency > sb.Latency
+	}},
+	{"uptime", func(a, b *target) bool { return a.uptime() < b.uptime() }},
+}
+
+// Dashboard holds the state of the monitor UI. Results are fed in with
+// Record while Run draws the screen and handles key presses.
+type Dashboard struct {
+	mu       sync.Mutex
+	out      io.Writer
+	interval time.Duration
+	targets  []*target
+	byName   map[string]*target
+
+	// statsWindow is the period the latency stats cover and apdexT the
+	// threshold of their Apdex score.
+	statsWindow time.Duration
+	apdexT      time.Duration
+
+	paused    bool
+	sortMode  int
+	filter    string
+	filtering bool
+	selected  int
+	detail    bool
+
+	width, height int
+
+	redraw  chan struct{}
+	recheck chan struct{}
+	pause   chan bool
+}
+
+// New returns a dashboard drawing to out, with a row for each target.
+func New(out io.Writer, targets []string, interval time.Duration) *Dashboard {
+	d := &Dashboard{
+		out:         out,
+		interval:    interval,
+		byName:      make(map[string]*target),
+		statsWindow: 5 * time.Minute,
+		apdexT:      500 * time.Millisecond,
+		width:       80,
+		height:      24,
+		redraw:      make(chan struct{}, 1),
+		recheck:     make(chan struct{}, 1),
+		pause:       make(chan bool, 1),
+	}
+	for _, name := range targets {
+		d.add(name)
+	}
+	return d
+}
+
+// SetStats sets the period covered by the latency percentiles and Apdex
+// scores, five minutes by default, and the Apdex threshold, by default
+// 500ms.
+func (d *Dashboard) SetStats(window, apdexT time.Duration) {
+	d.mu.Lock()
+	d.statsWindow, d.apdexT = window, apdexT
+	d.mu.Unlock()
+}
+
+func (d *Dashboard) add(name string) *target {
+	t := &target{name: name}
+	d.targets = append(d.targets, t)
+	d.byName[name] = t
+	return t
+}
+
+// Record adds the result of a check to a target's row.
+func (d *Dashboard) Record(name string, s Sample) {
+	d.mu.Lock()
+	t, ok := d.byName[name]
+	if !ok {
+		t = d.add(name)
+	}
+	// Failures during maintenance don't count towards uptime.
+	if s.State != "maintenance" {
+		t.checks++
+		if s.Up {
+			t.ups++
+		}
+		t.recent = append(t.recent, latency.Sample{Up: s.Up, Latency: s.Latency})
+		t.times = append(t.times, s.Time)
+	}
+	t.trim(s.Time.Add(-d.statsWindow))
+	t.samples = append(t.samples, s)
+	if len(t.samples) > HistorySize {
+		t.samples = t.samples[1:]
+	}
+	if s.Err != "" {
+		t.errors = append(t.errors, s)
+		if len(t.errors) > maxErrors {
+			t.errors = t.errors[1:]
+		}
+	}
+	d.mu.Unlock()
+	d.requestRedraw()
+}
+
+// Remove deletes a target's row.
+func (d *Dashboard) Remove(name string) {
+	d.mu.Lock()
+	if t, ok := d.byName[name]; ok {
+		delete(d.byName, name)
+		for i, other := range d.targets {
+			if other == t {
+				d.targets = append(d.targets[:i], d.targets[i+1:]...)
+				break
+			}
+		}
+	}
+	d.mu.Unlock()
+	d.requestRedraw()
+}
+
+// Paused reports whether the user has paused checking.
+func (d *Dashboard) Paused() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.paused
+}
+
+// Recheck delivers a value whenever the user asks for an immediate check.
+func (d *Dashboard) Recheck() <-chan struct{} {
+	return d.recheck
+}
+
+// PauseToggled delivers the new paused state whenever the user pauses or
+// resumes checking.
+func (d *Dashboard) PauseToggled() <-chan bool {
+	return d.pause
+}
+
+func (d *Dashboard) requestRedraw() {
+	select {
+	case d.redraw <- struct{}{}:
+	default:
+	}
+}
+
+// Run takes over the terminal until the user quits or ctx is done. It puts
+// in into raw mode to read key presses and restores the terminal on return.
+func (d *Dashboard) Run(ctx context.Context, in *os.File) error {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return fmt.Errorf("unable to switch the terminal to raw mode: %w", err)
+	}
+	defer term.Restore(int(in.Fd()), state)
+
+	fmt.Fprint(d.out, altScreenOn+cursorHide)
+	defer fmt.Fprint(d.out, cursorShow+altScreenOff)
+
+	keys := make(chan []byte)
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		buf := make([]byte, 16)
+		for {
+			n, err := in.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			key := make([]byte, n)
+			copy(key, buf[:n])
+			select {
+			case keys <- key:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	// Stop the key reader on return. A pending read is interrupted where
+	// the input supports deadlines; otherwise the reader exits after the
+	// next key press instead of blocking on keys forever.
+	defer func() {
+		close(stop)
+		if in.SetReadDeadline(time.Now()) == nil {
+			<-stopped
+			in.SetReadDeadline(time.Time{})
+		}
+	}()
+
+	// Redraw at least once a second so ages and the terminal size stay
+	// current.
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		if f, ok := d.out.(*os.File); ok {
+			if w, h, err := term.GetSize(int(f.Fd())); err == nil && w > 0 && h > 0 {
+				d.mu.Lock()
+				d.width, d.height = w, h
+				d.mu.Unlock()
+			}
+		}
+		d.draw()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case key, ok := <-keys:
+			if !ok || !d.handleKey(key) {
+				return nil
+			}
+		case <-d.redraw:
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleKey applies a key press and reports whether the dashboard should
+// keep running.
+func (d *Dashboard) handleKey(key []byte) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.filtering {
+		switch {
+		case key[0] == '\r' || key[0] == '\n':
+			d.filtering = false
+		case key[0] == 0x1b:
+			d.filtering = false
+			d.filter = ""
+		case key[0] == 0x7f || key[0] == 0x08:
+			if r := []rune(d.filter); len(r) > 0 {
+				d.filter = string(r[:len(r)-1])
+			}
+		case key[0] >= 0x20:
+			d.filter += string(key)
+		}
+		d.selected = 0
+		return true
+	}
+
+	switch string(key) {
+	case "q", "\x03":
+		return false
+	case "p":
+		d.paused = !d.paused
+		// Replace a state the reader hasn't picked up yet, so only the
+		// latest one is delivered.
+		select {
+		case <-d.pause:
+		default:
+		}
+		d.pause <- d.paused
+	case "r":
+		select {
+		case d.recheck <- struct{}{}:
+		default:
+		}
+	case "s":
+		d.sortMode = (d.sortMode + 1) % len(sortModes)
+	case "/":
+		d.filtering = true
+	case "\r", "\n":
+		d.detail = !d.detail
+	case "j", "\x1b[B":
+		d.selected++
+	case "k", "\x1b[A":
+		d.selected--
+	}
+	return true
+}
+
+func (d *Dashboard) draw() {
+	d.mu.Lock()
+	lines := d.render()
+	d.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString(cursorHome)
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteString(clearLine + "\r\n")
+	}
+	b.WriteString(clearBelow)
+	fmt.Fprint(d.out, b.String())
+}
+
+// visible returns the targets matching the filter, in the current sort
+// order, and clamps the selection to them.
+func (d *Dashboard) visible() []*target {
+	var rows []*target
+	for _, t := range d.targets {
+		if strings.Contains(t.name, d.filter) {
+			rows = append(rows, t)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return sortModes[d.sortMode].less(rows[i], rows[j])
+	})
+	d.selected = min(max(d.selected, 0), max(len(rows)-1, 0))
+	return rows
+}
+
+const (
+	statusWidth  = 11
+	latencyWidth = 8
+	apdexWidth   = 5
+	uptimeWidth  = 7
+)
+
+// render returns the screen contents, one entry per line.
+func (d *Dashboard) render() []string {
+	rows := d.visible()
+
+	header := fmt.Sprintf("healthcheck monitor - %d targets - every %s - sort: %s", len(d.targets), d.interval, sortModes[d.sortMode].name)
+	if d.filter != "" || d.filtering {
+		header += " - filter: " + d.filter
+		if d.filtering {
+			header += "_"
+		}
+	}
+	if d.paused {
+		header += " - " + color.New(color.FgYellow).Sprint("PAUSED")
+	}
+
+	// Two spaces of selection marker plus the fixed columns and the spaces
+	// between them; the target name gets whatever is left.
+	nameWidth := max(d.width-(2+statusWidth+2*latencyWidth+apdexWidth+2*HistorySize+uptimeWidth+7), 10)
+	lines := []string{
+		header,
+		"",
+		fmt.Sprintf("  %s %s %s %s %s %s %s %s",
+			pad("TARGET", nameWidth), pad("STATUS", statusWidth), pad("LATENCY", latencyWidth),
+			pad("P95", latencyWidth), pad("APDEX", apdexWidth),
+			pad("TREND", HistorySize), pad(fmt.Sprintf("LAST %d", HistorySize), HistorySize), "UPTIME"),
+	}
+	for i, t := range rows {
+		marker := "  "
+		if i == d.selected {
+			marker = "> "
+		}
+		status, last, p95, apdex, uptime := "-", "-", "-", "-", "-"
+		if s, ok := t.last(); ok {
+			status = stateText(s)
+			if s.Latency > 0 {
+				last = s.Latency.Round(time.Millisecond).String()
+			}
+			uptime = fmt.Sprintf("%.1f%%", t.uptime())
+		}
+		if stats := latency.Summarize(t.recent, d.apdexT); stats.Checks > 0 {
+			if stats.P95 > 0 {
+				p95 = stats.P95.Round(time.Millisecond).String()
+			}
+			apdex = fmt.Sprintf("%.2f", stats.Apdex)
+		}
+		lines = append(lines, fmt.Sprintf("%s%s %s %s %s %s %s %s %s",
+			marker, pad(shorten(t.name, nameWidth), nameWidth), pad(status, statusWidth), pad(last, latencyWidth),
+			pad(p95, latencyWidth), pad(apdex, apdexWidth),
+			pad(sparkline(t.samples), HistorySize), pad(dots(t.samples), HistorySize), uptime))
+	}
+	if len(rows) == 0 {
+		lines = append(lines, "  no targets match the filter")
+	}
+
+	if d.detail && len(rows) > 0 {
+		t := rows[d.selected]
+		lines = append(lines, "", fmt.Sprintf("Latency of %s over the last %s", t.name, d.statsWindow))
+		if stats := latency.Summarize(t.recent, d.apdexT); stats.P50 > 0 {
+			lines = append(lines, fmt.Sprintf("  min %s  mean %s  p50 %s  p90 %s  p95 %s  p99 %s  max %s  apdex %.2f (T=%s)",
+				stats.Min.Round(time.Millisecond), stats.Mean.Round(time.Millisecond), stats.P50.Round(time.Millisecond),
+				stats.P90.Round(time.Millisecond), stats.P95.Round(time.Millisecond), stats.P99.Round(time.Millisecond),
+				stats.Max.Round(time.Millisecond), stats.Apdex, d.apdexT))
+		} else {
+			lines = append(lines, "  no successful checks")
+		}
+		lines = append(lines, "", "Recent errors for "+t.name)
+		if len(t.errors) == 0 {
+			lines = append(lines, "  none")
+		}
+		for i := len(t.errors) - 1; i >= 0; i-- {
+			e := t.errors[i]
+			lines = append(lines, fmt.Sprintf("  %s  %s", e.Time.Format("15:04:05"), e.Err))
+		}
+	}
+
+	lines = append(lines, "", "q quit  p pause  r recheck  s sort  / filter  ↑↓ select  enter details")
+	if len(lines) > d.height && d.height > 0 {
+		lines = lines[:d.height]
+	}
+	for i, line := range lines {
+		lines[i] = truncate(line, d.width)
+	}
+	return lines
+}
+
+var stateColors = map[string]color.Attribute{
+	"up":          color.FgGreen,
+	"down":        color.FgRed,
+	"degraded":    color.FgYellow,
+	"flapping":    color.FgMagenta,
+	"maintenance": color.FgBlue,
+	"unreachable": color.FgHiBlack,
+}
+
+func stateText(s Sample) string {
+	state := s.State
+	if state == "" {
+		state = "down"
+		if s.Up {
+			state = "up"
+		}
+	}
+	label := strings.ToUpper(state[:1]) + state[1:]
+	return color.New(stateColors[state]).Sprint(pad(label, statusWidth))
+}
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws the latencies of samples scaled between their minimum and
+// maximum. Failed checks are drawn as a dot.
+func sparkline(samples []Sample) string {
+	var lo, hi time.Duration
+	for _, s := range samples {
+		if s.Latency == 0 {
+			continue
+		}
+		if lo == 0 || s.Latency < lo {
+			lo = s.Latency
+		}
+		hi = max(hi, s.Latency)
+	}
+	var b strings.Builder
+	for _, s := range samples {
+		switch {
+		case s.Latency == 0:
+			b.WriteRune('·')
+		case hi == lo:
+			b.WriteRune(sparks[0])
+		default:
+			b.WriteRune(sparks[int(s.Latency-lo)*(len(sparks)-1)/int(hi-lo)])
+		}
+	}
+	return b.String()
+}
+
+// dots draws one dot per sample, filled and green when the target was up
+// and hollow and red when it was down, or dotted and blue during
+// maintenance, so it reads without color too.
+func dots(samples []Sample) string {
+	var b strings.Builder
+	for _, s := range samples {
+		if s.Up {
+			b.WriteString(color.New(color.FgGreen).Sprint("●"))
+		} else if s.State == "maintenance" {
+			b.WriteString(color.New(color.FgBlue).Sprint("◌"))
+		} else {
+			b.WriteString(color.New(color.FgRed).Sprint("○"))
+		}
+	}
+	return b.String()
+}
+
+// pad left-aligns s in a field n cells wide, truncating it if needed.
+func pad(s string, n int) string {
+	s = truncate(s, n)
+	if w := displayWidth(s); w < n {
+		s += strings.Repeat(" ", n-w)
+	}
+	return s
+}
+
+// shorten replaces the middle of a plain string with an ellipsis so it fits
+// in n cells, keeping both the host and the end of a URL visible.
+func shorten(s string, n int) string {
+	rs := []rune(s)
+	if len(rs) <= n || n < 3 {
+		return s
+	}
+	head := (n - 1) / 2
+	return string(rs[:head]) + "…" + string(rs[len(rs)-(n-1-head):])
+}
+
+// truncate cuts s down to n cells, leaving ANSI color sequences intact.
+func truncate(s string, n int) string {
+	if displayWidth(s) <= n {
+		return s
+	}
+	var b strings.Builder
+	width, escape, colored := 0, false, false
+	for _, r := range s {
+		switch {
+		case r == 0x1b:
+			escape, colored = true, true
+		case escape:
+			if r == 'm' {
+				escape = false
+			}
+		default:
+			width += runewidth.RuneWidth(r)
+			if width > n {
+				if colored {
+					// Reset any color whose closing sequence was cut off.
+					b.WriteString("\x1b[0m")
+				}
+				return b.String()
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// displayWidth is the number of terminal cells s occupies, ignoring ANSI
+// color sequences.
+func displayWidth(s string) int {
+	width, escape := 0, false
+	for _, r := range s {
+		switch {
+		case r == 0x1b:
+			escape = true
+		case escape:
+			if r == 'm' {
+				escape = false
+			}
+		default:
+			width += runewidth.RuneWidth(r)
+		}
+	}
+	return width
+}
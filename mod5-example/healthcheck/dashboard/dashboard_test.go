@@ -0,0 +1,116 @@
+package dashboard
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDashboard() *Dashboard {
+	color.NoColor = true
+	d := New(io.Discard, []string{"http://b.com", "http://a.com"}, time.Second)
+	d.width = 120
+	now := time.Now()
+	d.Record("http://a.com", Sample{Time: now, Up: true, Latency: 10 * time.Millisecond})
+	d.Record("http://a.com", Sample{Time: now, Up: true, Latency: 80 * time.Millisecond})
+	d.Record("http://b.com", Sample{Time: now, Up: false, Err: "connection refused"})
+	return d
+}
+
+func TestRender(t *testing.T) {
+	d := newTestDashboard()
+	screen := strings.Join(d.render(), "\n")
+
+	assert.Contains(t, screen, "2 targets")
+	assert.Contains(t, screen, "100.0%")
+	assert.Contains(t, screen, "0.0%")
+	assert.Contains(t, screen, "▁█")
+	assert.Contains(t, screen, "●●")
+	assert.Contains(t, screen, "○")
+	assert.Contains(t, screen, "P95")
+	assert.Contains(t, screen, "80ms")
+	assert.Contains(t, screen, "1.00")
+	assert.Contains(t, screen, "0.00")
+	assert.NotContains(t, screen, "Recent errors")
+}
+
+func TestRender_Stats(t *testing.T) {
+	d := newTestDashboard()
+	d.SetStats(time.Minute, 50*time.Millisecond)
+	d.Record("http://a.com", Sample{Time: time.Now(), Up: true, Latency: 120 * time.Millisecond})
+	d.selected = 0
+	d.detail = true
+	screen := strings.Join(d.render(), "\n")
+
+	assert.Contains(t, screen, "Latency of http://a.com over the last 1m0s")
+	assert.Contains(t, screen, "min 10ms  mean 70ms  p50 80ms  p90 120ms  p95 120ms  p99 120ms  max 120ms  apdex 0.67 (T=50ms)")
+
+	// Samples older than the window drop out of the stats.
+	d.Record("http://a.com", Sample{Time: time.Now().Add(2 * time.Minute), Up: true, Latency: 30 * time.Millisecond})
+	screen = strings.Join(d.render(), "\n")
+	assert.Contains(t, screen, "min 30ms  mean 30ms")
+}
+
+func TestRemove(t *testing.T) {
+	d := newTestDashboard()
+	d.Remove("http://b.com")
+	d.Remove("http://missing.com")
+	screen := strings.Join(d.render(), "\n")
+
+	assert.Contains(t, screen, "1 targets")
+	assert.NotContains(t, screen, "http://b.com")
+}
+
+func TestHandleKey(t *testing.T) {
+	d := newTestDashboard()
+
+	assert.True(t, d.handleKey([]byte("p")))
+	assert.True(t, d.Paused())
+
+	d.handleKey([]byte("s"))
+	rows := d.visible()
+	assert.Equal(t, "http://b.com", rows[0].name, "status sort should put down targets first")
+
+	d.handleKey([]byte("\r"))
+	screen := strings.Join(d.render(), "\n")
+	assert.Contains(t, screen, "Recent errors for http://b.com")
+	assert.Contains(t, screen, "connection refused")
+
+	d.handleKey([]byte("r"))
+	select {
+	case <-d.Recheck():
+	default:
+		t.Error("expected a recheck request")
+	}
+
+	assert.False(t, d.handleKey([]byte("q")))
+}
+
+func TestFilter(t *testing.T) {
+	d := newTestDashboard()
+	for _, k := range []string{"/", "a", ".", "c", "\r"} {
+		d.handleKey([]byte(k))
+	}
+	rows := d.visible()
+	assert.Len(t, rows, 1)
+	assert.Equal(t, "http://a.com", rows[0].name)
+
+	d.handleKey([]byte("/"))
+	d.handleKey([]byte{0x1b})
+	assert.Len(t, d.visible(), 2)
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abcdef", 3))
+	assert.Equal(t, "abcdef", truncate("abcdef", 10))
+	assert.Equal(t, "ab   ", pad("ab", 5))
+}
+
+func TestShorten(t *testing.T) {
+	assert.Equal(t, "http:…18080", shorten("http://127.0.0.1:18080", 11))
+	assert.Equal(t, "http://a.io", shorten("http://a.io", 11))
+}
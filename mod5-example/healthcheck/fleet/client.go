@@ -0,0 +1,82 @@
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// clientTimeout caps each request an agent makes to the collector.
+const clientTimeout = 30 * time.Second
+
+// Client is an agent's connection to the collector at URL.
+type Client struct {
+	URL   string
+	Agent string
+	Token string
+	// HTTP is the client requests are made with. It defaults to one with
+	// a 30s timeout.
+	HTTP *http.Client
+}
+
+// Assignment fetches the agent's assignment, which also tells the collector
+// the agent is alive.
+func (c *Client) Assignment(ctx context.Context) (Assignment, error) {
+	var a Assignment
+	err := c.do(ctx, http.MethodGet, "assignment", nil, &a)
+	return a, err
+}
+
+// Report sends results to the collector.
+func (c *Client) Report(ctx context.Context, results []Result) error {
+	return c.do(ctx, http.MethodPost, "results", Report{Results: results}, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	u := strings.TrimSuffix(c.URL, "/") + "/api/v1/agents/" + url.PathEscape(c.Agent) + "/" + path
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := c.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: clientTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&e)
+		if e.Error == "" {
+			e.Error = resp.Status
+		}
+		return fmt.Errorf("collector refused %s: %s", path, e.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,46 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient(t *testing.T) {
+	var reported Report
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "missing or invalid token"}`))
+			return
+		}
+		switch r.Method + " " + r.URL.EscapedPath() {
+		case "GET /api/v1/agents/eu%2Fwest/assignment":
+			json.NewEncoder(w).Encode(Assignment{Targets: []Target{{Name: "api", URL: "http://api.test"}}, ReportInterval: time.Second})
+		case "POST /api/v1/agents/eu%2Fwest/results":
+			json.NewDecoder(r.Body).Decode(&reported)
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{URL: srv.URL + "/", Agent: "eu/west", Token: "secret"}
+	a, err := c.Assignment(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, Assignment{Targets: []Target{{Name: "api", URL: "http://api.test"}}, ReportInterval: time.Second}, a)
+
+	results := []Result{{Target: "api", URL: "http://api.test", Time: now, Up: true, StatusCode: 200, Latency: time.Millisecond}}
+	assert.NoError(t, c.Report(context.Background(), results))
+	assert.Equal(t, results, reported.Results)
+
+	c.Token = "wrong"
+	_, err = c.Assignment(context.Background())
+	assert.EqualError(t, err, "collector refused assignment: missing or invalid token")
+}
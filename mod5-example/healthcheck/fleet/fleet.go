@@ -0,0 +1,265 @@
+// Package fleet lets healthcheck agents on several machines run the checks
+// a central collector assigns them and report the results back. The
+// collector only declares a target down when enough agents agree, which
+// tells an outage of the target apart from a problem with one agent's
+// network.
+package fleet
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+)
+
+// Target is a target assigned to an agent.
+type Target struct {
+	Name     string            `json:"name"`
+	URL      string            `json:"url"`
+	Interval time.Duration     `json:"interval,omitempty"`
+	Cron     string            `json:"cron,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
+}
+
+// Config returns t as a target of the config file.
+func (t Target) Config() config.Target {
+	return config.Target{Name: t.Name, URL: t.URL, Interval: t.Interval, Cron: t.Cron, Tags: t.Tags}
+}
+
+// Assignment is the work the collector gives an agent.
+type Assignment struct {
+	Targets []Target `json:"targets"`
+	// ReportInterval is how often the agent sends its results.
+	ReportInterval time.Duration `json:"report_interval"`
+}
+
+// Result is a check run by an agent.
+type Result struct {
+	Target     string        `json:"target"`
+	URL        string        `json:"url"`
+	Time       time.Time     `json:"time"`
+	Up         bool          `json:"up"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Latency    time.Duration `json:"latency"`
+	Err        string        `json:"err,omitempty"`
+}
+
+// Report is a batch of results sent by an agent.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Verdict is what the agents checking a target agree on.
+type Verdict struct {
+	// State is Down if at least Quorum agents saw the target fail, Up if
+	// fewer did and Unknown if no live agent has checked it yet.
+	State status.State `json:"state"`
+	// Agents counts the live agents assigned the target, whether they
+	// have checked it yet or not.
+	Agents int    `json:"agents"`
+	Down   int    `json:"down"`
+	Quorum int    `json:"quorum"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Agent is an agent the collector has heard from.
+type Agent struct {
+	Name     string    `json:"name"`
+	LastSeen time.Time `json:"last_seen"`
+	// Live agents have been heard from within the collector's timeout.
+	// Only their results count towards verdicts.
+	Live bool `json:"live"`
+}
+
+// Collector assigns targets to agents and keeps the latest result of each
+// agent for every target. It is safe for concurrent use.
+type Collector struct {
+	quorum  int
+	timeout time.Duration
+
+	mu      sync.Mutex
+	targets []config.Target
+	seen    map[string]time.Time
+	// latest holds the latest result of each agent, by target and agent.
+	latest map[string]map[string]Result
+}
+
+// NewCollector returns a collector for targets that declares a target down
+// once quorum agents see it fail, or a majority of the live agents assigned
+// it if quorum is zero. Agents not heard from within timeout stop counting.
+func NewCollector(targets []config.Target, quorum int, timeout time.Duration) *Collector {
+	return &Collector{
+		quorum:  quorum,
+		timeout: timeout,
+		targets: targets,
+		seen:    make(map[string]time.Time),
+		latest:  make(map[string]map[string]Result),
+	}
+}
+
+// Target returns the target called name, if there is one.
+func (c *Collector) Target(name string) (config.Target, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if i := c.index(name); i >= 0 {
+		return c.targets[i], true
+	}
+	return config.Target{}, false
+}
+
+// Targets returns every target, in config order.
+func (c *Collector) Targets() []config.Target {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]config.Target(nil), c.targets...)
+}
+
+func (c *Collector) index(name string) int {
+	for i, t := range c.targets {
+		if t.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// assigned reports whether agent checks t: targets that don't list their
+// agents are checked by all of them.
+func assigned(t config.Target, agent string) bool {
+	if len(t.Agents) == 0 {
+		return true
+	}
+	for _, a := range t.Agents {
+		if a == agent {
+			return true
+		}
+	}
+	return false
+}
+
+// Assign records that agent is alive at now and returns the targets it
+// checks.
+func (c *Collector) Assign(agent string, now time.Time) []Target {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen[agent] = now
+	var targets []Target
+	for _, t := range c.targets {
+		if assigned(t, agent) {
+			targets = append(targets, Target{Name: t.Name, URL: t.URL, Interval: t.Interval, Cron: t.Cron, Tags: t.Tags})
+		}
+	}
+	return targets
+}
+
+// Record keeps the results agent reported at now and returns those it
+// accepted. Results for targets the agent isn't assigned, for example
+// because they were assigned to others since, are ignored, and so are
+// results older than the agent's latest for the same target.
+func (c *Collector) Record(agent string, results []Result, now time.Time) []Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen[agent] = now
+	var accepted []Result
+	for _, r := range results {
+		i := c.index(r.Target)
+		if i < 0 || !assigned(c.targets[i], agent) {
+			continue
+		}
+		byAgent := c.latest[r.Target]
+		if byAgent == nil {
+			byAgent = make(map[string]Result)
+			c.latest[r.Target] = byAgent
+		}
+		if prev, ok := byAgent[agent]; ok && r.Time.Before(prev.Time) {
+			continue
+		}
+		byAgent[agent] = r
+		accepted = append(accepted, r)
+	}
+	return accepted
+}
+
+// live reports whether agent was heard from within the timeout. c.mu must
+// be held.
+func (c *Collector) live(agent string, now time.Time) bool {
+	seen, ok := c.seen[agent]
+	return ok && now.Sub(seen) <= c.timeout
+}
+
+// Verdict returns what the live agents agree on about the target called
+// name at now.
+func (c *Collector) Verdict(name string, now time.Time) Verdict {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := c.index(name)
+	if i < 0 {
+		return Verdict{State: status.Unknown}
+	}
+	var v Verdict
+	var failed []string
+	checked := 0
+	for agent := range c.seen {
+		if !c.live(agent, now) || !assigned(c.targets[i], agent) {
+			continue
+		}
+		v.Agents++
+		r, ok := c.latest[name][agent]
+		if !ok {
+			continue
+		}
+		checked++
+		if !r.Up {
+			v.Down++
+			failed = append(failed, agent)
+		}
+	}
+	v.Quorum = c.quorum
+	if v.Quorum == 0 {
+		v.Quorum = v.Agents/2 + 1
+	}
+	sort.Strings(failed)
+	switch {
+	case checked == 0:
+		v.State = status.Unknown
+	case v.Down >= v.Quorum:
+		v.State = status.Down
+		v.Reason = fmt.Sprintf("down from %d of %d agents", v.Down, v.Agents)
+		if err := c.latest[name][failed[0]].Err; err != "" {
+			v.Reason += ": " + err
+		}
+	default:
+		v.State = status.Up
+		if v.Down > 0 {
+			v.Reason = fmt.Sprintf("down from %v only, below the quorum of %d", failed, v.Quorum)
+		}
+	}
+	return v
+}
+
+// Results returns the latest result of every agent that checked the target
+// called name, by agent.
+func (c *Collector) Results(name string) map[string]Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	results := make(map[string]Result, len(c.latest[name]))
+	for agent, r := range c.latest[name] {
+		results[agent] = r
+	}
+	return results
+}
+
+// Agents returns the agents heard from, by name.
+func (c *Collector) Agents(now time.Time) []Agent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	agents := make([]Agent, 0, len(c.seen))
+	for name, seen := range c.seen {
+		agents = append(agents, Agent{Name: name, LastSeen: seen, Live: c.live(name, now)})
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Name < agents[j].Name })
+	return agents
+}
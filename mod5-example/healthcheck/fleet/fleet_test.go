@@ -0,0 +1,86 @@
+package fleet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func TestCollector_Assign(t *testing.T) {
+	c := NewCollector([]config.Target{
+		{Name: "api", URL: "http://api.test", Interval: time.Minute},
+		{Name: "eu", URL: "http://eu.test", Agents: []string{"eu-west", "eu-central"}},
+	}, 0, time.Minute)
+
+	assert.Equal(t, []Target{{Name: "api", URL: "http://api.test", Interval: time.Minute}, {Name: "eu", URL: "http://eu.test"}}, c.Assign("eu-west", now))
+	assert.Equal(t, []Target{{Name: "api", URL: "http://api.test", Interval: time.Minute}}, c.Assign("us-east", now))
+	assert.Equal(t, []Agent{{Name: "eu-west", LastSeen: now, Live: true}, {Name: "us-east", LastSeen: now, Live: true}}, c.Agents(now))
+	assert.False(t, c.Agents(now.Add(2 * time.Minute))[0].Live)
+}
+
+func TestCollector_Record(t *testing.T) {
+	c := NewCollector([]config.Target{{Name: "api"}, {Name: "eu", Agents: []string{"eu-west"}}}, 0, time.Minute)
+	accepted := c.Record("us-east", []Result{
+		{Target: "api", Time: now, Up: true},
+		{Target: "eu", Time: now},
+		{Target: "removed", Time: now},
+		{Target: "api", Time: now.Add(-time.Second)},
+	}, now)
+	assert.Equal(t, []Result{{Target: "api", Time: now, Up: true}}, accepted, "only the latest results of assigned targets are kept")
+	assert.Equal(t, map[string]Result{"us-east": {Target: "api", Time: now, Up: true}}, c.Results("api"))
+	assert.Empty(t, c.Results("eu"))
+}
+
+func TestCollector_Verdict(t *testing.T) {
+	tests := []struct {
+		name    string
+		quorum  int
+		results map[string]bool
+		stale   []string
+		// waiting are live agents that haven't checked the target yet.
+		waiting  []string
+		expected Verdict
+	}{
+		{"No results", 0, nil, nil, []string{"a"}, Verdict{State: status.Unknown, Agents: 1, Quorum: 1}},
+		{"All up", 0, map[string]bool{"a": true, "b": true, "c": true}, nil, nil, Verdict{State: status.Up, Agents: 3, Quorum: 2}},
+		{"Majority down", 0, map[string]bool{"a": false, "b": false, "c": true}, nil,
+			nil, Verdict{State: status.Down, Agents: 3, Down: 2, Quorum: 2, Reason: "down from 2 of 3 agents: timeout from a"}},
+		{"Below majority", 0, map[string]bool{"a": true, "b": false, "c": true}, nil,
+			nil, Verdict{State: status.Up, Agents: 3, Down: 1, Quorum: 2, Reason: "down from [b] only, below the quorum of 2"}},
+		{"Explicit quorum", 3, map[string]bool{"a": false, "b": false, "c": true}, nil,
+			nil, Verdict{State: status.Up, Agents: 3, Down: 2, Quorum: 3, Reason: "down from [a b] only, below the quorum of 3"}},
+		{"Single agent", 0, map[string]bool{"a": false}, nil,
+			nil, Verdict{State: status.Down, Agents: 1, Down: 1, Quorum: 1, Reason: "down from 1 of 1 agents: timeout from a"}},
+		{"Stale agents don't count", 0, map[string]bool{"a": false, "b": false, "c": true}, []string{"a", "b"},
+			nil, Verdict{State: status.Up, Agents: 1, Quorum: 1}},
+		{"Agents yet to check count", 0, map[string]bool{"a": false, "b": false}, nil, []string{"c", "d"},
+			Verdict{State: status.Up, Agents: 4, Down: 2, Quorum: 3, Reason: "down from [a b] only, below the quorum of 3"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewCollector([]config.Target{{Name: "api"}}, tc.quorum, time.Minute)
+			for agent, up := range tc.results {
+				seen := now
+				for _, s := range tc.stale {
+					if s == agent {
+						seen = now.Add(-2 * time.Minute)
+					}
+				}
+				r := Result{Target: "api", Time: seen, Up: up}
+				if !up {
+					r.Err = "timeout from " + agent
+				}
+				c.Record(agent, []Result{r}, seen)
+			}
+			for _, agent := range tc.waiting {
+				c.Assign(agent, now)
+			}
+			assert.Equal(t, tc.expected, c.Verdict("api", now))
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// Package graph arranges targets by the dependencies they declare with
+// depends_on, and draws them as a tree or as a Graphviz DOT graph.
+package graph
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/composite"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+)
+
+// Graph links every target to the targets it depends on, its parents, and
+// to those depending on it, its children. Composite targets depend on the
+// targets their expression uses. The dependencies must not form a cycle,
+// which the config file is checked for.
+type Graph struct {
+	names    []string
+	parents  map[string][]string
+	children map[string][]string
+}
+
+// New returns the graph of targets. Dependencies on targets that aren't
+// among them are ignored.
+func New(targets []config.Target) *Graph {
+	g := &Graph{parents: make(map[string][]string), children: make(map[string][]string)}
+	for _, t := range targets {
+		g.names = append(g.names, t.Name)
+		g.parents[t.Name] = nil
+	}
+	for _, t := range targets {
+		parents := t.DependsOn
+		if e, err := composite.Parse(t.Expr); t.Expr != "" && err == nil {
+			parents = slices.Concat(parents, e.Targets())
+		}
+		for _, p := range parents {
+			if _, ok := g.parents[p]; !ok {
+				continue
+			}
+			g.parents[t.Name] = append(g.parents[t.Name], p)
+			g.children[p] = append(g.children[p], t.Name)
+		}
+	}
+	return g
+}
+
+// Parents returns the targets name depends on.
+func (g *Graph) Parents(name string) []string {
+	return g.parents[name]
+}
+
+// Children returns the targets depending on name, in target order.
+func (g *Graph) Children(name string) []string {
+	return g.children[name]
+}
+
+// Roots returns the targets that don't depend on any other, in target
+// order.
+func (g *Graph) Roots() []string {
+	var roots []string
+	for _, name := range g.names {
+		if len(g.parents[name]) == 0 {
+			roots = append(roots, name)
+		}
+	}
+	return roots
+}
+
+// Levels groups the targets so that every target comes in a later level
+// than all of its parents. The first level holds the roots. Targets of the
+// same level don't depend on each other.
+func (g *Graph) Levels() [][]string {
+	depth := make(map[string]int, len(g.names))
+	var visit func(name string) int
+	visit = func(name string) int {
+		if d, ok := depth[name]; ok {
+			return d
+		}
+		d := 0
+		for _, p := range g.parents[name] {
+			d = max(d, visit(p)+1)
+		}
+		depth[name] = d
+		return d
+	}
+	var levels [][]string
+	for _, name := range g.names {
+		d := visit(name)
+		for len(levels) <= d {
+			levels = append(levels, nil)
+		}
+		levels[d] = append(levels[d], name)
+	}
+	return levels
+}
+
+// WriteTree draws the graph as a tree from its roots, with each target
+// described by label. Targets with several parents appear under each of
+// them.
+func (g *Graph) WriteTree(w io.Writer, label func(name string) string) error {
+	var write func(name, prefix, branch, indent string) error
+	write = func(name, prefix, branch, indent string) error {
+		if _, err := fmt.Fprintf(w, "%s%s%s\n", prefix, branch, label(name)); err != nil {
+			return err
+		}
+		children := g.children[name]
+		for i, c := range children {
+			branch, next := "├── ", "│   "
+			if i == len(children)-1 {
+				branch, next = "└── ", "    "
+			}
+			if err := write(c, prefix+indent, branch, next); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, name := range g.Roots() {
+		if err := write(name, "", "", ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dotColors fill the nodes of a DOT graph by state.
+var dotColors = map[status.State]string{
+	status.Up:          "#3fb950",
+	status.Down:        "#f85149",
+	status.Degraded:    "#d29922",
+	status.Flapping:    "#bc8cff",
+	status.Maintenance: "#58a6ff",
+	status.Unreachable: "#6e7681",
+	status.Unknown:     "#8a94a3",
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language, with an edge from
+// every parent to its children and nodes labeled and filled by the state
+// of their target.
+func (g *Graph) WriteDOT(w io.Writer, state func(name string) status.State) error {
+	var b strings.Builder
+	b.WriteString("digraph healthcheck {\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+	for _, name := range g.names {
+		s := state(name)
+		fmt.Fprintf(&b, "  %s [label=%s, fillcolor=%s];\n", quote(name), quote(name+`\n`+string(s)), quote(dotColors[s]))
+	}
+	for _, name := range g.names {
+		for _, c := range g.children[name] {
+			fmt.Fprintf(&b, "  %s -> %s;\n", quote(name), quote(c))
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// quote writes s as a DOT string. Backslashes are kept, so labels can use
+// DOT escapes such as \n.
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/stretchr/testify/assert"
+)
+
+// targets is a load balancer in front of an api and a web frontend, which
+// both use the database.
+var targets = []config.Target{
+	{Name: "db"},
+	{Name: "web", DependsOn: []string{"lb", "db"}},
+	{Name: "lb"},
+	{Name: "api", DependsOn: []string{"lb", "db"}},
+	{Name: "worker", DependsOn: []string{"api", "gone"}},
+}
+
+func TestGraph(t *testing.T) {
+	g := New(targets)
+	assert.Equal(t, []string{"db", "lb"}, g.Roots())
+	assert.Equal(t, []string{"lb", "db"}, g.Parents("web"))
+	assert.Equal(t, []string{"api"}, g.Parents("worker"), "unknown targets should be ignored")
+	assert.Equal(t, []string{"web", "api"}, g.Children("lb"))
+	assert.Equal(t, [][]string{{"db", "lb"}, {"web", "api"}, {"worker"}}, g.Levels())
+}
+
+func TestNew_Composite(t *testing.T) {
+	g := New([]config.Target{
+		{Name: "api-1"},
+		{Name: "api-2"},
+		{Name: "api", Expr: "quorum(1, api-1, api-2)", DependsOn: []string{"lb"}},
+		{Name: "lb"},
+	})
+	assert.Equal(t, []string{"lb", "api-1", "api-2"}, g.Parents("api"))
+	assert.Equal(t, []string{"api"}, g.Children("api-2"))
+	assert.Equal(t, [][]string{{"api-1", "api-2", "lb"}, {"api"}}, g.Levels())
+}
+
+func TestWriteTree(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, New(targets).WriteTree(&b, func(name string) string { return name + " (" + name[:1] + ")" }))
+	assert.Equal(t, `db (d)
+├── web (w)
+└── api (a)
+    └── worker (w)
+lb (l)
+├── web (w)
+└── api (a)
+    └── worker (w)
+`, b.String())
+}
+
+func TestWriteDOT(t *testing.T) {
+	states := map[string]status.State{"lb": status.Down, "api": status.Unreachable}
+	var b bytes.Buffer
+	err := New(targets[2:4]).WriteDOT(&b, func(name string) status.State {
+		if s, ok := states[name]; ok {
+			return s
+		}
+		return status.Up
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `digraph healthcheck {
+  node [shape=box, style="rounded,filled", fontname="Helvetica"];
+  "lb" [label="lb\ndown", fillcolor="#f85149"];
+  "api" [label="api\nunreachable", fillcolor="#6e7681"];
+  "lb" -> "api";
+}
+`, b.String())
+}
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, `"say \"hi\""`, quote(`say "hi"`))
+}
@@ -0,0 +1,145 @@
+// Package history reads past check results back out of the healthcheck log
+// file, in either the JSON or the text log format.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sample is the recorded outcome of one check.
+type Sample struct {
+	Time       time.Time
+	URL        string
+	Up         bool
+	StatusCode int
+	Latency    time.Duration
+	Err        string
+	// Maintenance is the ID of the maintenance window the check ran in,
+	// if any.
+	Maintenance string
+}
+
+// ReadLog returns the check results logged to path at or after since, in
+// the order they were written. Lines that aren't check results are skipped.
+func ReadLog(path string, since time.Time) ([]Sample, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open log file: %w", err)
+	}
+	defer file.Close()
+
+	var samples []Sample
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := parseLine(scanner.Text())
+		if fields == nil {
+			continue
+		}
+		s, ok := toSample(fields)
+		if ok && !s.Time.Before(since) {
+			samples = append(samples, s)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read log file: %w", err)
+	}
+	return samples, nil
+}
+
+// toSample interprets the messages logged by a check: "successful check" and
+// "exceeded threshold" for completed requests and "fetching error" once all
+// retries failed.
+func toSample(f map[string]string) (Sample, bool) {
+	t, err := time.Parse(time.RFC3339Nano, f["time"])
+	if err != nil || f["url"] == "" {
+		return Sample{}, false
+	}
+	s := Sample{Time: t, URL: f["url"], Maintenance: f["maintenance"]}
+	s.StatusCode, _ = strconv.Atoi(f["statusCode"])
+	switch f["msg"] {
+	case "successful check":
+		s.Latency = parseDuration(f["duration"])
+		s.Up = s.StatusCode == 200
+	case "exceeded threshold":
+		s.Latency = parseDuration(f["responseTime"])
+		// Older logs don't record the status code of slow responses.
+		s.Up = s.StatusCode == 0 || s.StatusCode == 200
+	case "fetching error", "failed to create request":
+		s.Err = f["err"]
+	default:
+		return Sample{}, false
+	}
+	return s, true
+}
+
+// parseDuration accepts both the text handler's "1.5ms" and the JSON
+// handler's integer nanoseconds.
+func parseDuration(v string) time.Duration {
+	if d, err := time.ParseDuration(v); err == nil {
+		return d
+	}
+	ns, _ := strconv.ParseInt(v, 10, 64)
+	return time.Duration(ns)
+}
+
+// parseLine returns the top-level attributes of a JSON or text log line,
+// with every value as a string, or nil if the line can't be parsed.
+func parseLine(line string) map[string]string {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "{") {
+		var raw map[string]any
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			return nil
+		}
+		fields := make(map[string]string, len(raw))
+		for k, v := range raw {
+			if n, ok := v.(float64); ok {
+				fields[k] = strconv.FormatFloat(n, 'f', -1, 64)
+			} else {
+				fields[k] = fmt.Sprint(v)
+			}
+		}
+		return fields
+	}
+	return parseText(line)
+}
+
+// parseText parses the key=value pairs written by slog's text handler,
+// where values containing spaces or quotes are Go-quoted.
+func parseText(line string) map[string]string {
+	fields := make(map[string]string)
+	for line != "" {
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			return nil
+		}
+		key := line[:eq]
+		line = line[eq+1:]
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			quoted, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil
+			}
+			value, _ = strconv.Unquote(quoted)
+			line = line[len(quoted):]
+		} else {
+			end := strings.IndexByte(line, ' ')
+			if end < 0 {
+				end = len(line)
+			}
+			value = line[:end]
+			line = line[end:]
+		}
+		fields[key] = value
+		line = strings.TrimLeft(line, " ")
+	}
+	return fields
+}
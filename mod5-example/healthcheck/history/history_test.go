@@ -0,0 +1,72 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const textLog = `time=2024-04-19T16:56:18.407-07:00 level=INFO msg="successful check" url=http://www.google.com statusCode=200 duration=213.709µs
+time=2024-04-19T16:56:18.408-07:00 level=INFO msg="successful check" url=http://www.tripadvisor.com statusCode=500 duration=16.959µs
+time=2024-04-19T16:56:18.459-07:00 level=WARN msg="exceeded threshold" url=http://www.tripadvisor.com responseTime=51.123125ms
+time=2024-04-19T16:56:18.459-07:00 level=ERROR msg="failed to perform request" url=http://www.example.com err="Get \"http://www.example.com\": context deadline exceeded"
+time=2024-04-19T16:56:19.461-07:00 level=ERROR msg="fetching error" url=http://www.example.com retries=1 err="Get \"http://www.example.com\": context deadline exceeded"
+time=2024-04-19T16:56:21.461-07:00 level=ERROR msg="fetching error" url=http://www.example.com retries=1 err="connection refused" maintenance=a1b2c3d4
+`
+
+const jsonLog = `{"time":"2024-03-21T01:50:02.416832Z","level":"INFO","msg":"successful check","url":"http://www.google.com","statusCode":200,"duration":92382084}
+{"time":"2024-03-21T01:50:03.382959Z","level":"WARN","msg":"exceeded threshold","url":"http://www.aol.com","statusCode":503,"responseTime":714953375}
+not a log line
+`
+
+func writeLog(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "healthcheck.log")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadLog_Text(t *testing.T) {
+	samples, err := ReadLog(writeLog(t, textLog), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, samples, 5)
+
+	assert.True(t, samples[0].Up)
+	assert.Equal(t, 213709*time.Nanosecond, samples[0].Latency)
+	assert.False(t, samples[1].Up)
+	assert.Equal(t, 500, samples[1].StatusCode)
+	assert.True(t, samples[2].Up, "slow responses without a status code are from older logs")
+	assert.Equal(t, 51123125*time.Nanosecond, samples[2].Latency)
+	assert.False(t, samples[3].Up)
+	assert.Equal(t, `Get "http://www.example.com": context deadline exceeded`, samples[3].Err)
+	assert.Empty(t, samples[3].Maintenance)
+	assert.Equal(t, "a1b2c3d4", samples[4].Maintenance)
+}
+
+func TestReadLog_JSON(t *testing.T) {
+	samples, err := ReadLog(writeLog(t, jsonLog), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, samples, 2)
+	assert.Equal(t, 92382084*time.Nanosecond, samples[0].Latency)
+	assert.True(t, samples[0].Up)
+	assert.False(t, samples[1].Up)
+	assert.Equal(t, 503, samples[1].StatusCode)
+}
+
+func TestReadLog_Since(t *testing.T) {
+	since := time.Date(2024, 4, 19, 23, 56, 19, 0, time.UTC)
+	samples, err := ReadLog(writeLog(t, textLog), since)
+	assert.NoError(t, err)
+	assert.Len(t, samples, 2)
+	assert.Equal(t, "http://www.example.com", samples[0].URL)
+}
+
+func TestReadLog_MissingFile(t *testing.T) {
+	_, err := ReadLog(filepath.Join(t.TempDir(), "missing.log"), time.Time{})
+	assert.ErrorContains(t, err, "unable to open log file")
+}
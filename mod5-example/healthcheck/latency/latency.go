@@ -0,0 +1,142 @@
+// Package latency describes the distribution of response times: the
+// percentiles, the extremes and the Apdex score of a set of checks.
+package latency
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+)
+
+// Sample is the outcome of one check.
+type Sample struct {
+	Up      bool
+	Latency time.Duration
+}
+
+// Stats summarizes the latency of a set of checks. The latency figures
+// cover the successful checks, since a failed check's response time says
+// little about the service.
+type Stats struct {
+	Checks int           `json:"checks"`
+	Min    time.Duration `json:"min"`
+	Max    time.Duration `json:"max"`
+	Mean   time.Duration `json:"mean"`
+	P50    time.Duration `json:"p50"`
+	P90    time.Duration `json:"p90"`
+	P95    time.Duration `json:"p95"`
+	P99    time.Duration `json:"p99"`
+	// Apdex is the application performance index for the threshold
+	// ApdexT: checks no slower than ApdexT count fully, those no slower than
+	// four times ApdexT count half and failed checks not at all. It ranges
+	// from 0 to 1.
+	Apdex  float64       `json:"apdex"`
+	ApdexT time.Duration `json:"apdex_t"`
+	// Approximate is set when the figures were derived from hourly rollups,
+	// in which case percentiles are rounded up to the store's
+	// LatencyBuckets.
+	Approximate bool `json:"approximate,omitempty"`
+}
+
+// Summarize computes the stats of samples, scoring Apdex against t.
+func Summarize(samples []Sample, t time.Duration) Stats {
+	s := Stats{Checks: len(samples), ApdexT: t}
+	var latencies []time.Duration
+	var total time.Duration
+	satisfied, tolerating := 0, 0
+	for _, sample := range samples {
+		if !sample.Up {
+			continue
+		}
+		switch {
+		case sample.Latency <= t:
+			satisfied++
+		case sample.Latency <= 4*t:
+			tolerating++
+		}
+		if sample.Latency > 0 {
+			latencies = append(latencies, sample.Latency)
+			total += sample.Latency
+		}
+	}
+	if s.Checks > 0 {
+		s.Apdex = (float64(satisfied) + float64(tolerating)/2) / float64(s.Checks)
+	}
+	if len(latencies) == 0 {
+		return s
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	s.Min = latencies[0]
+	s.Max = latencies[len(latencies)-1]
+	s.Mean = total / time.Duration(len(latencies))
+	s.P50 = percentile(latencies, 50)
+	s.P90 = percentile(latencies, 90)
+	s.P95 = percentile(latencies, 95)
+	s.P99 = percentile(latencies, 99)
+	return s
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// SummarizeRollups computes approximate stats from hourly rollups, for
+// periods whose raw results are no longer kept. Min, Max and Mean cover
+// every timed check, failed or not.
+func SummarizeRollups(rollups []store.Rollup, t time.Duration) Stats {
+	s := Stats{ApdexT: t, Approximate: true}
+	buckets := make([]int, len(store.LatencyBuckets)+1)
+	var total time.Duration
+	timed, satisfied, tolerating := 0, 0, 0
+	for _, r := range rollups {
+		s.Checks += r.Checks
+		satisfied += r.UpWithin(t)
+		tolerating += r.UpWithin(4*t) - r.UpWithin(t)
+		total += r.TotalLatency
+		timed += r.Timed
+		s.Max = max(s.Max, r.MaxLatency)
+		if r.MinLatency > 0 && (s.Min == 0 || r.MinLatency < s.Min) {
+			s.Min = r.MinLatency
+		}
+		for i, n := range r.Buckets {
+			buckets[i] += n
+		}
+	}
+	if s.Checks > 0 {
+		s.Apdex = (float64(satisfied) + float64(tolerating)/2) / float64(s.Checks)
+	}
+	if timed > 0 {
+		s.Mean = total / time.Duration(timed)
+	}
+	s.P50 = bucketPercentile(buckets, 50, s.Max)
+	s.P90 = bucketPercentile(buckets, 90, s.Max)
+	s.P95 = bucketPercentile(buckets, 95, s.Max)
+	s.P99 = bucketPercentile(buckets, 99, s.Max)
+	return s
+}
+
+// bucketPercentile returns the upper bound of the histogram bucket holding
+// the p-th percentile, or slowest for the overflow bucket.
+func bucketPercentile(buckets []int, p float64, slowest time.Duration) time.Duration {
+	n := 0
+	for _, count := range buckets {
+		n += count
+	}
+	if n == 0 {
+		return 0
+	}
+	rank := max(int(math.Ceil(p/100*float64(n))), 1)
+	for i, count := range buckets {
+		if rank -= count; rank <= 0 {
+			if i < len(store.LatencyBuckets) {
+				return min(store.LatencyBuckets[i], slowest)
+			}
+			break
+		}
+	}
+	return slowest
+}
@@ -0,0 +1,66 @@
+package latency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarize(t *testing.T) {
+	var samples []Sample
+	for i := 1; i <= 100; i++ {
+		samples = append(samples, Sample{Up: true, Latency: time.Duration(i) * time.Millisecond})
+	}
+	samples = append(samples, Sample{Up: false})
+
+	s := Summarize(samples, 50*time.Millisecond)
+	assert.Equal(t, 101, s.Checks)
+	assert.Equal(t, time.Millisecond, s.Min)
+	assert.Equal(t, 100*time.Millisecond, s.Max)
+	assert.Equal(t, 50500*time.Microsecond, s.Mean)
+	assert.Equal(t, 50*time.Millisecond, s.P50)
+	assert.Equal(t, 90*time.Millisecond, s.P90)
+	assert.Equal(t, 95*time.Millisecond, s.P95)
+	assert.Equal(t, 99*time.Millisecond, s.P99)
+	// 50 satisfied, 50 tolerating and one failure.
+	assert.InDelta(t, 75.0/101, s.Apdex, 0.0001)
+	assert.False(t, s.Approximate)
+}
+
+func TestSummarize_Empty(t *testing.T) {
+	s := Summarize(nil, time.Second)
+	assert.Equal(t, Stats{ApdexT: time.Second}, s)
+
+	s = Summarize([]Sample{{Up: false}}, time.Second)
+	assert.Equal(t, 1, s.Checks)
+	assert.Zero(t, s.Apdex)
+	assert.Zero(t, s.P99)
+}
+
+func TestSummarizeRollups(t *testing.T) {
+	st, err := store.Open(t.TempDir()+"/healthcheck.db", store.DefaultRetention)
+	assert.NoError(t, err)
+	hour := time.Date(2024, 4, 19, 8, 0, 0, 0, time.UTC)
+	var results []store.Result
+	for i := 0; i < 90; i++ {
+		results = append(results, store.Result{Time: hour.Add(time.Duration(i) * time.Second), URL: "http://a.com", Up: true, Latency: 20 * time.Millisecond})
+	}
+	for i := 0; i < 10; i++ {
+		results = append(results, store.Result{Time: hour.Add(2*time.Hour + time.Duration(i)*time.Second), URL: "http://a.com", Up: true, Latency: 3 * time.Second})
+	}
+	assert.NoError(t, st.Write(results...))
+	rollups, err := st.Rollups("http://a.com", time.Time{}, time.Time{})
+	assert.NoError(t, err)
+
+	s := SummarizeRollups(rollups, 250*time.Millisecond)
+	assert.True(t, s.Approximate)
+	assert.Equal(t, 100, s.Checks)
+	assert.Equal(t, 20*time.Millisecond, s.Min)
+	assert.Equal(t, 3*time.Second, s.Max)
+	assert.Equal(t, 25*time.Millisecond, s.P50)
+	assert.Equal(t, 25*time.Millisecond, s.P90)
+	assert.Equal(t, 3*time.Second, s.P95)
+	assert.InDelta(t, 0.9, s.Apdex, 0.0001)
+}
@@ -91,7 +91,36 @@ func New(logFile string, verbose, silent bool, output string) *slog.Logger {
 	}
 
 	if silent {
-		return slog.New(handler.GetFileHandler())
+		return slog.New(&contextHandler{handler.GetFileHandler()})
 	}
-	return slog.New(handler)
+	return slog.New(&contextHandler{handler})
+}
+
+type ctxKey struct{}
+
+// WithAttrs returns a copy of ctx whose log records carry attrs in addition
+// to their own, so callers can tag everything logged on their behalf.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	prev, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	return context.WithValue(ctx, ctxKey{}, append(prev[:len(prev):len(prev)], attrs...))
+}
+
+// contextHandler adds the attributes stored by WithAttrs to every record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if attrs, ok := ctx.Value(ctxKey{}).([]slog.Attr); ok {
+		rec.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
 }
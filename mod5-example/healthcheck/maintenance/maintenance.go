@@ -0,0 +1,250 @@
+// Package maintenance describes windows during which targets are knowingly
+// unavailable, so their failures are neither alerted on nor counted as
+// downtime.
+package maintenance
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/scheduler"
+)
+
+// Window is a one-off or recurring maintenance window. It applies to the
+// targets named in Targets and to those whose tags match Selector.
+type Window struct {
+	ID string `json:"id" yaml:"id"`
+	// Targets lists target names the window applies to.
+	Targets []string `json:"targets,omitempty" yaml:"targets"`
+	// Selector is a comma-separated list of key=value pairs that must all
+	// be present in a target's tags, such as "team=payments,env=prod".
+	Selector string `json:"selector,omitempty" yaml:"selector"`
+	// Start and End bound a one-off window. For a recurring window they
+	// are optional and limit when it recurs.
+	Start time.Time `json:"start,omitempty" yaml:"start"`
+	End   time.Time `json:"end,omitempty" yaml:"end"`
+	// Cron makes the window recurring: it opens at every time matching the
+	// five-field expression and stays open for Duration.
+	Cron     string        `json:"cron,omitempty" yaml:"cron"`
+	Duration time.Duration `json:"duration,omitempty" yaml:"duration"`
+	Comment  string        `json:"comment,omitempty" yaml:"comment"`
+
+	// cron and selector are Cron and Selector as parsed by Parse.
+	cron     *scheduler.Cron
+	selector Selector
+}
+
+// Parse parses Cron and Selector once, for Active and Matches to use on
+// every check. Validate and Load call it; until it succeeds, a recurring
+// window is never active and the selector matches no target.
+func (w *Window) Parse() error {
+	sel, err := ParseSelector(w.Selector)
+	if err != nil {
+		return err
+	}
+	var c *scheduler.Cron
+	if w.Cron != "" {
+		if c, err = scheduler.ParseCron(w.Cron); err != nil {
+			return err
+		}
+	}
+	w.cron, w.selector = c, sel
+	return nil
+}
+
+// Validate checks that the window is complete and well-formed, and parses
+// it.
+func (w *Window) Validate() error {
+	if len(w.Targets) == 0 && w.Selector == "" {
+		return errors.New("a maintenance window needs targets or a selector")
+	}
+	if err := w.Parse(); err != nil {
+		return err
+	}
+	if w.Cron != "" {
+		if w.Duration <= 0 {
+			return errors.New("a recurring maintenance window needs a duration")
+		}
+		return nil
+	}
+	if w.Start.IsZero() || w.End.IsZero() || !w.End.After(w.Start) {
+		return errors.New("a maintenance window needs a start before its end")
+	}
+	return nil
+}
+
+// Active reports whether the window is open at t.
+func (w *Window) Active(t time.Time) bool {
+	if !w.Start.IsZero() && t.Before(w.Start) {
+		return false
+	}
+	if !w.End.IsZero() && !t.Before(w.End) {
+		return false
+	}
+	if w.Cron == "" {
+		return true
+	}
+	if w.cron == nil {
+		return false
+	}
+	// The window is open if it last opened within Duration of t.
+	opened := w.cron.Next(t.Add(-w.Duration))
+	return !opened.IsZero() && !opened.After(t)
+}
+
+// Expired reports whether a one-off window has ended by t.
+func (w *Window) Expired(t time.Time) bool {
+	return !w.End.IsZero() && !t.Before(w.End)
+}
+
+// Matches reports whether the window applies to the named target.
+func (w *Window) Matches(name string, tags map[string]string) bool {
+	for _, t := range w.Targets {
+		if t == name {
+			return true
+		}
+	}
+	if w.Selector == "" || w.selector == nil {
+		return false
+	}
+	return w.selector.Matches(tags)
+}
+
+// Selector matches target tags.
+type Selector map[string]string
+
+// ParseSelector parses a comma-separated list of key=value pairs.
+func ParseSelector(s string) (Selector, error) {
+	sel := make(Selector)
+	if strings.TrimSpace(s) == "" {
+		return sel, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid selector %q: expected key=value pairs", s)
+		}
+		sel[k] = v
+	}
+	return sel, nil
+}
+
+// Matches reports whether every pair of the selector is in tags.
+func (s Selector) Matches(tags map[string]string) bool {
+	for k, v := range s {
+		if tags[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// NewID returns a short random identifier for a window.
+func NewID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Load reads and parses the windows saved in the silences file at path. A
+// missing file holds no windows.
+func Load(path string) ([]Window, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read silences file: %w", err)
+	}
+	var windows []Window
+	if err := json.Unmarshal(data, &windows); err != nil {
+		return nil, fmt.Errorf("unable to parse silences file %s: %w", path, err)
+	}
+	for i := range windows {
+		// A window that doesn't parse is kept, so it can still be listed
+		// and removed, but never applies.
+		windows[i].Parse()
+	}
+	return windows, nil
+}
+
+// Save writes windows to the silences file at path, replacing it
+// atomically so a running monitor never reads a partial file.
+func Save(path string, windows []Window) error {
+	data, err := json.MarshalIndent(windows, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("unable to write silences file: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// reloadEvery is how often a Registry looks for changes to its file.
+const reloadEvery = 5 * time.Second
+
+// Registry answers which window, if any, covers a target. It combines the
+// windows from the config file with those in the silences file, which it
+// rereads when it changes so silences added while monitor runs take effect.
+type Registry struct {
+	static []Window
+	path   string
+
+	mu      sync.Mutex
+	file    []Window
+	modTime time.Time
+	checked time.Time
+}
+
+// NewRegistry returns a registry of the static windows and those saved in
+// the silences file at path, which may be empty.
+func NewRegistry(static []Window, path string) *Registry {
+	return &Registry{static: static, path: path}
+}
+
+// Active returns the first window open at t that applies to the target,
+// or nil.
+func (r *Registry) Active(name string, tags map[string]string, t time.Time) *Window {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reload()
+	for _, windows := range [][]Window{r.static, r.file} {
+		for i := range windows {
+			w := &windows[i]
+			if w.Matches(name, tags) && w.Active(t) {
+				return w
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Registry) reload() {
+	if r.path == "" || time.Since(r.checked) < reloadEvery {
+		return
+	}
+	r.checked = time.Now()
+	info, err := os.Stat(r.path)
+	if err != nil {
+		r.file = nil
+		return
+	}
+	if info.ModTime().Equal(r.modTime) {
+		return
+	}
+	windows, err := Load(r.path)
+	if err != nil {
+		return
+	}
+	r.file, r.modTime = windows, info.ModTime()
+}
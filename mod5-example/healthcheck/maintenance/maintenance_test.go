@@ -0,0 +1,115 @@
+package maintenance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindow_Active(t *testing.T) {
+	start := time.Date(2024, 5, 5, 2, 0, 0, 0, time.UTC) // a Sunday
+	oneOff := Window{Start: start, End: start.Add(30 * time.Minute)}
+	weekly := Window{Cron: "0 2 * * 0", Duration: time.Hour}
+
+	tests := []struct {
+		name     string
+		window   Window
+		at       time.Time
+		expected bool
+	}{
+		{"Before one-off", oneOff, start.Add(-time.Second), false},
+		{"Start of one-off", oneOff, start, true},
+		{"End of one-off", oneOff, start.Add(30 * time.Minute), false},
+		{"Recurring opened", weekly, start, true},
+		{"Recurring open", weekly, start.Add(59 * time.Minute), true},
+		{"Recurring closed", weekly, start.Add(time.Hour), false},
+		{"Recurring next week", weekly, start.AddDate(0, 0, 7).Add(10 * time.Minute), true},
+		{"Recurring other day", weekly, start.AddDate(0, 0, 1), false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := tc.window
+			assert.NoError(t, w.Parse())
+			assert.Equal(t, tc.expected, w.Active(tc.at))
+		})
+	}
+}
+
+func TestWindow_Matches(t *testing.T) {
+	tags := map[string]string{"team": "payments", "env": "prod"}
+	tests := []struct {
+		name     string
+		window   Window
+		expected bool
+	}{
+		{"By name", Window{Targets: []string{"api"}}, true},
+		{"Other name", Window{Targets: []string{"docs"}}, false},
+		{"Selector", Window{Selector: "team=payments"}, true},
+		{"Selector with all tags", Window{Selector: "team=payments, env=prod"}, true},
+		{"Selector with other tag", Window{Selector: "team=payments,env=staging"}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := tc.window
+			assert.NoError(t, w.Parse())
+			assert.Equal(t, tc.expected, w.Matches("api", tags))
+		})
+	}
+}
+
+func TestParseSelector_Invalid(t *testing.T) {
+	_, err := ParseSelector("team")
+	assert.ErrorContains(t, err, "expected key=value pairs")
+}
+
+func TestRegistry(t *testing.T) {
+	now := time.Now()
+	path := filepath.Join(t.TempDir(), "silences.json")
+	static := []Window{{ID: "planned", Targets: []string{"docs"}, Start: now.Add(-time.Minute), End: now.Add(time.Minute)}}
+	assert.NoError(t, Save(path, []Window{
+		{ID: "deploy", Selector: "team=payments", Start: now.Add(-time.Minute), End: now.Add(time.Minute)},
+	}))
+
+	r := NewRegistry(static, path)
+	assert.Equal(t, "planned", r.Active("docs", nil, now).ID)
+	assert.Equal(t, "deploy", r.Active("api", map[string]string{"team": "payments"}, now).ID)
+	assert.Nil(t, r.Active("api", nil, now))
+	assert.Nil(t, r.Active("docs", nil, now.Add(time.Hour)))
+}
+
+func TestLoad_Parses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "silences.json")
+	start := time.Date(2024, 5, 5, 2, 0, 0, 0, time.UTC) // a Sunday
+	assert.NoError(t, Save(path, []Window{
+		{ID: "weekly", Selector: "team=payments", Cron: "0 2 * * 0", Duration: time.Hour},
+		{ID: "broken", Selector: "team", Cron: "every sunday", Duration: time.Hour},
+	}))
+
+	windows, err := Load(path)
+	assert.NoError(t, err)
+	if assert.Len(t, windows, 2) {
+		tags := map[string]string{"team": "payments"}
+		assert.True(t, windows[0].Matches("api", tags))
+		assert.True(t, windows[0].Active(start))
+		assert.False(t, windows[1].Matches("api", tags), "windows that don't parse never apply")
+		assert.False(t, windows[1].Active(start))
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	windows, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NoError(t, err)
+	assert.Empty(t, windows)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "silences.json")
+	assert.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+	_, err := Load(path)
+	assert.ErrorContains(t, err, "unable to parse silences file")
+}
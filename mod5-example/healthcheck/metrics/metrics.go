@@ -0,0 +1,324 @@
+// Package metrics exposes the latest check results of the monitor command
+// to Prometheus, in the Prometheus text format or, when the scraper asks
+// for it, in OpenMetrics.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+)
+
+const (
+	textType        = "text/plain; version=0.0.4; charset=utf-8"
+	openMetricsType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// States are the values of the state label of healthcheck_state, one of
+// which is 1 for each target.
+var States = []status.State{status.Up, status.Down, status.Degraded, status.Flapping, status.Maintenance, status.Unreachable, status.Unknown}
+
+// Check is the outcome of one check of a target.
+type Check struct {
+	Time       time.Time
+	Up         bool
+	State      status.State
+	StatusCode int
+	Latency    time.Duration
+	// Phases breaks Latency down by step of the request, such as "dns"
+	// or "connect". It is empty if there was no response.
+	Phases     map[string]time.Duration
+	CertExpiry time.Time
+	Attempts   int
+}
+
+// target holds the series of one target.
+type target struct {
+	labels   []label
+	last     Check
+	checks   uint64
+	failures uint64
+	// buckets counts the responses by store.LatencyBuckets, the last one
+	// holding the slower ones.
+	buckets []uint64
+	sum     time.Duration
+}
+
+type label struct {
+	name, value string
+}
+
+// Registry keeps the metrics of every target.
+type Registry struct {
+	mu      sync.Mutex
+	targets map[string]*target
+}
+
+// New returns an empty registry.
+func New() *Registry {
+	return &Registry{targets: make(map[string]*target)}
+}
+
+// Observe records a check of the target called name. Its URL and tags
+// become labels of every series of the target.
+func (r *Registry) Observe(name, url string, tags map[string]string, c Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.targets[name]
+	if !ok {
+		t = &target{buckets: make([]uint64, len(store.LatencyBuckets)+1)}
+		r.targets[name] = t
+	}
+	t.labels = targetLabels(name, url, tags)
+	t.last = c
+	t.checks++
+	if !c.Up {
+		t.failures++
+	}
+	if c.Latency > 0 {
+		i := sort.Search(len(store.LatencyBuckets), func(i int) bool { return c.Latency <= store.LatencyBuckets[i] })
+		t.buckets[i]++
+		t.sum += c.Latency
+	}
+}
+
+// Remove drops the series of the target called name.
+func (r *Registry) Remove(name string) {
+	r.mu.Lock()
+	delete(r.targets, name)
+	r.mu.Unlock()
+}
+
+// reserved are the label names set by the registry itself, which tags are
+// not allowed to override.
+var reserved = map[string]bool{"target": true, "url": true, "state": true, "phase": true, "le": true}
+
+// targetLabels returns the labels identifying a target. Tag keys are made
+// valid label names, and prefixed with "tag_" if they clash with the
+// registry's own labels.
+func targetLabels(name, url string, tags map[string]string) []label {
+	labels := []label{{"target", name}, {"url", url}}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		n := labelName(k)
+		if reserved[n] || strings.HasPrefix(n, "__") {
+			n = "tag_" + n
+		}
+		labels = append(labels, label{n, tags[k]})
+	}
+	return labels
+}
+
+// labelName replaces the characters not allowed in label names with
+// underscores, and prefixes one if s starts with a digit.
+func labelName(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			b[i] = '_'
+		}
+	}
+	if len(b) == 0 || (b[0] >= '0' && b[0] <= '9') {
+		return "_" + string(b)
+	}
+	return string(b)
+}
+
+// ServeHTTP writes the metrics in the format the scraper prefers.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	openMetrics := strings.Contains(req.Header.Get("Accept"), "application/openmetrics-text")
+	if openMetrics {
+		w.Header().Set("Content-Type", openMetricsType)
+	} else {
+		w.Header().Set("Content-Type", textType)
+	}
+	r.Write(w, openMetrics)
+}
+
+// family is a metric and how to get its samples from a target.
+type family struct {
+	name, kind, help string
+	samples          func(e *encoder, t *target)
+}
+
+var families = []family{
+	{"healthcheck_up", "gauge", "Whether the latest check of the target succeeded.", func(e *encoder, t *target) {
+		e.sample("healthcheck_up", t.labels, bool01(t.last.Up))
+	}},
+	{"healthcheck_state", "gauge", "The state of the target, 1 for the current one.", func(e *encoder, t *target) {
+		state := t.last.State
+		if state == "" {
+			state = status.Unknown
+		}
+		for _, s := range States {
+			e.sample("healthcheck_state", with(t.labels, "state", string(s)), bool01(s == state))
+		}
+	}},
+	{"healthcheck_status_code", "gauge", "HTTP status code of the latest response, 0 if there was none.", func(e *encoder, t *target) {
+		e.sample("healthcheck_status_code", t.labels, float64(t.last.StatusCode))
+	}},
+	{"healthcheck_attempts", "gauge", "Requests made by the latest check, including retries.", func(e *encoder, t *target) {
+		e.sample("healthcheck_attempts", t.labels, float64(t.last.Attempts))
+	}},
+	{"healthcheck_last_check_timestamp_seconds", "gauge", "When the latest check ran.", func(e *encoder, t *target) {
+		e.sample("healthcheck_last_check_timestamp_seconds", t.labels, unix(t.last.Time))
+	}},
+	{"healthcheck_cert_expiry_timestamp_seconds", "gauge", "When the TLS certificate of the target expires.", func(e *encoder, t *target) {
+		if !t.last.CertExpiry.IsZero() {
+			e.sample("healthcheck_cert_expiry_timestamp_seconds", t.labels, unix(t.last.CertExpiry))
+		}
+	}},
+	{"healthcheck_phase_duration_seconds", "gauge", "Duration of each phase of the latest request.", func(e *encoder, t *target) {
+		phases := make([]string, 0, len(t.last.Phases))
+		for p := range t.last.Phases {
+			phases = append(phases, p)
+		}
+		sort.Strings(phases)
+		for _, p := range phases {
+			e.sample("healthcheck_phase_duration_seconds", with(t.labels, "phase", p), t.last.Phases[p].Seconds())
+		}
+	}},
+	{"healthcheck_checks", "counter", "Checks run since monitor started.", func(e *encoder, t *target) {
+		e.sample("healthcheck_checks_total", t.labels, float64(t.checks))
+	}},
+	{"healthcheck_check_failures", "counter", "Failed checks since monitor started.", func(e *encoder, t *target) {
+		e.sample("healthcheck_check_failures_total", t.labels, float64(t.failures))
+	}},
+	{"healthcheck_response_duration_seconds", "histogram", "Time until the response headers arrived.", func(e *encoder, t *target) {
+		var count uint64
+		for i, n := range t.buckets {
+			count += n
+			le := math.Inf(1)
+			if i < len(store.LatencyBuckets) {
+				le = store.LatencyBuckets[i].Seconds()
+			}
+			e.sample("healthcheck_response_duration_seconds_bucket", with(t.labels, "le", formatFloat(le)), float64(count))
+		}
+		e.sample("healthcheck_response_duration_seconds_sum", t.labels, t.sum.Seconds())
+		e.sample("healthcheck_response_duration_seconds_count", t.labels, float64(count))
+	}},
+}
+
+// Write writes the metrics of every target in the Prometheus text format,
+// or in OpenMetrics if openMetrics is set.
+func (r *Registry) Write(w io.Writer, openMetrics bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.targets))
+	for name := range r.targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	e := &encoder{w: bufio.NewWriter(w)}
+	for _, f := range families {
+		name := f.name
+		if f.kind == "counter" && !openMetrics {
+			name += "_total"
+		}
+		fmt.Fprintf(e.w, "# HELP %s %s\n# TYPE %s %s\n", name, f.help, name, f.kind)
+		for _, n := range names {
+			f.samples(e, r.targets[n])
+		}
+	}
+	if openMetrics {
+		e.w.WriteString("# EOF\n")
+	}
+	return e.w.Flush()
+}
+
+// Gauge is a gauge and its samples, for metrics that aren't kept in a
+// registry, such as the outcome of a single probe.
+type Gauge struct {
+	Name, Help string
+	Samples    []Sample
+}
+
+// Sample is a value of a gauge. Labels holds label names and values in
+// turn.
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// WriteGauges writes gauges in the Prometheus text format, sorted by name.
+func WriteGauges(w io.Writer, gauges []Gauge) error {
+	sorted := make([]Gauge, len(gauges))
+	copy(sorted, gauges)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	e := &encoder{w: bufio.NewWriter(w)}
+	for _, g := range sorted {
+		fmt.Fprintf(e.w, "# HELP %s %s\n# TYPE %s gauge\n", g.Name, g.Help, g.Name)
+		for _, s := range g.Samples {
+			labels := make([]label, 0, len(s.Labels)/2)
+			for i := 0; i+1 < len(s.Labels); i += 2 {
+				labels = append(labels, label{s.Labels[i], s.Labels[i+1]})
+			}
+			e.sample(g.Name, labels, s.Value)
+		}
+	}
+	return e.w.Flush()
+}
+
+type encoder struct {
+	w *bufio.Writer
+}
+
+func (e *encoder) sample(name string, labels []label, v float64) {
+	e.w.WriteString(name)
+	if len(labels) > 0 {
+		e.w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				e.w.WriteByte(',')
+			}
+			e.w.WriteString(l.name)
+			e.w.WriteString(`="`)
+			e.w.WriteString(labelEscaper.Replace(l.value))
+			e.w.WriteByte('"')
+		}
+		e.w.WriteByte('}')
+	}
+	e.w.WriteByte(' ')
+	e.w.WriteString(formatFloat(v))
+	e.w.WriteByte('\n')
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// with returns labels with one more label appended.
+func with(labels []label, name, value string) []label {
+	return append(labels[:len(labels):len(labels)], label{name, value})
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func bool01(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func unix(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/stretchr/testify/assert"
+)
+
+var checked = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func testRegistry() *Registry {
+	r := New()
+	r.Observe("api", "https://api.example.com", map[string]string{"team": "payments", "url": "x", "env-name": "prod"}, Check{
+		Time: checked, Up: true, State: status.Up, StatusCode: 200, Latency: 80 * time.Millisecond, Attempts: 1,
+		Phases:     map[string]time.Duration{"dns": 5 * time.Millisecond, "processing": 70 * time.Millisecond},
+		CertExpiry: checked.AddDate(0, 1, 0),
+	})
+	r.Observe("api", "https://api.example.com", map[string]string{"team": "payments", "url": "x", "env-name": "prod"}, Check{
+		Time: checked.Add(time.Minute), Up: true, State: status.Degraded, StatusCode: 200, Latency: 700 * time.Millisecond, Attempts: 1,
+	})
+	r.Observe("docs", "http://docs.example.com", nil, Check{Time: checked, State: status.Down, Attempts: 4})
+	return r
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, testRegistry().Write(&buf, false))
+	out := buf.String()
+
+	labels := `target="api",url="https://api.example.com",env_name="prod",team="payments",tag_url="x"`
+	for _, line := range []string{
+		"# TYPE healthcheck_up gauge",
+		`healthcheck_up{` + labels + `} 1`,
+		`healthcheck_up{target="docs",url="http://docs.example.com"} 0`,
+		`healthcheck_state{` + labels + `,state="degraded"} 1`,
+		`healthcheck_state{` + labels + `,state="up"} 0`,
+		`healthcheck_status_code{target="docs",url="http://docs.example.com"} 0`,
+		`healthcheck_attempts{target="docs",url="http://docs.example.com"} 4`,
+		`healthcheck_last_check_timestamp_seconds{` + labels + `} 1714564860`,
+		"# TYPE healthcheck_checks_total counter",
+		`healthcheck_checks_total{` + labels + `} 2`,
+		`healthcheck_check_failures_total{target="docs",url="http://docs.example.com"} 1`,
+		"# TYPE healthcheck_response_duration_seconds histogram",
+		`healthcheck_response_duration_seconds_bucket{` + labels + `,le="0.1"} 1`,
+		`healthcheck_response_duration_seconds_bucket{` + labels + `,le="0.5"} 1`,
+		`healthcheck_response_duration_seconds_bucket{` + labels + `,le="1"} 2`,
+		`healthcheck_response_duration_seconds_bucket{` + labels + `,le="+Inf"} 2`,
+		`healthcheck_response_duration_seconds_sum{` + labels + `} 0.78`,
+		`healthcheck_response_duration_seconds_count{` + labels + `} 2`,
+		`healthcheck_response_duration_seconds_count{target="docs",url="http://docs.example.com"} 0`,
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+	assert.NotContains(t, out, "healthcheck_phase_duration_seconds{", "the latest check of api has no phases")
+	assert.NotContains(t, out, `healthcheck_cert_expiry_timestamp_seconds{target="docs"`)
+	assert.NotContains(t, out, "# EOF")
+}
+
+func TestWrite_OpenMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, testRegistry().Write(&buf, true))
+	out := buf.String()
+	assert.Contains(t, out, "# TYPE healthcheck_checks counter\n")
+	assert.Contains(t, out, `healthcheck_checks_total{target="docs",url="http://docs.example.com"} 1`)
+	assert.True(t, strings.HasSuffix(out, "# EOF\n"))
+}
+
+func TestRegistry_Remove(t *testing.T) {
+	r := testRegistry()
+	r.Remove("api")
+	var buf bytes.Buffer
+	assert.NoError(t, r.Write(&buf, false))
+	assert.NotContains(t, buf.String(), `target="api"`)
+	assert.Contains(t, buf.String(), `target="docs"`)
+}
+
+func TestServeHTTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		accept   string
+		expected string
+	}{
+		{"Prometheus", "text/plain;version=0.0.4;q=0.5,*/*;q=0.1", textType},
+		{"OpenMetrics", "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5", openMetricsType},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.Header.Set("Accept", tc.accept)
+			rec := httptest.NewRecorder()
+			testRegistry().ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tc.expected, rec.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestLabelName(t *testing.T) {
+	assert.Equal(t, "team", labelName("team"))
+	assert.Equal(t, "app_kubernetes_io_name", labelName("app.kubernetes.io/name"))
+	assert.Equal(t, "_1st", labelName("1st"))
+	assert.Equal(t, "_", labelName(""))
+}
+
+func TestWriteGauges(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, WriteGauges(&buf, []Gauge{
+		{Name: "probe_success", Help: "Whether the probe succeeded.", Samples: []Sample{{Value: 1}}},
+		{Name: "probe_http_duration_seconds", Help: "Duration of each phase.", Samples: []Sample{
+			{Labels: []string{"phase", "connect"}, Value: 0.25},
+			{Labels: []string{"phase", "tls"}, Value: 0},
+		}},
+	}))
+	assert.Equal(t, `# HELP probe_http_duration_seconds Duration of each phase.
+# TYPE probe_http_duration_seconds gauge
+probe_http_duration_seconds{phase="connect"} 0.25
+probe_http_duration_seconds{phase="tls"} 0
+# HELP probe_success Whether the probe succeeded.
+# TYPE probe_success gauge
+probe_success 1
+`, buf.String())
+}
@@ -0,0 +1,78 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+)
+
+// probeDNS asks the DNS server target, whose port defaults to 53, for the
+// records of c.
+func probeDNS(ctx context.Context, target string, c config.DNSProbe, g *gauges) error {
+	server := target
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		server = net.JoinHostPort(target, "53")
+	}
+	network := c.TransportProtocol
+	if network == "" {
+		network = "udp"
+	}
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+
+	start := time.Now()
+	answers, err := lookup(ctx, r, c)
+	g.set("probe_dns_duration_seconds", "Duration of DNS request by phase", time.Since(start).Seconds(), "phase", "request")
+	g.set("probe_dns_answer_rrs", "Returns number of entries in the answer resource record list", float64(answers))
+	if err != nil {
+		return fmt.Errorf("%s query for %s failed: %w", c.QueryType, c.QueryName, err)
+	}
+	if answers == 0 {
+		return fmt.Errorf("%s query for %s returned no records", c.QueryType, c.QueryName)
+	}
+	return nil
+}
+
+// lookup runs the query of c with r and returns the number of records in
+// the answer.
+func lookup(ctx context.Context, r *net.Resolver, c config.DNSProbe) (int, error) {
+	name := c.QueryName
+	switch c.QueryType {
+	case "", "A":
+		ips, err := r.LookupIP(ctx, "ip4", name)
+		return len(ips), err
+	case "AAAA":
+		ips, err := r.LookupIP(ctx, "ip6", name)
+		return len(ips), err
+	case "CNAME":
+		cname, err := r.LookupCNAME(ctx, name)
+		if err != nil || cname == "" {
+			return 0, err
+		}
+		return 1, nil
+	case "MX":
+		mx, err := r.LookupMX(ctx, name)
+		return len(mx), err
+	case "NS":
+		ns, err := r.LookupNS(ctx, name)
+		return len(ns), err
+	case "PTR":
+		names, err := r.LookupAddr(ctx, name)
+		return len(names), err
+	case "SRV":
+		_, srv, err := r.LookupSRV(ctx, "", "", name)
+		return len(srv), err
+	case "TXT":
+		txt, err := r.LookupTXT(ctx, name)
+		return len(txt), err
+	}
+	return 0, fmt.Errorf("unsupported query type %q", c.QueryType)
+}
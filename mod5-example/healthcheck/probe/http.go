@@ -0,0 +1,144 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+)
+
+// maxBody is how much of a response body an http probe reads.
+const maxBody = 10 << 20
+
+// probeHTTP requests target, a URL that defaults to the http scheme.
+func probeHTTP(ctx context.Context, target string, c config.HTTPProbe, g *gauges) error {
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("invalid target: %w", err)
+	}
+	tlsCfg, err := tlsConfig(c.TLSConfig)
+	if err != nil {
+		return err
+	}
+
+	var pt Timer
+	redirects := 0
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			TLSClientConfig:   tlsCfg,
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			redirects = len(via)
+			if c.NoFollowRedirects {
+				return http.ErrUseLastResponse
+			}
+			if redirects >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+	method := c.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, pt.Trace()), method, u.String(), strings.NewReader(c.Body))
+	if err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	for k, v := range c.Headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	var body []byte
+	if err == nil {
+		body, err = io.ReadAll(io.LimitReader(resp.Body, maxBody))
+		resp.Body.Close()
+	}
+	phases := pt.Done()
+	g.set("probe_dns_lookup_time_seconds", "Returns the time taken for probe dns lookup in seconds", phases.DNS.Seconds())
+	for _, p := range []struct {
+		name string
+		d    float64
+	}{
+		{"resolve", phases.DNS.Seconds()},
+		{"connect", phases.Connect.Seconds()},
+		{"tls", phases.TLS.Seconds()},
+		{"processing", phases.Processing.Seconds()},
+		{"transfer", phases.Transfer.Seconds()},
+	} {
+		g.set("probe_http_duration_seconds", "Duration of http request by phase, for the final request", p.d, "phase", p.name)
+	}
+	g.set("probe_http_redirects", "The number of redirects", float64(redirects))
+	if err != nil {
+		g.set("probe_http_status_code", "Response HTTP status code", 0)
+		return err
+	}
+
+	g.set("probe_http_status_code", "Response HTTP status code", float64(resp.StatusCode))
+	g.set("probe_http_content_length", "Length of http content response", float64(resp.ContentLength))
+	g.set("probe_http_uncompressed_body_length", "Length of uncompressed response body", float64(len(body)))
+	g.set("probe_http_version", "Returns the version of HTTP of the probe response", float64(resp.ProtoMajor)+float64(resp.ProtoMinor)/10)
+	g.set("probe_http_ssl", "Indicates if SSL was used for the final redirect", bool01(resp.TLS != nil))
+	if resp.TLS != nil {
+		g.setTLS(*resp.TLS)
+	}
+
+	if !validStatus(resp.StatusCode, c.ValidStatusCodes) {
+		return fmt.Errorf("invalid status code %d", resp.StatusCode)
+	}
+	if c.FailIfSSL && resp.TLS != nil {
+		return errors.New("final request was over SSL")
+	}
+	if c.FailIfNotSSL && resp.TLS == nil {
+		return errors.New("final request was not over SSL")
+	}
+	failed, err := matchBody(body, c)
+	g.set("probe_failed_due_to_regex", "Indicates if probe failed due to regex", bool01(failed))
+	return err
+}
+
+func validStatus(code int, valid []int) bool {
+	if len(valid) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, v := range valid {
+		if v == code {
+			return true
+		}
+	}
+	return false
+}
+
+// matchBody checks body against the regular expressions of c, which have
+// been validated with the config file.
+func matchBody(body []byte, c config.HTTPProbe) (bool, error) {
+	for _, expr := range c.FailIfBodyMatchesRegexp {
+		if regexp.MustCompile(expr).Match(body) {
+			return true, fmt.Errorf("body matched %q", expr)
+		}
+	}
+	for _, expr := range c.FailIfBodyNotMatchesRegexp {
+		if !regexp.MustCompile(expr).Match(body) {
+			return true, fmt.Errorf("body did not match %q", expr)
+		}
+	}
+	return false, nil
+}
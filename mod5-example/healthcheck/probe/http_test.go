@@ -0,0 +1,107 @@
+package probe
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun_HTTP(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("status: healthy")) })
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) { http.Error(w, "oops", http.StatusInternalServerError) })
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/health", http.StatusFound) })
+	mux.HandleFunc("/host", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(r.Host)) })
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	tlsSrv := httptest.NewTLSServer(mux)
+	defer tlsSrv.Close()
+	insecure := config.TLSConfig{InsecureSkipVerify: true}
+
+	tests := []struct {
+		name     string
+		target   string
+		probe    config.HTTPProbe
+		success  bool
+		expected map[string]float64
+	}{
+		{"Up", srv.URL + "/health", config.HTTPProbe{}, true, map[string]float64{
+			"probe_http_status_code": 200, "probe_http_ssl": 0, "probe_http_redirects": 0, "probe_http_version": 1.1,
+			"probe_http_uncompressed_body_length": 15, "probe_failed_due_to_regex": 0,
+		}},
+		{"Without scheme", strings.TrimPrefix(srv.URL, "http://") + "/health", config.HTTPProbe{}, true, nil},
+		{"Invalid status", srv.URL + "/broken", config.HTTPProbe{}, false, map[string]float64{"probe_http_status_code": 500}},
+		{"Valid status", srv.URL + "/broken", config.HTTPProbe{ValidStatusCodes: []int{500}}, true, nil},
+		{"Redirect", srv.URL + "/old", config.HTTPProbe{}, true, map[string]float64{"probe_http_redirects": 1, "probe_http_status_code": 200}},
+		{"Redirect not followed", srv.URL + "/old", config.HTTPProbe{NoFollowRedirects: true}, false, map[string]float64{"probe_http_status_code": 302}},
+		{"Body matches", srv.URL + "/health", config.HTTPProbe{FailIfBodyMatchesRegexp: []string{"healthy"}}, false, map[string]float64{"probe_failed_due_to_regex": 1}},
+		{"Body doesn't match", srv.URL + "/health", config.HTTPProbe{FailIfBodyNotMatchesRegexp: []string{"status: (ok|healthy)"}}, true, nil},
+		{"Host header", srv.URL + "/host", config.HTTPProbe{Headers: map[string]string{"Host": "api.example.com"}, FailIfBodyNotMatchesRegexp: []string{"^api.example.com$"}}, true, nil},
+		{"Not SSL", srv.URL + "/health", config.HTTPProbe{FailIfNotSSL: true}, false, nil},
+		{"SSL", tlsSrv.URL + "/health", config.HTTPProbe{FailIfNotSSL: true, TLSConfig: insecure}, true, map[string]float64{"probe_http_ssl": 1}},
+		{"Untrusted certificate", tlsSrv.URL + "/health", config.HTTPProbe{}, false, map[string]float64{"probe_http_status_code": 0}},
+		{"Connection refused", "http://127.0.0.1:1", config.HTTPProbe{}, false, map[string]float64{"probe_http_status_code": 0}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := Run(context.Background(), tc.target, config.Module{Prober: "http", Timeout: 5 * time.Second, HTTP: tc.probe})
+			assert.Equal(t, tc.success, r.Success, "err: %v", r.Err)
+			assert.Equal(t, bool01(tc.success), value(t, r, "probe_success"))
+			for name, v := range tc.expected {
+				assert.Equal(t, v, value(t, r, name), name)
+			}
+			value(t, r, "probe_duration_seconds")
+			value(t, r, "probe_http_duration_seconds", "phase", "connect")
+		})
+	}
+}
+
+func TestRun_HTTPTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	r := Run(context.Background(), srv.URL, config.Module{Prober: "http", HTTP: config.HTTPProbe{TLSConfig: config.TLSConfig{InsecureSkipVerify: true}}})
+	assert.True(t, r.Success)
+	assert.Equal(t, float64(srv.Certificate().NotAfter.Unix()), value(t, r, "probe_ssl_earliest_cert_expiry"))
+	assert.Equal(t, 1.0, value(t, r, "probe_tls_version_info", "version", "TLS 1.3"))
+	assert.Greater(t, value(t, r, "probe_http_duration_seconds", "phase", "tls"), 0.0)
+}
+
+func TestRun_HTTPTLSVerified(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	assert.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o644))
+
+	tests := []struct {
+		name       string
+		serverName string
+		expected   bool
+	}{
+		// Without a server name, every connection, including those of
+		// redirects, is verified against the host it is made to.
+		{"Host of the URL", "", true},
+		{"Configured server name", "example.com", true},
+		{"Configured name the certificate lacks", "other.example", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := config.TLSConfig{CAFile: caFile, ServerName: tc.serverName}
+			cfg, err := tlsConfig(c)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.serverName, cfg.ServerName)
+			r := Run(context.Background(), srv.URL, config.Module{Prober: "http", HTTP: config.HTTPProbe{TLSConfig: c}})
+			assert.Equal(t, tc.expected, r.Success, r.Err)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package probe
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Phases is how long each step of a request took. Steps skipped because a
+// connection was reused, or because the target isn't served over TLS, take
+// no time.
+type Phases struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	// Processing is the time from sending the request to the first byte
+	// of the response, and Transfer the time to read the body after it.
+	Processing time.Duration
+	Transfer   time.Duration
+}
+
+// ByName returns the phases keyed by the names used in metrics.
+func (p Phases) ByName() map[string]time.Duration {
+	return map[string]time.Duration{
+		"dns":        p.DNS,
+		"connect":    p.Connect,
+		"tls":        p.TLS,
+		"processing": p.Processing,
+		"transfer":   p.Transfer,
+	}
+}
+
+// Timer times the phases of a request through its client trace.
+type Timer struct {
+	mu                                          sync.Mutex
+	p                                           Phases
+	dnsStart, connStart, tlsStart, wrote, first time.Time
+}
+
+// Trace returns the hooks that time the request. The connection hooks may
+// be called concurrently when dialing several addresses at once.
+func (pt *Timer) Trace() *httptrace.ClientTrace {
+	mark := func(t *time.Time) {
+		pt.mu.Lock()
+		*t = time.Now()
+		pt.mu.Unlock()
+	}
+	since := func(d *time.Duration, start *time.Time) {
+		pt.mu.Lock()
+		if !start.IsZero() {
+			*d = time.Since(*start)
+		}
+		pt.mu.Unlock()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { mark(&pt.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { since(&pt.p.DNS, &pt.dnsStart) },
+		ConnectStart:      func(string, string) { mark(&pt.connStart) },
+		ConnectDone:       func(string, string, error) { since(&pt.p.Connect, &pt.connStart) },
+		TLSHandshakeStart: func() { mark(&pt.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { since(&pt.p.TLS, &pt.tlsStart) },
+		WroteRequest:      func(httptrace.WroteRequestInfo) { mark(&pt.wrote) },
+		GotFirstResponseByte: func() {
+			mark(&pt.first)
+			since(&pt.p.Processing, &pt.wrote)
+		},
+	}
+}
+
+// Done records the end of the body and returns the phases.
+func (pt *Timer) Done() Phases {
+	end := time.Now()
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	if !pt.first.IsZero() {
+		pt.p.Transfer = end.Sub(pt.first)
+	}
+	return pt.p
+}
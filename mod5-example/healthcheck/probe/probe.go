@@ -0,0 +1,112 @@
+// Package probe runs single checks on demand, the way blackbox_exporter
+// does, and describes their outcome with metrics named like its own so
+// existing dashboards and alerts keep working. It also times the phases of
+// the HTTP requests made by regular checks.
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/metrics"
+)
+
+// Result is the outcome of a probe.
+type Result struct {
+	Success  bool
+	Duration time.Duration
+	// Err explains why the probe failed.
+	Err error
+	// Gauges are the metrics describing the probe, including
+	// probe_success and probe_duration_seconds.
+	Gauges []metrics.Gauge
+}
+
+// Run probes target with module m until it succeeds, fails or ctx is done.
+func Run(ctx context.Context, target string, m config.Module) Result {
+	if m.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.Timeout)
+		defer cancel()
+	}
+	g := &gauges{}
+	start := time.Now()
+	var err error
+	switch m.Prober {
+	case "http":
+		err = probeHTTP(ctx, target, m.HTTP, g)
+	case "tcp":
+		err = probeTCP(ctx, target, m.TCP, g)
+	case "dns":
+		err = probeDNS(ctx, target, m.DNS, g)
+	default:
+		err = fmt.Errorf("unknown prober %q", m.Prober)
+	}
+	r := Result{Success: err == nil, Duration: time.Since(start), Err: err}
+	g.set("probe_duration_seconds", "Returns how long the probe took to complete in seconds", r.Duration.Seconds())
+	g.set("probe_success", "Displays whether or not the probe was a success", bool01(r.Success))
+	r.Gauges = g.list
+	return r
+}
+
+// gauges collects the metrics of a probe.
+type gauges struct {
+	list []metrics.Gauge
+}
+
+// set adds a sample with the given labels, as name and value pairs, to the
+// gauge called name.
+func (g *gauges) set(name, help string, v float64, labels ...string) {
+	for i := range g.list {
+		if g.list[i].Name == name {
+			g.list[i].Samples = append(g.list[i].Samples, metrics.Sample{Labels: labels, Value: v})
+			return
+		}
+	}
+	g.list = append(g.list, metrics.Gauge{Name: name, Help: help, Samples: []metrics.Sample{{Labels: labels, Value: v}}})
+}
+
+// setTLS adds the metrics describing a TLS connection.
+func (g *gauges) setTLS(state tls.ConnectionState) {
+	var earliest time.Time
+	for _, cert := range state.PeerCertificates {
+		if earliest.IsZero() || cert.NotAfter.Before(earliest) {
+			earliest = cert.NotAfter
+		}
+	}
+	if !earliest.IsZero() {
+		g.set("probe_ssl_earliest_cert_expiry", "Returns earliest SSL cert expiry in unixtime", float64(earliest.Unix()))
+	}
+	g.set("probe_tls_version_info", "Contains the TLS version used", 1, "version", tls.VersionName(state.Version))
+}
+
+// tlsConfig returns the client TLS settings of c. ServerName is only set
+// when c overrides it, so that connections are verified against the host
+// they are made to, including redirects to other hosts.
+func tlsConfig(c config.TLSConfig) (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify, ServerName: c.ServerName}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read ca_file: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("ca_file holds no certificates")
+		}
+	}
+	return cfg, nil
+}
+
+func bool01(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,140 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// value returns the sample of the gauge called name with the given labels,
+// failing the test if there is none.
+func value(t *testing.T, r Result, name string, labels ...string) float64 {
+	t.Helper()
+	for _, g := range r.Gauges {
+		if g.Name != name {
+			continue
+		}
+		for _, s := range g.Samples {
+			if slices.Equal(s.Labels, labels) {
+				return s.Value
+			}
+		}
+	}
+	t.Errorf("no %s%v sample", name, labels)
+	return 0
+}
+
+func TestRun_TCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+	tlsSrv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsSrv.Close()
+	tlsAddr := tlsSrv.Listener.Addr().String()
+
+	tests := []struct {
+		name    string
+		target  string
+		probe   config.TCPProbe
+		success bool
+	}{
+		{"Open", ln.Addr().String(), config.TCPProbe{}, true},
+		{"Closed", closed.Addr().String(), config.TCPProbe{}, false},
+		{"Without port", "127.0.0.1", config.TCPProbe{}, false},
+		{"TLS", tlsAddr, config.TCPProbe{TLS: true, TLSConfig: config.TLSConfig{InsecureSkipVerify: true}}, true},
+		{"Untrusted TLS", tlsAddr, config.TCPProbe{TLS: true}, false},
+		{"No TLS", ln.Addr().String(), config.TCPProbe{TLS: true}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := Run(context.Background(), tc.target, config.Module{Prober: "tcp", Timeout: time.Second, TCP: tc.probe})
+			assert.Equal(t, tc.success, r.Success, "err: %v", r.Err)
+			assert.Equal(t, bool01(tc.success), value(t, r, "probe_success"))
+		})
+	}
+
+	r := Run(context.Background(), tlsAddr, config.Module{Prober: "tcp", TCP: config.TCPProbe{TLS: true, TLSConfig: config.TLSConfig{InsecureSkipVerify: true}}})
+	assert.Equal(t, float64(tlsSrv.Certificate().NotAfter.Unix()), value(t, r, "probe_ssl_earliest_cert_expiry"))
+	assert.Equal(t, 4.0, value(t, r, "probe_ip_protocol"))
+}
+
+// dnsServer answers A queries for example.test with 192.0.2.1 and every
+// other query with NXDOMAIN.
+func dnsServer(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			query := buf[:n]
+			// The question follows the 12 byte header: a name as
+			// length-prefixed labels, then its type and class.
+			end := 12
+			for end < n && query[end] != 0 {
+				end += int(query[end]) + 1
+			}
+			end += 5
+			if end > n {
+				continue
+			}
+			name, qtype := string(query[12:end-4]), query[end-3]
+			resp := append([]byte{query[0], query[1], 0x81, 0x83, 0, 1, 0, 0, 0, 0, 0, 0}, query[12:end]...)
+			if name == "\x07example\x04test\x00" && qtype == 1 {
+				resp[3], resp[7] = 0x80, 1
+				resp = append(resp, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 192, 0, 2, 1)
+			}
+			conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestRun_DNS(t *testing.T) {
+	server := dnsServer(t)
+	tests := []struct {
+		name    string
+		probe   config.DNSProbe
+		success bool
+		answers float64
+	}{
+		{"Answered", config.DNSProbe{QueryName: "example.test", QueryType: "A"}, true, 1},
+		{"No such name", config.DNSProbe{QueryName: "missing.test", QueryType: "A"}, false, 0},
+		{"No records of the type", config.DNSProbe{QueryName: "example.test", QueryType: "MX"}, false, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := Run(context.Background(), server, config.Module{Prober: "dns", Timeout: 2 * time.Second, DNS: tc.probe})
+			assert.Equal(t, tc.success, r.Success, "err: %v", r.Err)
+			assert.Equal(t, tc.answers, value(t, r, "probe_dns_answer_rrs"))
+		})
+	}
+}
+
+func TestRun_UnknownProber(t *testing.T) {
+	r := Run(context.Background(), "example.com", config.Module{Prober: "icmp"})
+	assert.False(t, r.Success)
+	assert.ErrorContains(t, r.Err, "unknown prober")
+	assert.Equal(t, 0.0, value(t, r, "probe_success"))
+}
@@ -0,0 +1,55 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+)
+
+// probeTCP connects to target, a host and port, and completes a TLS
+// handshake if c asks for one.
+func probeTCP(ctx context.Context, target string, c config.TCPProbe, g *gauges) error {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		return fmt.Errorf("invalid target, expected host:port: %w", err)
+	}
+	start := time.Now()
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	g.set("probe_dns_lookup_time_seconds", "Returns the time taken for probe dns lookup in seconds", time.Since(start).Seconds())
+	if err != nil {
+		return fmt.Errorf("unable to resolve %s: %w", host, err)
+	}
+	ip := ips[0].IP
+	protocol := 6
+	if ip.To4() != nil {
+		protocol = 4
+	}
+	g.set("probe_ip_protocol", "Specifies whether probe ip protocol is IP4 or IP6", float64(protocol))
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), port))
+	if err != nil {
+		return fmt.Errorf("unable to connect: %w", err)
+	}
+	defer conn.Close()
+	if !c.TLS {
+		return nil
+	}
+	cfg, err := tlsConfig(c.TLSConfig)
+	if err != nil {
+		return err
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+	tc := tls.Client(conn, cfg)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("tls handshake failed: %w", err)
+	}
+	g.setTLS(tc.ConnectionState())
+	return nil
+}
@@ -0,0 +1,174 @@
+// Package report summarizes check history for digests and reports.
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+	"github.com/olekukonko/tablewriter"
+)
+
+// Incident is a period during which a target was down.
+type Incident struct {
+	URL   string
+	Start time.Time
+	// End is the time of the first successful check afterwards, or zero if
+	// the target was still down at the end of the history.
+	End    time.Time
+	Reason string
+}
+
+// Duration is how long the incident lasted, up to until if it is ongoing.
+func (i Incident) Duration(until time.Time) time.Duration {
+	if i.End.IsZero() {
+		return until.Sub(i.Start)
+	}
+	return i.End.Sub(i.Start)
+}
+
+// Summary describes a single target over a period.
+type Summary struct {
+	URL    string
+	Checks int
+	Up     int
+	// Maintenance counts the failed checks during maintenance windows,
+	// which are left out of Checks and so out of the uptime.
+	Maintenance int
+	Incidents   []Incident
+	AvgLatency  time.Duration
+	MaxLatency  time.Duration
+}
+
+// Uptime is the percentage of checks that found the target up.
+func (s Summary) Uptime() float64 {
+	if s.Checks == 0 {
+		return 0
+	}
+	return 100 * float64(s.Up) / float64(s.Checks)
+}
+
+// Summarize groups samples by URL. Samples must be in time order; the
+// summaries are sorted by URL.
+func Summarize(samples []store.Result) []Summary {
+	byURL := make(map[string]*Summary)
+	total := make(map[string]time.Duration)
+	timed := make(map[string]int)
+	for _, s := range samples {
+		sum, ok := byURL[s.URL]
+		if !ok {
+			sum = &Summary{URL: s.URL}
+			byURL[s.URL] = sum
+		}
+		if s.Maintenance != "" && !s.Up {
+			sum.Maintenance++
+			continue
+		}
+		sum.Checks++
+		open := len(sum.Incidents) > 0 && sum.Incidents[len(sum.Incidents)-1].End.IsZero()
+		if s.Up {
+			sum.Up++
+			if open {
+				sum.Incidents[len(sum.Incidents)-1].End = s.Time
+			}
+		} else if !open {
+			reason := s.Err
+			if reason == "" && s.StatusCode != 0 {
+				reason = fmt.Sprintf("unexpected status code %d", s.StatusCode)
+			}
+			sum.Incidents = append(sum.Incidents, Incident{URL: s.URL, Start: s.Time, Reason: reason})
+		}
+		if s.Latency > 0 {
+			total[s.URL] += s.Latency
+			timed[s.URL]++
+			sum.MaxLatency = max(sum.MaxLatency, s.Latency)
+		}
+	}
+
+	summaries := make([]Summary, 0, len(byURL))
+	for url, sum := range byURL {
+		if timed[url] > 0 {
+			sum.AvgLatency = total[url] / time.Duration(timed[url])
+		}
+		summaries = append(summaries, *sum)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].URL < summaries[j].URL })
+	return summaries
+}
+
+// SummarizeRollups summarizes the hourly rollups of url. The summary has no
+// incidents, since rollups don't record when each check failed.
+func SummarizeRollups(url string, rollups []store.Rollup) Summary {
+	sum := Summary{URL: url}
+	var total time.Duration
+	var timed int
+	for _, r := range rollups {
+		sum.Checks += r.Checks
+		sum.Up += r.Up
+		sum.Maintenance += r.Maintenance
+		total += r.TotalLatency
+		timed += r.Timed
+		sum.MaxLatency = max(sum.MaxLatency, r.MaxLatency)
+	}
+	if timed > 0 {
+		sum.AvgLatency = total / time.Duration(timed)
+	}
+	return sum
+}
+
+// slowestCount is the number of endpoints listed as slowest in a digest.
+const slowestCount = 5
+
+// WriteDigest writes a plain-text digest of summaries covering from to to:
+// uptime per target, the incidents in the period and the slowest endpoints.
+func WriteDigest(w io.Writer, summaries []Summary, from, to time.Time) {
+	const layout = "01/02/2006 03:04PM"
+	fmt.Fprintf(w, "Health check digest for %s - %s (UTC)\n\n", from.Format(layout), to.Format(layout))
+	if len(summaries) == 0 {
+		fmt.Fprintln(w, "No checks were recorded in this period.")
+		return
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"URL", "Uptime", "Checks", "Maintenance", "Incidents", "Avg Latency", "Max Latency"})
+	for _, s := range summaries {
+		table.Append([]string{
+			s.URL,
+			fmt.Sprintf("%.2f%%", s.Uptime()),
+			fmt.Sprint(s.Checks),
+			fmt.Sprint(s.Maintenance),
+			fmt.Sprint(len(s.Incidents)),
+			s.AvgLatency.Round(time.Millisecond).String(),
+			s.MaxLatency.Round(time.Millisecond).String(),
+		})
+	}
+	table.Render()
+
+	fmt.Fprintln(w, "\nIncidents")
+	var incidents []Incident
+	for _, s := range summaries {
+		incidents = append(incidents, s.Incidents...)
+	}
+	sort.Slice(incidents, func(i, j int) bool { return incidents[i].Start.Before(incidents[j].Start) })
+	if len(incidents) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	for _, i := range incidents {
+		end := "ongoing"
+		if !i.End.IsZero() {
+			end = i.End.Format(layout)
+		}
+		fmt.Fprintf(w, "  %s  %s - %s (%s): %s\n", i.URL, i.Start.Format(layout), end, i.Duration(to).Round(time.Second), i.Reason)
+	}
+
+	fmt.Fprintln(w, "\nSlowest endpoints")
+	slowest := make([]Summary, len(summaries))
+	copy(slowest, summaries)
+	sort.SliceStable(slowest, func(i, j int) bool { return slowest[i].AvgLatency > slowest[j].AvgLatency })
+	for i, s := range slowest[:min(slowestCount, len(slowest))] {
+		fmt.Fprintf(w, "  %d. %s  avg %s, max %s\n", i+1, s.URL, s.AvgLatency.Round(time.Millisecond), s.MaxLatency.Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,71 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarize(t *testing.T) {
+	start := time.Date(2024, 4, 19, 8, 0, 0, 0, time.UTC)
+	at := func(m int) time.Time { return start.Add(time.Duration(m) * time.Minute) }
+	samples := []store.Result{
+		{Time: at(0), URL: "http://api", Up: true, StatusCode: 200, Latency: 100 * time.Millisecond},
+		{Time: at(1), URL: "http://api", StatusCode: 503},
+		{Time: at(1), URL: "http://docs", Up: true, StatusCode: 200, Latency: 900 * time.Millisecond},
+		{Time: at(2), URL: "http://api", Err: "connection refused"},
+		{Time: at(3), URL: "http://api", Up: true, StatusCode: 200, Latency: 300 * time.Millisecond},
+		{Time: at(4), URL: "http://api", Err: "timeout"},
+		{Time: at(5), URL: "http://docs", Err: "connection refused", Maintenance: "deploy"},
+	}
+
+	summaries := Summarize(samples)
+	assert.Len(t, summaries, 2)
+
+	api := summaries[0]
+	assert.Equal(t, "http://api", api.URL)
+	assert.Equal(t, 5, api.Checks)
+	assert.InDelta(t, 40.0, api.Uptime(), 0.001)
+	assert.Equal(t, 200*time.Millisecond, api.AvgLatency)
+	assert.Equal(t, 0, api.Maintenance)
+	assert.Equal(t, 300*time.Millisecond, api.MaxLatency)
+	assert.Equal(t, []Incident{
+		{URL: "http://api", Start: at(1), End: at(3), Reason: "unexpected status code 503"},
+		{URL: "http://api", Start: at(4), Reason: "timeout"},
+	}, api.Incidents)
+
+	docs := summaries[1]
+	assert.Equal(t, 1, docs.Checks)
+	assert.Equal(t, 1, docs.Maintenance)
+	assert.InDelta(t, 100.0, docs.Uptime(), 0.001, "failures during maintenance are not downtime")
+	assert.Empty(t, docs.Incidents)
+
+	var b strings.Builder
+	WriteDigest(&b, summaries, start, at(10))
+	digest := b.String()
+	assert.Contains(t, digest, "40.00%")
+	assert.Contains(t, digest, "04/19/2024 08:01AM - 04/19/2024 08:03AM (2m0s): unexpected status code 503")
+	assert.Contains(t, digest, "ongoing (6m0s): timeout")
+	assert.Contains(t, digest, "1. http://docs  avg 900ms")
+}
+
+func TestWriteDigest_Empty(t *testing.T) {
+	var b strings.Builder
+	WriteDigest(&b, nil, time.Now().Add(-time.Hour), time.Now())
+	assert.Contains(t, b.String(), "No checks were recorded")
+}
+
+func TestSummarizeRollups(t *testing.T) {
+	sum := SummarizeRollups("http://api", []store.Rollup{
+		{Checks: 10, Up: 9, TotalLatency: time.Second, Timed: 10, MaxLatency: 300 * time.Millisecond},
+		{Checks: 10, Up: 10, Maintenance: 2, TotalLatency: 3 * time.Second, Timed: 10, MaxLatency: 500 * time.Millisecond},
+	})
+	assert.Equal(t, 20, sum.Checks)
+	assert.Equal(t, 2, sum.Maintenance)
+	assert.InDelta(t, 95.0, sum.Uptime(), 0.001)
+	assert.Equal(t, 200*time.Millisecond, sum.AvgLatency)
+	assert.Equal(t, 500*time.Millisecond, sum.MaxLatency)
+}
@@ -0,0 +1,49 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, "expected %q to be rejected", expr)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2024, 4, 19, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2024, 4, 19, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 4, 19, 10, 15, 0, 0, time.UTC)},
+		{"0 9-17 * * *", time.Date(2024, 4, 19, 11, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2024, 4, 20, 2, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		// April 19th 2024 is a Friday; the next Monday is the 22nd.
+		{"0 8 * * 1", time.Date(2024, 4, 22, 8, 0, 0, 0, time.UTC)},
+		// With both day fields restricted either one matching is enough.
+		{"0 8 1 * 1", time.Date(2024, 4, 22, 8, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			c, err := ParseCron(tc.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, c.Next(from))
+		})
+	}
+}
+
+func TestCronNext_HalfHourZone(t *testing.T) {
+	ist := time.FixedZone("IST", 5*60*60+30*60)
+	c, err := ParseCron("0 12 * * *")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 4, 19, 12, 0, 0, 0, ist), c.Next(time.Date(2024, 4, 19, 10, 7, 30, 0, ist)))
+}
@@ -0,0 +1,371 @@
+// Package scheduler runs recurring jobs, each on its own interval or cron
+// schedule, without ever overlapping two runs of the same job.
+//
+// Jobs wait in a heap ordered by their next run, from which a single
+// dispatcher hands the due ones to a bounded pool of workers, so thousands
+// of jobs cost neither a goroutine nor a timer each.
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultWorkers is the number of jobs that may run at once unless Workers
+// says otherwise.
+const DefaultWorkers = 64
+
+// Job is a recurring unit of work, such as checking one target.
+type Job struct {
+	Name string
+	// Interval is the time between the start of consecutive runs.
+	Interval time.Duration
+	// Cron, when set, is used instead of Interval.
+	Cron *Cron
+	// NextInterval, when set, is called after every run and returns the
+	// interval until the next one, overriding Interval. Cron jobs ignore
+	// it.
+	NextInterval func() time.Duration
+	// Background marks housekeeping jobs, such as flushes and reports,
+	// which RunNow leaves on their schedule.
+	Background bool
+	Run        func(ctx context.Context)
+}
+
+// Scheduler runs jobs on their schedules until its context is cancelled.
+type Scheduler struct {
+	// Jitter is the upper bound of a random delay added to each job's first
+	// run, so targets sharing an interval don't all fire at once.
+	Jitter time.Duration
+	// Workers is the number of jobs that may run at once. Due jobs wait for
+	// a free worker, so a zero or negative value uses DefaultWorkers. It
+	// must be set before Run.
+	Workers int
+
+	mu      sync.Mutex
+	entries []*entry
+	queue   queue
+	// wake tells the dispatcher that the head of the queue may have changed.
+	wake chan struct{}
+	// ctx is the context Run was called with, or nil before that.
+	ctx    context.Context
+	paused atomic.Bool
+}
+
+// entry is a job along with its place in the schedule.
+type entry struct {
+	job  Job
+	next time.Time
+	// index is the position of the entry in the queue, or -1 while it is
+	// running or has no next run.
+	index int
+	// forced is set by RunNow, so the next run happens right away even if
+	// the scheduler is paused.
+	forced  bool
+	removed bool
+	// cancel cancels the run in flight, if any.
+	cancel context.CancelFunc
+}
+
+// New returns a scheduler that adds up to jitter of random delay to the
+// first run of each job.
+func New(jitter time.Duration) *Scheduler {
+	return &Scheduler{Jitter: jitter, wake: make(chan struct{}, 1)}
+}
+
+// Add registers a job. Jobs added while Run is running start right away,
+// after a random delay of up to Jitter.
+func (s *Scheduler) Add(j Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := &entry{job: j, index: -1}
+	s.entries = append(s.entries, e)
+	if s.ctx != nil {
+		var offset time.Duration
+		if s.Jitter > 0 {
+			offset = time.Duration(rand.Int63n(int64(s.Jitter)))
+		}
+		s.first(e, time.Now(), offset)
+	}
+}
+
+// Remove stops and unregisters the job with the given name. A run in
+// flight is cancelled through its context. It reports whether the job was
+// found.
+func (s *Scheduler) Remove(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, e := range s.entries {
+		if e.job.Name != name {
+			continue
+		}
+		e.removed = true
+		if e.index >= 0 {
+			heap.Remove(&s.queue, e.index)
+		}
+		if e.cancel != nil {
+			e.cancel()
+		}
+		s.entries = append(s.entries[:i], s.entries[i+1:]...)
+		return true
+	}
+	return false
+}
+
+// Pause stops scheduled runs until Resume is called. Runs requested with
+// RunNow still happen.
+func (s *Scheduler) Pause() {
+	s.paused.Store(true)
+}
+
+// Resume undoes Pause.
+func (s *Scheduler) Resume() {
+	s.paused.Store(false)
+}
+
+// RunNow asks every job but the background ones to run immediately. Jobs
+// that are already running run again as soon as they are done.
+func (s *Scheduler) RunNow() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, e := range s.entries {
+		if !e.job.Background {
+			s.force(e, now)
+		}
+	}
+	s.notify()
+}
+
+// Trigger asks the job with the given name to run immediately, even while
+// paused, or as soon as its run in flight is done. It reports whether the
+// job was found.
+func (s *Scheduler) Trigger(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if e.job.Name == name {
+			s.force(e, time.Now())
+			s.notify()
+			return true
+		}
+	}
+	return false
+}
+
+// force moves the next run of e to now. s.mu must be held.
+func (s *Scheduler) force(e *entry, now time.Time) {
+	e.forced = true
+	if e.index >= 0 {
+		e.next = now
+		heap.Fix(&s.queue, e.index)
+	}
+}
+
+// Run starts every job and blocks until ctx is done and all runs in flight
+// have returned.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	now := time.Now()
+	for i, offset := range s.offsets() {
+		s.first(s.entries[i], now, offset)
+	}
+	workers := s.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	s.mu.Unlock()
+
+	work := make(chan *entry)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range work {
+				s.run(e)
+			}
+		}()
+	}
+	s.dispatch(ctx, work)
+	close(work)
+	wg.Wait()
+}
+
+// offsets staggers the first run of the jobs that share an interval evenly
+// across that interval, plus a random jitter. Cron jobs start on their
+// schedule.
+func (s *Scheduler) offsets() []time.Duration {
+	byInterval := make(map[time.Duration][]int)
+	for i, e := range s.entries {
+		if e.job.Cron == nil {
+			byInterval[e.job.Interval] = append(byInterval[e.job.Interval], i)
+		}
+	}
+	offsets := make([]time.Duration, len(s.entries))
+	for interval, idx := range byInterval {
+		for n, i := range idx {
+			offsets[i] = interval * time.Duration(n) / time.Duration(len(idx))
+		}
+	}
+	if s.Jitter > 0 {
+		for i := range offsets {
+			offsets[i] += time.Duration(rand.Int63n(int64(s.Jitter)))
+		}
+	}
+	return offsets
+}
+
+// first queues the first run of e, offset after now. s.mu must be held.
+func (s *Scheduler) first(e *entry, now time.Time, offset time.Duration) {
+	next := now.Add(offset)
+	if e.job.Cron != nil {
+		next = e.job.Cron.Next(now)
+	}
+	s.schedule(e, next)
+}
+
+// schedule queues e to run at next. Cron schedules that are over, which
+// have a zero next run, are left out of the queue. s.mu must be held.
+func (s *Scheduler) schedule(e *entry, next time.Time) {
+	if e.forced {
+		next = time.Now()
+	}
+	if next.IsZero() {
+		return
+	}
+	e.next = next
+	heap.Push(&s.queue, e)
+	s.notify()
+}
+
+// notify wakes the dispatcher up without blocking.
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch sends every job that is due to the workers, in the order they
+// are due, until ctx is done. Jobs leave the queue while they run, so a
+// run that takes longer than the interval delays the next one rather than
+// overlapping it.
+func (s *Scheduler) dispatch(ctx context.Context, work chan<- *entry) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		var due *entry
+		wait := time.Hour
+		s.mu.Lock()
+		if len(s.queue) > 0 {
+			if d := time.Until(s.queue[0].next); d > 0 {
+				wait = d
+			} else {
+				due = heap.Pop(&s.queue).(*entry)
+			}
+		}
+		s.mu.Unlock()
+
+		if due != nil {
+			select {
+			case work <- due:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// run runs e once and queues its next run, unless it was removed meanwhile.
+func (s *Scheduler) run(e *entry) {
+	s.mu.Lock()
+	if e.removed {
+		s.mu.Unlock()
+		return
+	}
+	forced := e.forced
+	e.forced = false
+	ctx, cancel := context.WithCancel(s.ctx)
+	e.cancel = cancel
+	s.mu.Unlock()
+
+	start := time.Now()
+	if forced || !s.paused.Load() {
+		e.job.Run(ctx)
+	}
+	cancel()
+	next := e.nextRun(start)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.cancel = nil
+	if !e.removed && s.ctx.Err() == nil {
+		s.schedule(e, next)
+	}
+}
+
+// nextRun returns when the job should run next after a run that started at
+// start, or the zero time if its cron schedule is over.
+func (e *entry) nextRun(start time.Time) time.Time {
+	if e.job.Cron != nil {
+		return e.job.Cron.Next(time.Now())
+	}
+	interval := e.job.Interval
+	if e.job.NextInterval != nil {
+		interval = e.job.NextInterval()
+	}
+	next := start.Add(interval)
+	// Skip any runs that were missed while this one was in flight.
+	for interval > 0 && !next.After(time.Now()) {
+		next = next.Add(interval)
+	}
+	return next
+}
+
+// queue is a min-heap of entries by their next run.
+type queue []*entry
+
+func (q queue) Len() int           { return len(q) }
+func (q queue) Less(i, j int) bool { return q[i].next.Before(q[j].next) }
+
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *queue) Push(x any) {
+	e := x.(*entry)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *queue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	e.index = -1
+	*q = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,238 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOffsets_Staggered(t *testing.T) {
+	s := New(0)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		s.Add(Job{Name: name, Interval: 4 * time.Second})
+	}
+	s.Add(Job{Name: "slow", Interval: time.Minute})
+
+	assert.Equal(t, []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second, 0}, s.offsets())
+}
+
+func TestRun_NoOverlap(t *testing.T) {
+	var running, overlaps, runs atomic.Int32
+	s := New(0)
+	s.Add(Job{
+		Name:     "slow",
+		Interval: 5 * time.Millisecond,
+		Run: func(ctx context.Context) {
+			if running.Add(1) > 1 {
+				overlaps.Add(1)
+			}
+			runs.Add(1)
+			time.Sleep(20 * time.Millisecond)
+			running.Add(-1)
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	go func() {
+		for ctx.Err() == nil {
+			s.RunNow()
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	s.Run(ctx)
+
+	assert.Zero(t, overlaps.Load())
+	assert.Greater(t, runs.Load(), int32(1))
+}
+
+func TestRun_PerJobIntervals(t *testing.T) {
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	count := func(name string) func(context.Context) {
+		return func(context.Context) {
+			mu.Lock()
+			counts[name]++
+			mu.Unlock()
+		}
+	}
+
+	s := New(0)
+	s.Add(Job{Name: "fast", Interval: 10 * time.Millisecond, Run: count("fast")})
+	s.Add(Job{Name: "slow", Interval: time.Hour, Run: count("slow")})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 105*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+
+	assert.GreaterOrEqual(t, counts["fast"], 5)
+	assert.Equal(t, 1, counts["slow"])
+}
+
+func TestRun_NextInterval(t *testing.T) {
+	var runs atomic.Int32
+	s := New(0)
+	s.Add(Job{
+		Name:     "adaptive",
+		Interval: time.Hour,
+		// Speed up after the first run, then back off again after the third.
+		NextInterval: func() time.Duration {
+			if n := runs.Load(); n >= 1 && n < 3 {
+				return 5 * time.Millisecond
+			}
+			return time.Hour
+		},
+		Run: func(context.Context) { runs.Add(1) },
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+
+	assert.EqualValues(t, 3, runs.Load())
+}
+
+func TestPause(t *testing.T) {
+	var runs atomic.Int32
+	s := New(0)
+	s.Add(Job{Name: "a", Interval: 5 * time.Millisecond, Run: func(context.Context) { runs.Add(1) }})
+	s.Pause()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+
+	assert.Zero(t, runs.Load())
+}
+
+func TestRun_AddRemove(t *testing.T) {
+	var early, late atomic.Int32
+	s := New(0)
+	s.Add(Job{Name: "early", Interval: 5 * time.Millisecond, Run: func(context.Context) { early.Add(1) }})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	s.Add(Job{Name: "late", Interval: 5 * time.Millisecond, Run: func(context.Context) { late.Add(1) }})
+	assert.True(t, s.Remove("early"))
+	assert.False(t, s.Remove("missing"))
+	time.Sleep(5 * time.Millisecond)
+	stopped := early.Load()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Greater(t, stopped, int32(0))
+	assert.Equal(t, stopped, early.Load(), "removed jobs don't run anymore")
+	assert.Greater(t, late.Load(), int32(0), "jobs added while running start")
+}
+
+func TestRun_Workers(t *testing.T) {
+	var running, most atomic.Int32
+	s := New(0)
+	s.Workers = 2
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		s.Add(Job{
+			Name:     name,
+			Interval: 5 * time.Millisecond,
+			Run: func(context.Context) {
+				n := running.Add(1)
+				for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+				}
+				time.Sleep(5 * time.Millisecond)
+				running.Add(-1)
+			},
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+
+	assert.EqualValues(t, 2, most.Load(), "no more jobs than workers should run at once")
+}
+
+// BenchmarkRun measures how fast the scheduler dispatches 10k jobs that are
+// always due, which bounds the checks per second of a monitor.
+func BenchmarkRun(b *testing.B) {
+	const jobs = 10_000
+	var runs atomic.Int64
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := int64(b.N)
+	s := New(0)
+	for i := range jobs {
+		s.Add(Job{
+			Name:     fmt.Sprint(i),
+			Interval: time.Millisecond,
+			Run: func(context.Context) {
+				if runs.Add(1) == n {
+					cancel()
+				}
+			},
+		})
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	start := time.Now()
+	s.Run(ctx)
+	b.ReportMetric(float64(runs.Load())/time.Since(start).Seconds(), "runs/s")
+}
+
+func TestTrigger(t *testing.T) {
+	var a, b atomic.Int32
+	s := New(0)
+	s.Add(Job{Name: "a", Interval: time.Hour, Run: func(context.Context) { a.Add(1) }})
+	s.Add(Job{Name: "b", Interval: time.Hour, Run: func(context.Context) { b.Add(1) }})
+	s.Pause()
+	assert.False(t, s.Trigger("missing"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	assert.True(t, s.Trigger("a"))
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	<-done
+
+	assert.EqualValues(t, 1, a.Load(), "triggered jobs run even while paused")
+	assert.Zero(t, b.Load())
+}
+
+func TestRunNow_SkipsBackground(t *testing.T) {
+	var target, housekeeping atomic.Int32
+	s := New(0)
+	s.Add(Job{Name: "target", Interval: time.Hour, Run: func(context.Context) { target.Add(1) }})
+	s.Add(Job{Name: "digest", Interval: time.Hour, Background: true, Run: func(context.Context) { housekeeping.Add(1) }})
+	s.Pause()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	s.RunNow()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	<-done
+
+	assert.EqualValues(t, 1, target.Load())
+	assert.Zero(t, housekeeping.Load(), "background jobs keep their schedule")
+}
@@ -0,0 +1,75 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// influxLine returns r in InfluxDB line protocol, with the target and its
+// URL as tags and up, status_code and latency_seconds as fields. The latter
+// two are left out if no response arrived.
+func influxLine(measurement string, tags map[string]string, r Result) string {
+	tags["target"] = r.Target
+	tags["url"] = r.URL
+	keys := make([]string, 0, len(tags))
+	for k, v := range tags {
+		if k != "" && v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(measurementEscaper.Replace(measurement))
+	for _, k := range keys {
+		b.WriteString("," + tagEscaper.Replace(k) + "=" + tagEscaper.Replace(tags[k]))
+	}
+	if r.Up {
+		b.WriteString(" up=1i")
+	} else {
+		b.WriteString(" up=0i")
+	}
+	if r.StatusCode != 0 {
+		fmt.Fprintf(&b, ",status_code=%di,latency_seconds=%s", r.StatusCode, strconv.FormatFloat(r.Latency.Seconds(), 'f', -1, 64))
+	}
+	fmt.Fprintf(&b, " %d", r.Time.UnixNano())
+	return b.String()
+}
+
+// sendInflux posts batch to the InfluxDB write URL in a single request.
+func (s *Sink) sendInflux(ctx context.Context, batch []Result) error {
+	var body bytes.Buffer
+	for _, r := range batch {
+		body.WriteString(influxLine(s.c.Prefix, s.tags(r), r))
+		body.WriteByte('\n')
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.c.URL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	for k, v := range s.c.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
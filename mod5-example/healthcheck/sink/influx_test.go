@@ -0,0 +1,59 @@
+package sink
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfluxLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     map[string]string
+		result   Result
+		expected string
+	}{
+		{"Up", map[string]string{}, Result{Time: checkedAt, Target: "api", URL: "http://api.test", Up: true, StatusCode: 200, Latency: 20 * time.Millisecond},
+			"hc,target=api,url=http://api.test up=1i,status_code=200i,latency_seconds=0.02 1714564800000000000"},
+		{"No response", map[string]string{}, Result{Time: checkedAt, Target: "api", URL: "http://api.test"},
+			"hc,target=api,url=http://api.test up=0i 1714564800000000000"},
+		{"Escaped tags", map[string]string{"team": "core, payments", "empty": ""}, Result{Time: checkedAt, Target: "a=b", URL: "http://api.test"},
+			`hc,target=a\=b,team=core\,\ payments,url=http://api.test up=0i 1714564800000000000`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, influxLine("hc", tc.tags, tc.result))
+		})
+	}
+}
+
+func TestSink_Influx(t *testing.T) {
+	var body, auth string
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body, auth = string(data), r.Header.Get("Authorization")
+		w.WriteHeader(status)
+		w.Write([]byte("bucket not found"))
+	}))
+	defer srv.Close()
+	s := New(config.Sink{Name: "influx", Type: "influxdb", URL: srv.URL, Prefix: "healthcheck", Headers: map[string]string{"Authorization": "Token secret"},
+		Tags: map[string]string{"env": "prod"}, FlushInterval: time.Second, BufferSize: 100})
+
+	s.Push(Result{Time: checkedAt, Target: "api", URL: "http://api.test", Tags: map[string]string{"env": "staging"}, Up: true, StatusCode: 200, Latency: time.Second})
+	s.Push(Result{Time: checkedAt, Target: "web", URL: "http://web.test"})
+	assert.NoError(t, s.Flush(context.Background()))
+	assert.Equal(t, "Token secret", auth)
+	assert.Equal(t, "healthcheck,env=staging,target=api,url=http://api.test up=1i,status_code=200i,latency_seconds=1 1714564800000000000\n"+
+		"healthcheck,env=prod,target=web,url=http://web.test up=0i 1714564800000000000\n", body)
+
+	status = http.StatusNotFound
+	s.Push(Result{Time: checkedAt, Target: "api"})
+	assert.EqualError(t, s.Flush(context.Background()), "dropped 1 results: unexpected status 404 Not Found: bucket not found")
+}
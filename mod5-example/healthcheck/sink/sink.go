@@ -0,0 +1,104 @@
+// Package sink pushes check results to StatsD and InfluxDB collectors. Results
+// are buffered and sent in batches, and dropped rather than held up when a
+// collector is slow or unavailable.
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+)
+
+// Result is a check result to push.
+type Result struct {
+	Time   time.Time
+	Target string
+	URL    string
+	Tags   map[string]string
+	Up     bool
+	// StatusCode is zero if no response arrived, in which case Latency
+	// isn't pushed either.
+	StatusCode int
+	Latency    time.Duration
+}
+
+// Sink buffers results for a collector until they are flushed.
+type Sink struct {
+	c    config.Sink
+	send func(ctx context.Context, batch []Result) error
+
+	mu      sync.Mutex
+	pending []Result
+	dropped int
+}
+
+// New returns a sink for the collector c, whose defaults have been applied
+// by loading the config file.
+func New(c config.Sink) *Sink {
+	s := &Sink{c: c}
+	switch c.Type {
+	case "influxdb":
+		s.send = s.sendInflux
+	default:
+		s.send = s.sendStatsD
+	}
+	return s
+}
+
+// Name returns the name of the sink in the config file.
+func (s *Sink) Name() string {
+	return s.c.Name
+}
+
+// Push buffers r for the next flush. It never blocks, and reports false if
+// r was dropped because the buffer is full.
+func (s *Sink) Push(r Result) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) >= s.c.BufferSize {
+		s.dropped++
+		return false
+	}
+	s.pending = append(s.pending, r)
+	return true
+}
+
+// Flush sends the buffered results, giving up after the flush interval. The
+// results are dropped if they can't be sent, so a collector that is down
+// doesn't hold up the ones pushed after it comes back. The error also
+// counts the results dropped by Push since the last flush.
+func (s *Sink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	batch, dropped := s.pending, s.dropped
+	s.pending, s.dropped = nil, 0
+	s.mu.Unlock()
+
+	var errs []error
+	if dropped > 0 {
+		errs = append(errs, fmt.Errorf("dropped %d results while the buffer was full", dropped))
+	}
+	if len(batch) > 0 {
+		ctx, cancel := context.WithTimeout(ctx, s.c.FlushInterval)
+		defer cancel()
+		if err := s.send(ctx, batch); err != nil {
+			errs = append(errs, fmt.Errorf("dropped %d results: %w", len(batch), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// tags returns the tags of r, which take precedence over those of the sink.
+func (s *Sink) tags(r Result) map[string]string {
+	tags := make(map[string]string, len(s.c.Tags)+len(r.Tags))
+	for k, v := range s.c.Tags {
+		tags[k] = v
+	}
+	for k, v := range r.Tags {
+		tags[k] = v
+	}
+	return tags
+}
@@ -0,0 +1,61 @@
+package sink
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/stretchr/testify/assert"
+)
+
+var checkedAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func TestSink_Push(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	s := New(config.Sink{Name: "influx", Type: "influxdb", URL: srv.URL, Prefix: "healthcheck", FlushInterval: time.Second, BufferSize: 2})
+
+	assert.True(t, s.Push(Result{Target: "a"}))
+	assert.True(t, s.Push(Result{Target: "b"}))
+	assert.False(t, s.Push(Result{Target: "c"}), "the buffer is full")
+	assert.EqualError(t, s.Flush(context.Background()), "dropped 1 results while the buffer was full")
+	assert.True(t, s.Push(Result{Target: "c"}), "flushing empties the buffer")
+	assert.NoError(t, s.Flush(context.Background()))
+	assert.NoError(t, s.Flush(context.Background()), "there is nothing to flush")
+}
+
+func TestSink_Unavailable(t *testing.T) {
+	blocked := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-blocked }))
+	defer hanging.Close()
+	defer close(blocked)
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+
+	tests := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{"Connection refused", "http://" + closed.Addr().String(), "dropped 1 results"},
+		{"Hanging", hanging.URL, "context deadline exceeded"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := New(config.Sink{Name: "influx", Type: "influxdb", URL: tc.url, Prefix: "healthcheck", FlushInterval: 100 * time.Millisecond, BufferSize: 10})
+			s.Push(Result{Target: "api", Time: checkedAt})
+			start := time.Now()
+			err := s.Flush(context.Background())
+			assert.ErrorContains(t, err, tc.expected)
+			assert.Less(t, time.Since(start), time.Second, "flushing gives up after the flush interval")
+			assert.NoError(t, s.Flush(context.Background()), "failed results aren't retried")
+		})
+	}
+}
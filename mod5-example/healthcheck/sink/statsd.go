@@ -0,0 +1,85 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxPacket keeps StatsD datagrams within the MTU of most networks.
+const maxPacket = 1432
+
+var (
+	nameReplacer = strings.NewReplacer(".", "_", ":", "_", "|", "_", "@", "_", "#", "_", "/", "_", " ", "_")
+	tagReplacer  = strings.NewReplacer(",", "_", "|", "_", "#", "_", " ", "_")
+)
+
+// statsdLines returns the metrics of r: an up gauge and, if a response
+// arrived, a latency timer and a counter named after the status code.
+// Tags are appended in the DogStatsD format.
+func statsdLines(prefix string, tags map[string]string, r Result) []string {
+	name := prefix + "." + nameReplacer.Replace(r.Target) + "."
+	suffix := ""
+	if len(tags) > 0 {
+		pairs := make([]string, 0, len(tags))
+		for k, v := range tags {
+			pairs = append(pairs, strings.ReplaceAll(tagReplacer.Replace(k), ":", "_")+":"+tagReplacer.Replace(v))
+		}
+		sort.Strings(pairs)
+		suffix = "|#" + strings.Join(pairs, ",")
+	}
+	up := "0"
+	if r.Up {
+		up = "1"
+	}
+	lines := []string{name + "up:" + up + "|g" + suffix}
+	if r.StatusCode != 0 {
+		ms := strconv.FormatFloat(float64(r.Latency.Microseconds())/1000, 'f', -1, 64)
+		lines = append(lines,
+			name+"latency:"+ms+"|ms"+suffix,
+			fmt.Sprintf("%sstatus.%d:1|c%s", name, r.StatusCode, suffix),
+		)
+	}
+	return lines
+}
+
+// sendStatsD writes batch to the StatsD server over UDP, with as many lines
+// per datagram as fit.
+func (s *Sink) sendStatsD(ctx context.Context, batch []Result) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", s.c.Address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetWriteDeadline(deadline)
+	}
+
+	var packet []byte
+	write := func() error {
+		if len(packet) == 0 {
+			return nil
+		}
+		_, err := conn.Write(packet)
+		packet = packet[:0]
+		return err
+	}
+	for _, r := range batch {
+		for _, line := range statsdLines(s.c.Prefix, s.tags(r), r) {
+			if len(packet) > 0 && len(packet)+1+len(line) > maxPacket {
+				if err := write(); err != nil {
+					return err
+				}
+			}
+			if len(packet) > 0 {
+				packet = append(packet, '\n')
+			}
+			packet = append(packet, line...)
+		}
+	}
+	return write()
+}
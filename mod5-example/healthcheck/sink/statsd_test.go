@@ -0,0 +1,68 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsdLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     map[string]string
+		result   Result
+		expected []string
+	}{
+		{"Up", nil, Result{Target: "api", Up: true, StatusCode: 200, Latency: 20500 * time.Microsecond}, []string{
+			"hc.api.up:1|g",
+			"hc.api.latency:20.5|ms",
+			"hc.api.status.200:1|c",
+		}},
+		{"No response", nil, Result{Target: "api"}, []string{"hc.api.up:0|g"}},
+		{"Tags", map[string]string{"team": "payments", "env": "prod,eu"}, Result{Target: "https://api.example.com", StatusCode: 503, Latency: time.Millisecond}, []string{
+			"hc.https___api_example_com.up:0|g|#env:prod_eu,team:payments",
+			"hc.https___api_example_com.latency:1|ms|#env:prod_eu,team:payments",
+			"hc.https___api_example_com.status.503:1|c|#env:prod_eu,team:payments",
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, statsdLines("hc", tc.tags, tc.result))
+		})
+	}
+}
+
+func TestSink_StatsD(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	s := New(config.Sink{Name: "statsd", Type: "statsd", Address: conn.LocalAddr().String(), Prefix: "healthcheck",
+		Tags: map[string]string{"env": "prod"}, FlushInterval: time.Second, BufferSize: 100})
+	for i := 0; i < 30; i++ {
+		s.Push(Result{Target: fmt.Sprintf("target-%d", i), Up: true, StatusCode: 200, Latency: time.Millisecond})
+	}
+	assert.NoError(t, s.Flush(context.Background()))
+
+	var lines []string
+	buf := make([]byte, 2*maxPacket)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for len(lines) < 90 {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.LessOrEqual(t, n, maxPacket)
+		lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+	}
+	assert.Len(t, lines, 90)
+	assert.Equal(t, "healthcheck.target-0.up:1|g|#env:prod", lines[0])
+	assert.Equal(t, "healthcheck.target-29.status.200:1|c|#env:prod", lines[89])
+}
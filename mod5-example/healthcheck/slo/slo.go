@@ -0,0 +1,124 @@
+// Package slo computes how well targets meet their service level
+// objectives from the results in the store.
+package slo
+
+import (
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+)
+
+// Report is the attainment of an SLO by one target.
+type Report struct {
+	SLO       string        `json:"slo"`
+	Target    string        `json:"target"`
+	URL       string        `json:"url"`
+	Objective float64       `json:"objective"`
+	Latency   time.Duration `json:"latency,omitempty"`
+	Window    time.Duration `json:"window"`
+	// Checks and Good count the checks in the window, leaving out
+	// failures during maintenance.
+	Checks int `json:"checks"`
+	Good   int `json:"good"`
+	// Attainment is the percentage of good checks in the window.
+	Attainment float64 `json:"attainment"`
+	// Budget is the percentage of the error budget left. It is negative
+	// once the objective has been missed.
+	Budget float64 `json:"error_budget_remaining"`
+	// BurnRate is how many times faster than allowed the error budget was
+	// spent over the last BurnWindow.
+	BurnRate      float64       `json:"burn_rate"`
+	BurnWindow    time.Duration `json:"burn_window"`
+	BurnThreshold float64       `json:"burn_rate_threshold"`
+}
+
+// Burning reports whether the burn rate has reached the alert threshold.
+func (r Report) Burning() bool {
+	return r.BurnThreshold > 0 && r.BurnRate >= r.BurnThreshold
+}
+
+// Status summarizes the report: "no data", "breached" once the objective
+// has been missed, "burning" while the budget is spent too fast and "ok"
+// otherwise.
+func (r Report) Status() string {
+	switch {
+	case r.Checks == 0:
+		return "no data"
+	case r.Attainment < r.Objective:
+		return "breached"
+	case r.Burning():
+		return "burning"
+	default:
+		return "ok"
+	}
+}
+
+// Evaluate computes the report of def for target t at now.
+func Evaluate(st *store.Store, def config.SLO, t config.Target, now time.Time) (Report, error) {
+	r := Report{
+		SLO:           def.Name,
+		Target:        t.Name,
+		URL:           t.URL,
+		Objective:     def.Objective,
+		Latency:       def.Latency,
+		Window:        def.Window,
+		BurnWindow:    def.BurnWindow,
+		BurnThreshold: def.BurnRate,
+		Attainment:    100,
+		Budget:        100,
+	}
+	allowed := 1 - def.Objective/100
+
+	var err error
+	r.Checks, r.Good, err = count(st, t.URL, def.Latency, now.Add(-def.Window), now)
+	if err != nil {
+		return r, err
+	}
+	if r.Checks > 0 {
+		bad := float64(r.Checks-r.Good) / float64(r.Checks)
+		r.Attainment = 100 * float64(r.Good) / float64(r.Checks)
+		r.Budget = 100 * (1 - bad/allowed)
+	}
+
+	checks, good, err := count(st, t.URL, def.Latency, now.Add(-def.BurnWindow), now)
+	if err != nil {
+		return r, err
+	}
+	if checks > 0 {
+		r.BurnRate = float64(checks-good) / float64(checks) / allowed
+	}
+	return r, nil
+}
+
+// count returns the number of checks of url between from and to and how
+// many of them were good. Periods reaching back further than raw results are
+// kept are counted from the hourly rollups.
+func count(st *store.Store, url string, latency time.Duration, from, to time.Time) (checks, good int, err error) {
+	if keep := st.Retention().Raw; keep > 0 && from.Before(to.Add(-keep)) {
+		rollups, err := st.Rollups(url, from, to)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, r := range rollups {
+			checks += r.Checks
+			good += r.UpWithin(latency)
+		}
+		return checks, good, nil
+	}
+
+	results, err := st.Query(store.Query{URLs: []string{url}, From: from, To: to})
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, r := range results {
+		if r.Maintenance != "" && !r.Up {
+			continue
+		}
+		checks++
+		if r.Up && (latency <= 0 || r.Latency <= latency) {
+			good++
+		}
+	}
+	return checks, good, nil
+}
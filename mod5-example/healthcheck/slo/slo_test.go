@@ -0,0 +1,90 @@
+package slo
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/config"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+	"github.com/stretchr/testify/assert"
+)
+
+var api = config.Target{Name: "api", URL: "http://api"}
+
+// writeResults records a check of api every minute for the last n minutes,
+// failing the ones for which failed returns true.
+func writeResults(t *testing.T, st *store.Store, now time.Time, n int, failed func(i int) bool) {
+	t.Helper()
+	var results []store.Result
+	for i := 0; i < n; i++ {
+		r := store.Result{Time: now.Add(-time.Duration(n-i) * time.Minute), URL: api.URL, Up: true, Latency: 100 * time.Millisecond}
+		if failed(i) {
+			r.Up, r.Latency, r.Err = false, 0, "timeout"
+		}
+		results = append(results, r)
+	}
+	assert.NoError(t, st.Write(results...))
+}
+
+func openStore(t *testing.T, retention store.Retention) *store.Store {
+	t.Helper()
+	st, err := store.Open(filepath.Join(t.TempDir(), "healthcheck.db"), retention)
+	assert.NoError(t, err)
+	return st
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Now()
+	st := openStore(t, store.DefaultRetention)
+	// One failure in 1000 checks, 3 hours before now.
+	writeResults(t, st, now, 1000, func(i int) bool { return i == 820 })
+
+	def := config.SLO{Name: "availability", Objective: 99.9, Window: 24 * time.Hour, BurnWindow: time.Hour, BurnRate: 14.4}
+	r, err := Evaluate(st, def, api, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, r.Checks)
+	assert.Equal(t, 999, r.Good)
+	assert.InDelta(t, 99.9, r.Attainment, 0.0001)
+	assert.InDelta(t, 0, r.Budget, 0.0001, "one failure in 1000 spends the whole 99.9% budget")
+	assert.InDelta(t, 0, r.BurnRate, 0.0001)
+	assert.Equal(t, "ok", r.Status())
+
+	def.Latency = 50 * time.Millisecond
+	r, err = Evaluate(st, def, api, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, r.Good, "every check was slower than the latency objective")
+	assert.Equal(t, "breached", r.Status())
+}
+
+func TestEvaluate_Burning(t *testing.T) {
+	now := time.Now()
+	st := openStore(t, store.DefaultRetention)
+	// Every tenth check in the last hour failed: 100x the 99.9% budget.
+	writeResults(t, st, now, 60, func(i int) bool { return i%10 == 0 })
+
+	def := config.SLO{Name: "availability", Objective: 99.9, Window: 30 * 24 * time.Hour, BurnWindow: time.Hour, BurnRate: 14.4}
+	r, err := Evaluate(st, def, api, now)
+	assert.NoError(t, err)
+	assert.InDelta(t, 100, r.BurnRate, 0.0001)
+	assert.True(t, r.Burning())
+}
+
+func TestEvaluate_Rollups(t *testing.T) {
+	now := time.Now()
+	st := openStore(t, store.Retention{Raw: time.Hour, Rollups: 365 * 24 * time.Hour})
+	writeResults(t, st, now, 180, func(i int) bool { return i < 18 })
+
+	def := config.SLO{Name: "latency", Objective: 90, Latency: 100 * time.Millisecond, Window: 24 * time.Hour, BurnWindow: time.Hour}
+	r, err := Evaluate(st, def, api, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 180, r.Checks)
+	assert.Equal(t, 162, r.Good, "rollups count checks in latency buckets within the objective")
+}
+
+func TestEvaluate_NoData(t *testing.T) {
+	r, err := Evaluate(openStore(t, store.DefaultRetention), config.SLO{Objective: 99, Window: time.Hour, BurnWindow: time.Hour}, api, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, "no data", r.Status())
+	assert.Equal(t, 100.0, r.Budget)
+}
@@ -0,0 +1,165 @@
+// Package status tracks the state of each monitored target and reports when
+// it changes.
+package status
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the health of a target as derived from its check results.
+type State string
+
+const (
+	Unknown  State = "unknown"
+	Up       State = "up"
+	Down     State = "down"
+	Degraded State = "degraded"
+	// Flapping means the target changed state too often to be trusted;
+	// its changes are not reported until it settles down.
+	Flapping State = "flapping"
+	// Maintenance means the target failed during a maintenance window, so
+	// the failure is expected and doesn't count as downtime.
+	Maintenance State = "maintenance"
+	// Unreachable means the target failed while a target it depends on
+	// was down, so the failure is put down to that one and not alerted on.
+	Unreachable State = "unreachable"
+	// Burning means a service level objective is using up its error
+	// budget too fast. It applies to SLOs rather than targets.
+	Burning State = "burning"
+)
+
+// Transition records a target moving from one state to another.
+type Transition struct {
+	Target string
+	From   State
+	To     State
+	Time   time.Time
+	// Reason explains the new state, such as the error that caused a Down.
+	Reason string
+}
+
+// Options control how quickly a target's state follows its check results.
+type Options struct {
+	// DownAfter is the number of consecutive failed checks before a
+	// target is considered Down.
+	DownAfter int
+	// UpAfter is the number of consecutive successful checks before a Down
+	// target is considered Up again.
+	UpAfter int
+	// FlapWindow and FlapThreshold mark a target as Flapping when its
+	// state changed at least FlapThreshold times within FlapWindow. A zero
+	// value disables flap detection.
+	FlapWindow    time.Duration
+	FlapThreshold int
+}
+
+type target struct {
+	// state is the settled state, ignoring flapping.
+	state State
+	// pending is the state the recent checks point at and streak how many
+	// consecutive checks did so.
+	pending State
+	streak  int
+	// changes holds the times state changed, within the flap window.
+	changes  []time.Time
+	flapping bool
+}
+
+// Tracker remembers the current state of every target.
+type Tracker struct {
+	opts    Options
+	mu      sync.Mutex
+	targets map[string]*target
+}
+
+// NewTracker returns a tracker with every target in the Unknown state.
+func NewTracker(opts Options) *Tracker {
+	opts.DownAfter = max(opts.DownAfter, 1)
+	opts.UpAfter = max(opts.UpAfter, 1)
+	return &Tracker{opts: opts, targets: make(map[string]*target)}
+}
+
+func (t *target) reported() State {
+	if t.flapping {
+		return Flapping
+	}
+	return t.state
+}
+
+// State returns the current state of name.
+func (t *Tracker) State(name string) State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if tg, ok := t.targets[name]; ok {
+		return tg.reported()
+	}
+	return Unknown
+}
+
+// Observe records the state seen by the latest check of name. It returns
+// the resulting transition and true if the reported state changed.
+//
+// A change only takes effect once enough consecutive checks agree, as set
+// by the Options. While a target is flapping its changes are tracked but
+// not reported.
+func (t *Tracker) Observe(name string, s State, at time.Time, reason string) (Transition, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tg, ok := t.targets[name]
+	if !ok {
+		tg = &target{state: Unknown}
+		t.targets[name] = tg
+	}
+	before := tg.reported()
+
+	if s == tg.state {
+		tg.pending, tg.streak = "", 0
+	} else {
+		if s == tg.pending {
+			tg.streak++
+		} else {
+			tg.pending, tg.streak = s, 1
+		}
+		if tg.streak >= t.required(tg.state, s) {
+			if tg.state != Unknown {
+				tg.changes = append(tg.changes, at)
+			}
+			tg.state, tg.pending, tg.streak = s, "", 0
+		}
+	}
+	t.updateFlapping(tg, at)
+
+	after := tg.reported()
+	if after == before {
+		return Transition{}, false
+	}
+	if after != s {
+		reason = ""
+	}
+	return Transition{Target: name, From: before, To: after, Time: at, Reason: reason}, true
+}
+
+// required is the number of consecutive checks needed to move from one
+// state to another.
+func (t *Tracker) required(from, to State) int {
+	switch {
+	case to == Down:
+		return t.opts.DownAfter
+	case from == Down:
+		return t.opts.UpAfter
+	default:
+		return 1
+	}
+}
+
+func (t *Tracker) updateFlapping(tg *target, at time.Time) {
+	if t.opts.FlapWindow <= 0 || t.opts.FlapThreshold <= 0 {
+		return
+	}
+	cutoff := at.Add(-t.opts.FlapWindow)
+	for len(tg.changes) > 0 && tg.changes[0].Before(cutoff) {
+		tg.changes = tg.changes[1:]
+	}
+	tg.flapping = len(tg.changes) >= t.opts.FlapThreshold
+}
@@ -0,0 +1,82 @@
+package status
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// observeAll feeds states to a tracker a minute apart and returns the
+// reported state after each one.
+func observeAll(tr *Tracker, states ...State) []State {
+	start := time.Date(2024, 4, 19, 10, 0, 0, 0, time.UTC)
+	var reported []State
+	for i, s := range states {
+		tr.Observe("api", s, start.Add(time.Duration(i)*time.Minute), "")
+		reported = append(reported, tr.State("api"))
+	}
+	return reported
+}
+
+func TestObserve_Transitions(t *testing.T) {
+	tr := NewTracker(Options{})
+	at := time.Now()
+
+	_, changed := tr.Observe("api", Up, at, "")
+	assert.True(t, changed)
+	_, changed = tr.Observe("api", Up, at, "")
+	assert.False(t, changed)
+
+	transition, changed := tr.Observe("api", Down, at, "connection refused")
+	assert.True(t, changed)
+	assert.Equal(t, Transition{Target: "api", From: Up, To: Down, Time: at, Reason: "connection refused"}, transition)
+}
+
+func TestObserve_Hysteresis(t *testing.T) {
+	tr := NewTracker(Options{DownAfter: 3, UpAfter: 2})
+	reported := observeAll(tr, Up, Down, Down, Up, Down, Down, Down, Up, Down, Up, Up)
+	assert.Equal(t, []State{Up, Up, Up, Up, Up, Up, Down, Down, Down, Down, Up}, reported)
+}
+
+func TestObserve_DegradedNeedsNoStreak(t *testing.T) {
+	tr := NewTracker(Options{DownAfter: 3, UpAfter: 2})
+	reported := observeAll(tr, Up, Degraded, Up)
+	assert.Equal(t, []State{Up, Degraded, Up}, reported)
+}
+
+func TestObserve_Flapping(t *testing.T) {
+	tr := NewTracker(Options{FlapWindow: 5 * time.Minute, FlapThreshold: 3})
+	start := time.Date(2024, 4, 19, 10, 0, 0, 0, time.UTC)
+	var transitions []Transition
+	observe := func(minute int, s State) {
+		if tr, ok := tr.Observe("api", s, start.Add(time.Duration(minute)*time.Minute), ""); ok {
+			transitions = append(transitions, tr)
+		}
+	}
+
+	observe(0, Up)
+	observe(1, Down)
+	observe(2, Up)
+	observe(3, Down) // third change within five minutes
+	assert.Equal(t, Flapping, tr.State("api"))
+
+	// Changes while flapping are not reported.
+	observe(4, Up)
+	observe(5, Down)
+	assert.Len(t, transitions, 4)
+
+	// Once the changes age out of the window the settled state is reported.
+	observe(12, Down)
+	assert.Equal(t, Down, tr.State("api"))
+	assert.Equal(t, []State{Unknown, Up, Down, Up}, froms(transitions[:4]))
+	assert.Equal(t, Transition{Target: "api", From: Flapping, To: Down, Time: start.Add(12 * time.Minute)}, transitions[4])
+}
+
+func froms(ts []Transition) []State {
+	var out []State
+	for _, t := range ts {
+		out = append(out, t.From)
+	}
+	return out
+}
@@ -0,0 +1,166 @@
+package statuspage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Progress statuses of a public incident, as posted with its updates.
+const (
+	Investigating = "investigating"
+	Identified    = "identified"
+	Monitoring    = "monitoring"
+	Resolved      = "resolved"
+)
+
+// ValidStatus reports whether s is one of the progress statuses.
+func ValidStatus(s string) bool {
+	switch s {
+	case Investigating, Identified, Monitoring, Resolved:
+		return true
+	}
+	return false
+}
+
+// Update is a note posted on an incident.
+type Update struct {
+	Time    time.Time `json:"time"`
+	Status  string    `json:"status"`
+	Message string    `json:"message"`
+}
+
+// Incident is a problem explained to the public on the status page. Unlike
+// alert incidents, which monitor opens on its own, these are written by
+// people and resolved when they say so.
+type Incident struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// Components names the affected targets.
+	Components []string `json:"components,omitempty"`
+	// Updates are the notes posted so far, oldest first.
+	Updates []Update `json:"updates"`
+}
+
+// Opened is when the first note was posted.
+func (i Incident) Opened() time.Time {
+	if len(i.Updates) == 0 {
+		return time.Time{}
+	}
+	return i.Updates[0].Time
+}
+
+// Status is the status of the latest note.
+func (i Incident) Status() string {
+	if len(i.Updates) == 0 {
+		return Investigating
+	}
+	return i.Updates[len(i.Updates)-1].Status
+}
+
+// Resolved reports whether the latest note resolved the incident.
+func (i Incident) Resolved() bool {
+	return i.Status() == Resolved
+}
+
+// Notes keeps public incidents in a JSON file.
+type Notes struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewNotes returns the incidents kept in the file at path.
+func NewNotes(path string) *Notes {
+	return &Notes{path: path}
+}
+
+// List returns all incidents in the order they were opened.
+func (n *Notes) List() ([]Incident, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.load()
+}
+
+// Add posts u on the incident with the given ID. If there is none, open is
+// stored as a new incident under that ID, or a fresh one when id is empty;
+// it needs a title. An update without a status keeps the incident's
+// current one.
+func (n *Notes) Add(id string, open Incident, u Update) (Incident, error) {
+	if u.Status != "" && !ValidStatus(u.Status) {
+		return Incident{}, fmt.Errorf("unknown status %q, valid statuses are: %s, %s, %s, %s", u.Status, Investigating, Identified, Monitoring, Resolved)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	incidents, err := n.load()
+	if err != nil {
+		return Incident{}, err
+	}
+	i := -1
+	for j := range incidents {
+		if id != "" && incidents[j].ID == id {
+			i = j
+			break
+		}
+	}
+	if i < 0 {
+		if open.Title == "" {
+			if id != "" {
+				return Incident{}, fmt.Errorf("no incident with id %s", id)
+			}
+			return Incident{}, errors.New("a new incident needs a title")
+		}
+		open.ID, open.Updates = id, nil
+		if open.ID == "" {
+			open.ID = newID()
+		}
+		incidents = append(incidents, open)
+		i = len(incidents) - 1
+	}
+	inc := &incidents[i]
+	if u.Status == "" {
+		u.Status = inc.Status()
+	}
+	inc.Updates = append(inc.Updates, u)
+	if err := n.save(incidents); err != nil {
+		return Incident{}, err
+	}
+	return *inc, nil
+}
+
+func (n *Notes) load() ([]Incident, error) {
+	data, err := os.ReadFile(n.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read notes file: %w", err)
+	}
+	var incidents []Incident
+	if err := json.Unmarshal(data, &incidents); err != nil {
+		return nil, fmt.Errorf("unable to parse notes file %s: %w", n.path, err)
+	}
+	return incidents, nil
+}
+
+func (n *Notes) save(incidents []Incident) error {
+	data, err := json.MarshalIndent(incidents, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := n.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("unable to write notes file: %w", err)
+	}
+	return os.Rename(tmp, n.path)
+}
+
+func newID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
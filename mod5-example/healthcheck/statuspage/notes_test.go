@@ -0,0 +1,46 @@
+package statuspage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotes_Add(t *testing.T) {
+	notes := NewNotes(filepath.Join(t.TempDir(), "notes.json"))
+	opened := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	_, err := notes.Add("", Incident{}, Update{Time: opened, Message: "Looking into it"})
+	assert.ErrorContains(t, err, "needs a title")
+
+	inc, err := notes.Add("", Incident{Title: "API errors", Components: []string{"api"}}, Update{Time: opened, Status: Investigating, Message: "Looking into it"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, inc.ID)
+	assert.Equal(t, opened, inc.Opened())
+
+	inc, err = notes.Add(inc.ID, Incident{}, Update{Time: opened.Add(time.Hour), Message: "Still looking"})
+	assert.NoError(t, err)
+	assert.Equal(t, Investigating, inc.Status(), "an update without a status keeps the current one")
+	assert.False(t, inc.Resolved())
+
+	inc, err = notes.Add(inc.ID, Incident{}, Update{Time: opened.Add(2 * time.Hour), Status: Resolved, Message: "Fixed"})
+	assert.NoError(t, err)
+	assert.True(t, inc.Resolved())
+	assert.Len(t, inc.Updates, 3)
+
+	_, err = notes.Add("missing", Incident{}, Update{Message: "?"})
+	assert.ErrorContains(t, err, "no incident with id missing")
+	_, err = notes.Add(inc.ID, Incident{}, Update{Status: "fixed"})
+	assert.ErrorContains(t, err, "unknown status")
+
+	alert, err := notes.Add("3f2a9c1b", Incident{Title: "Outage of web"}, Update{Time: opened, Status: Identified, Message: "Disk full"})
+	assert.NoError(t, err)
+	assert.Equal(t, "3f2a9c1b", alert.ID, "incidents opened for an alert keep its ID")
+
+	list, err := notes.List()
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, "API errors", list[0].Title)
+}
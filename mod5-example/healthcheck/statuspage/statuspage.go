@@ -0,0 +1,338 @@
+// Package statuspage generates a static public status page from the
+// recorded check results: the current state of each component, daily
+// uptime bars and the incident notes posted by the people running the
+// service. The page is plain HTML and CSS, so it can be published on any
+// static host.
+package statuspage
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// Days is the number of days the uptime bars cover.
+const Days = 90
+
+// Uptime levels of a day, used to color its bar.
+const (
+	NoData = "none"
+	Good   = "good"
+	Minor  = "minor"
+	Major  = "major"
+)
+
+// Day is the uptime of a component on one UTC day.
+type Day struct {
+	Date   time.Time
+	Checks int
+	Up     int
+	// Incidents are the titles of the incidents open on the day.
+	Incidents []string
+}
+
+// Uptime is the percentage of checks that found the component up.
+func (d Day) Uptime() float64 {
+	if d.Checks == 0 {
+		return 0
+	}
+	return 100 * float64(d.Up) / float64(d.Checks)
+}
+
+// Level grades the day: Good from 99.9% uptime, Minor from 99% and Major
+// below that.
+func (d Day) Level() string {
+	switch u := d.Uptime(); {
+	case d.Checks == 0:
+		return NoData
+	case u >= 99.9:
+		return Good
+	case u >= 99:
+		return Minor
+	default:
+		return Major
+	}
+}
+
+// Component is a target as shown on the page.
+type Component struct {
+	Name  string
+	State status.State
+	// Days holds the last Days days, oldest first.
+	Days []Day
+}
+
+// NewComponent returns the component called name in state, with its days
+// up to the one holding end summed from its hourly rollups.
+func NewComponent(name string, state status.State, rollups []store.Rollup, end time.Time) Component {
+	c := Component{Name: name, State: state, Days: make([]Day, Days)}
+	last := day(end)
+	for i := range c.Days {
+		c.Days[i].Date = last.AddDate(0, 0, i-Days+1)
+	}
+	for _, r := range rollups {
+		i := Days - 1 - int(last.Sub(day(r.Hour)).Hours()/24)
+		if i < 0 || i >= Days {
+			continue
+		}
+		c.Days[i].Checks += r.Checks
+		c.Days[i].Up += r.Up
+	}
+	return c
+}
+
+func day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Uptime is the percentage of checks over all days that found the
+// component up.
+func (c Component) Uptime() float64 {
+	var checks, up int
+	for _, d := range c.Days {
+		checks += d.Checks
+		up += d.Up
+	}
+	if checks == 0 {
+		return 0
+	}
+	return 100 * float64(up) / float64(checks)
+}
+
+// Group is a set of components shown together.
+type Group struct {
+	Name       string
+	Components []Component
+}
+
+// State is the worst state of the group's components.
+func (g Group) State() status.State {
+	states := make([]status.State, len(g.Components))
+	for i, c := range g.Components {
+		states[i] = c.State
+	}
+	return worst(states)
+}
+
+// severity orders states from unknown, through up, to down.
+var severity = map[status.State]int{
+	status.Unknown:     0,
+	status.Up:          1,
+	status.Maintenance: 2,
+	status.Degraded:    3,
+	status.Flapping:    3,
+	status.Down:        4,
+	status.Unreachable: 4,
+}
+
+func worst(states []status.State) status.State {
+	w := status.Unknown
+	for _, s := range states {
+		if severity[s] > severity[w] {
+			w = s
+		}
+	}
+	return w
+}
+
+// Page is everything shown on the status page.
+type Page struct {
+	Title     string
+	Generated time.Time
+	Groups    []Group
+	// Active are the unresolved incidents and Past those resolved in the
+	// last Days days, both latest first.
+	Active []Incident
+	Past   []Incident
+}
+
+// NewPage returns the page of groups and incidents as of now. Each day of
+// a component lists the incidents affecting it that were open that day.
+func NewPage(title string, groups []Group, incidents []Incident, now time.Time) Page {
+	p := Page{Title: title, Generated: now, Groups: groups}
+	cutoff := day(now).AddDate(0, 0, -Days+1)
+	for _, inc := range incidents {
+		if !inc.Resolved() {
+			p.Active = append(p.Active, inc)
+		} else if !inc.Updates[len(inc.Updates)-1].Time.Before(cutoff) {
+			p.Past = append(p.Past, inc)
+		}
+	}
+	for _, list := range [][]Incident{p.Active, p.Past} {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Opened().After(list[j].Opened()) })
+	}
+
+	for _, g := range p.Groups {
+		for _, c := range g.Components {
+			for i := range c.Days {
+				d := &c.Days[i]
+				for _, inc := range append(p.Active, p.Past...) {
+					if affects(inc, c.Name) && openOn(inc, d.Date, now) {
+						d.Incidents = append(d.Incidents, inc.Title)
+					}
+				}
+			}
+		}
+	}
+	return p
+}
+
+func affects(inc Incident, component string) bool {
+	for _, c := range inc.Components {
+		if c == component {
+			return true
+		}
+	}
+	return false
+}
+
+// openOn reports whether inc was open at some point on the day starting at
+// date.
+func openOn(inc Incident, date, now time.Time) bool {
+	end := now
+	if inc.Resolved() {
+		end = inc.Updates[len(inc.Updates)-1].Time
+	}
+	return inc.Opened().Before(date.AddDate(0, 0, 1)) && !end.Before(date)
+}
+
+// State is the worst state of all components.
+func (p Page) State() status.State {
+	var states []status.State
+	for _, g := range p.Groups {
+		states = append(states, g.State())
+	}
+	return worst(states)
+}
+
+// Summary describes the state of all components in a sentence.
+func (p Page) Summary() string {
+	switch p.State() {
+	case status.Up:
+		return "All systems operational"
+	case status.Maintenance:
+		return "Scheduled maintenance in progress"
+	case status.Degraded, status.Flapping:
+		return "Degraded performance"
+	case status.Down, status.Unreachable:
+		return "Partial outage"
+	default:
+		return "Status unknown"
+	}
+}
+
+var funcs = template.FuncMap{
+	"percent": func(f float64) string { return fmt.Sprintf("%.2f%%", f) },
+	"date":    func(t time.Time) string { return t.UTC().Format("Jan 2, 2006") },
+	"time":    func(t time.Time) string { return t.UTC().Format("Jan 2, 2006 15:04 UTC") },
+	"label": func(s status.State) string {
+		switch s {
+		case status.Up:
+			return "Operational"
+		case status.Maintenance:
+			return "Under maintenance"
+		case status.Degraded, status.Flapping:
+			return "Degraded performance"
+		case status.Down, status.Unreachable:
+			return "Outage"
+		default:
+			return "Unknown"
+		}
+	},
+	"join": strings.Join,
+	"title": func(s string) string {
+		if s == "" {
+			return s
+		}
+		return strings.ToUpper(s[:1]) + s[1:]
+	},
+}
+
+// Write renders p into the directory out. The page is the index.html
+// template of theme, a directory that may also hold partial templates
+// (other .html files) and assets, which are copied as they are. An empty
+// theme uses the built-in one.
+func Write(out string, p Page, theme string) error {
+	var files fs.FS
+	if theme == "" {
+		files, _ = fs.Sub(templates, "templates")
+	} else {
+		files = os.DirFS(theme)
+	}
+	tmpl := template.New("").Funcs(funcs)
+	var assets []string
+	err := fs.WalkDir(files, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if path.Ext(name) != ".html" {
+			assets = append(assets, name)
+			return nil
+		}
+		data, err := fs.ReadFile(files, name)
+		if err != nil {
+			return err
+		}
+		_, err = tmpl.New(name).Parse(string(data))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to load status page templates: %w", err)
+	}
+	if tmpl.Lookup("index.html") == nil {
+		return fmt.Errorf("status page templates in %s have no index.html", theme)
+	}
+
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		return fmt.Errorf("unable to create %s: %w", out, err)
+	}
+	for _, name := range assets {
+		if err := copyFile(files, name, filepath.Join(out, filepath.FromSlash(name))); err != nil {
+			return err
+		}
+	}
+	f, err := os.Create(filepath.Join(out, "index.html"))
+	if err != nil {
+		return fmt.Errorf("unable to write status page: %w", err)
+	}
+	defer f.Close()
+	if err := tmpl.ExecuteTemplate(f, "index.html", p); err != nil {
+		return fmt.Errorf("unable to render status page: %w", err)
+	}
+	return f.Close()
+}
+
+func copyFile(files fs.FS, name, dst string) error {
+	src, err := files.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("unable to write %s: %w", dst, err)
+	}
+	if _, err := io.Copy(f, src); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to write %s: %w", dst, err)
+	}
+	return f.Close()
+}
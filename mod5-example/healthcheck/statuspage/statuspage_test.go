@@ -0,0 +1,117 @@
+package statuspage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/store"
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2024, 5, 10, 15, 30, 0, 0, time.UTC)
+
+func TestNewComponent(t *testing.T) {
+	today := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	rollups := []store.Rollup{
+		{Hour: today.AddDate(0, 0, -Days), Checks: 60, Up: 0},
+		{Hour: today.AddDate(0, 0, -1).Add(3 * time.Hour), Checks: 60, Up: 60},
+		{Hour: today.AddDate(0, 0, -1).Add(4 * time.Hour), Checks: 60, Up: 59},
+		{Hour: today.Add(14 * time.Hour), Checks: 60, Up: 50},
+	}
+	c := NewComponent("api", status.Up, rollups, now)
+	assert.Len(t, c.Days, Days)
+	assert.Equal(t, today.AddDate(0, 0, -Days+1), c.Days[0].Date)
+	assert.Equal(t, NoData, c.Days[0].Level(), "rollups older than the bars are left out")
+	yesterday := c.Days[Days-2]
+	assert.Equal(t, 120, yesterday.Checks)
+	assert.Equal(t, Minor, yesterday.Level())
+	assert.Equal(t, Major, c.Days[Days-1].Level())
+	assert.InDelta(t, 100*169.0/180, c.Uptime(), 0.0001)
+}
+
+func TestDay_Level(t *testing.T) {
+	tests := []struct {
+		name     string
+		day      Day
+		expected string
+	}{
+		{"No checks", Day{}, NoData},
+		{"All up", Day{Checks: 1000, Up: 1000}, Good},
+		{"Good", Day{Checks: 1000, Up: 999}, Good},
+		{"Minor", Day{Checks: 1000, Up: 990}, Minor},
+		{"Major", Day{Checks: 1000, Up: 989}, Major},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.day.Level())
+		})
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	groups := []Group{
+		{Name: "API", Components: []Component{NewComponent("api", status.Up, nil, now), NewComponent("auth", status.Degraded, nil, now)}},
+		{Name: "Web", Components: []Component{NewComponent("web", status.Unknown, nil, now)}},
+	}
+	incidents := []Incident{
+		{ID: "old", Title: "Old outage", Components: []string{"api"}, Updates: []Update{
+			{Time: now.AddDate(0, 0, -100), Status: Investigating}, {Time: now.AddDate(0, 0, -100), Status: Resolved},
+		}},
+		{ID: "fixed", Title: "Slow logins", Components: []string{"auth"}, Updates: []Update{
+			{Time: now.AddDate(0, 0, -3), Status: Investigating}, {Time: now.AddDate(0, 0, -2), Status: Resolved},
+		}},
+		{ID: "open", Title: "API errors", Components: []string{"api"}, Updates: []Update{
+			{Time: now.Add(-time.Hour), Status: Identified},
+		}},
+	}
+	p := NewPage("Acme", groups, incidents, now)
+
+	assert.Equal(t, status.Degraded, p.Groups[0].State())
+	assert.Equal(t, status.Unknown, p.Groups[1].State())
+	assert.Equal(t, status.Degraded, p.State())
+	assert.Equal(t, "Degraded performance", p.Summary())
+	if assert.Len(t, p.Active, 1) && assert.Len(t, p.Past, 1) {
+		assert.Equal(t, "open", p.Active[0].ID)
+		assert.Equal(t, "fixed", p.Past[0].ID)
+	}
+	auth := p.Groups[0].Components[1]
+	assert.Equal(t, []string{"Slow logins"}, auth.Days[Days-4].Incidents)
+	assert.Equal(t, []string{"Slow logins"}, auth.Days[Days-3].Incidents)
+	assert.Empty(t, auth.Days[Days-2].Incidents)
+	assert.Equal(t, []string{"API errors"}, p.Groups[0].Components[0].Days[Days-1].Incidents)
+}
+
+func TestWrite(t *testing.T) {
+	p := NewPage("Acme <status>", []Group{{Name: "API", Components: []Component{NewComponent("api", status.Down, nil, now)}}}, []Incident{
+		{ID: "open", Title: "API errors", Components: []string{"api"}, Updates: []Update{{Time: now, Status: Investigating, Message: "Looking into it"}}},
+	}, now)
+
+	out := t.TempDir()
+	assert.NoError(t, Write(out, p, ""))
+	page, err := os.ReadFile(filepath.Join(out, "index.html"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(page), "Acme &lt;status&gt;")
+	assert.Contains(t, string(page), "Partial outage")
+	assert.Contains(t, string(page), "Looking into it")
+	assert.Contains(t, string(page), "May 10, 2024 15:30 UTC")
+	assert.NotContains(t, string(page), "<script")
+	assert.FileExists(t, filepath.Join(out, "style.css"))
+
+	theme := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(theme, "index.html"), []byte(`{{template "banner.html" .}}`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(theme, "banner.html"), []byte(`<h1>{{.Title}}: {{.Summary}}</h1>`), 0o644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(theme, "img"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(theme, "img", "logo.svg"), []byte("<svg/>"), 0o644))
+	out = t.TempDir()
+	assert.NoError(t, Write(out, p, theme))
+	page, err = os.ReadFile(filepath.Join(out, "index.html"))
+	assert.NoError(t, err)
+	assert.Equal(t, "<h1>Acme &lt;status&gt;: Partial outage</h1>", string(page))
+	assert.FileExists(t, filepath.Join(out, "img", "logo.svg"))
+	assert.NoFileExists(t, filepath.Join(out, "banner.html"))
+
+	assert.ErrorContains(t, Write(t.TempDir(), p, t.TempDir()), "no index.html")
+}
@@ -0,0 +1,409 @@
+// Package store keeps check results in an embedded bbolt database. Raw
+// results are kept for a short time and rolled up per hour for much
+// longer, so history queries don't depend on the log file or its format.
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	rawBucket    = []byte("raw")
+	hourlyBucket = []byte("hourly")
+)
+
+// lockTimeout is how long an operation waits for another process, such as
+// a running monitor, to release the database.
+const lockTimeout = 5 * time.Second
+
+// Result is the recorded outcome of one check.
+type Result struct {
+	Time       time.Time     `json:"time"`
+	Target     string        `json:"target"`
+	URL        string        `json:"url"`
+	Up         bool          `json:"up"`
+	State      status.State  `json:"state"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Latency    time.Duration `json:"latency"`
+	Err        string        `json:"err,omitempty"`
+	// Maintenance is the ID of the maintenance window the check ran in,
+	// if any.
+	Maintenance string `json:"maintenance,omitempty"`
+}
+
+// Rollup aggregates the results for a URL over one hour.
+type Rollup struct {
+	Hour   time.Time `json:"hour"`
+	Checks int       `json:"checks"`
+	Up     int       `json:"up"`
+	// Maintenance counts failed checks during maintenance windows, which
+	// are not included in Checks.
+	Maintenance  int           `json:"maintenance"`
+	TotalLatency time.Duration `json:"total_latency"`
+	MinLatency   time.Duration `json:"min_latency,omitempty"`
+	MaxLatency   time.Duration `json:"max_latency"`
+	// Timed counts the checks that recorded a latency.
+	Timed int `json:"timed"`
+	// Buckets counts the successful checks by latency: Buckets[i] holds
+	// those no slower than LatencyBuckets[i], and the last one the rest.
+	Buckets []int `json:"buckets,omitempty"`
+}
+
+// LatencyBuckets are the upper bounds of the rollup latency histogram.
+var LatencyBuckets = []time.Duration{
+	5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
+// bucket returns the index of the histogram bucket for latency d.
+func bucket(d time.Duration) int {
+	return sort.Search(len(LatencyBuckets), func(i int) bool { return d <= LatencyBuckets[i] })
+}
+
+// UpWithin counts the successful checks no slower than limit. Unless limit
+// is one of the LatencyBuckets, checks in the bucket holding it count as
+// too slow.
+func (r Rollup) UpWithin(limit time.Duration) int {
+	if limit <= 0 {
+		return r.Up
+	}
+	n := 0
+	for i, count := range r.Buckets {
+		if i < len(LatencyBuckets) && LatencyBuckets[i] <= limit {
+			n += count
+		}
+	}
+	return n
+}
+
+// AvgLatency is the mean latency of the timed checks in the hour.
+func (r Rollup) AvgLatency() time.Duration {
+	if r.Timed == 0 {
+		return 0
+	}
+	return r.TotalLatency / time.Duration(r.Timed)
+}
+
+func (r *Rollup) add(res Result) {
+	if res.Maintenance != "" && !res.Up {
+		r.Maintenance++
+		return
+	}
+	r.Checks++
+	if res.Up {
+		r.Up++
+	}
+	if res.Latency > 0 {
+		r.Timed++
+		r.TotalLatency += res.Latency
+		if r.MinLatency == 0 || res.Latency < r.MinLatency {
+			r.MinLatency = res.Latency
+		}
+		r.MaxLatency = max(r.MaxLatency, res.Latency)
+		if res.Up {
+			if r.Buckets == nil {
+				r.Buckets = make([]int, len(LatencyBuckets)+1)
+			}
+			r.Buckets[bucket(res.Latency)]++
+		}
+	}
+}
+
+// Retention sets how long data is kept.
+type Retention struct {
+	// Raw is how long individual results are kept.
+	Raw time.Duration
+	// Rollups is how long hourly rollups are kept.
+	Rollups time.Duration
+}
+
+// DefaultRetention keeps raw results for a week and rollups for a year.
+var DefaultRetention = Retention{Raw: 7 * 24 * time.Hour, Rollups: 365 * 24 * time.Hour}
+
+// Query selects results. Zero values match everything.
+type Query struct {
+	URLs   []string
+	From   time.Time
+	To     time.Time
+	States []status.State
+	// Last, when positive, keeps only the latest Last matching results of
+	// each URL.
+	Last int
+}
+
+func (q Query) matches(r Result) bool {
+	if len(q.States) == 0 {
+		return true
+	}
+	for _, s := range q.States {
+		if r.State == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Store is a result database on disk. It opens the file only for the
+// duration of each operation, so several processes can share it.
+type Store struct {
+	path      string
+	retention Retention
+}
+
+// Open returns the store in the file at path, creating it if needed.
+func Open(path string, retention Retention) (*Store, error) {
+	s := &Store{path: path, retention: retention}
+	err := s.update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{rawBucket, hourlyBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Retention returns the store's retention policy.
+func (s *Store) Retention() Retention {
+	return s.retention
+}
+
+func (s *Store) update(fn func(*bolt.Tx) error) error {
+	db, err := bolt.Open(s.path, 0o644, &bolt.Options{Timeout: lockTimeout})
+	if err != nil {
+		return fmt.Errorf("unable to open store %s: %w", s.path, err)
+	}
+	defer db.Close()
+	return db.Update(fn)
+}
+
+func (s *Store) view(fn func(*bolt.Tx) error) error {
+	if _, err := os.Stat(s.path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	db, err := bolt.Open(s.path, 0o644, &bolt.Options{Timeout: lockTimeout, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("unable to open store %s: %w", s.path, err)
+	}
+	defer db.Close()
+	return db.View(fn)
+}
+
+// timeKey encodes t so keys sort chronologically.
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
+
+func keyTime(key []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key))).UTC()
+}
+
+// Write records results and adds them to their hourly rollups.
+func (s *Store) Write(results ...Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+	return s.update(func(tx *bolt.Tx) error {
+		for _, r := range results {
+			raw, err := tx.Bucket(rawBucket).CreateBucketIfNotExists([]byte(r.URL))
+			if err != nil {
+				return err
+			}
+			key := timeKey(r.Time)
+			// Keep both results of checks recorded in the same nanosecond.
+			for raw.Get(key) != nil {
+				key = timeKey(keyTime(key).Add(1))
+			}
+			data, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			if err := raw.Put(key, data); err != nil {
+				return err
+			}
+
+			hourly, err := tx.Bucket(hourlyBucket).CreateBucketIfNotExists([]byte(r.URL))
+			if err != nil {
+				return err
+			}
+			hour := r.Time.UTC().Truncate(time.Hour)
+			rollup := Rollup{Hour: hour}
+			if data := hourly.Get(timeKey(hour)); data != nil {
+				if err := json.Unmarshal(data, &rollup); err != nil {
+					return err
+				}
+			}
+			rollup.add(r)
+			if data, err = json.Marshal(rollup); err != nil {
+				return err
+			}
+			if err := hourly.Put(timeKey(hour), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// URLs returns every URL with recorded results, sorted.
+func (s *Store) URLs() ([]string, error) {
+	var urls []string
+	err := s.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(hourlyBucket).ForEachBucket(func(name []byte) error {
+			urls = append(urls, string(name))
+			return nil
+		})
+	})
+	return urls, err
+}
+
+// Query returns the raw results matching q, ordered by time.
+func (s *Store) Query(q Query) ([]Result, error) {
+	urls := q.URLs
+	if len(urls) == 0 {
+		var err error
+		if urls, err = s.URLs(); err != nil {
+			return nil, err
+		}
+	}
+	var results []Result
+	err := s.view(func(tx *bolt.Tx) error {
+		for _, url := range urls {
+			b := tx.Bucket(rawBucket).Bucket([]byte(url))
+			if b == nil {
+				continue
+			}
+			matched, err := q.scan(b.Cursor())
+			if err != nil {
+				return fmt.Errorf("corrupt result for %s: %w", url, err)
+			}
+			results = append(results, matched...)
+		}
+		return nil
+	})
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Time.Before(results[j].Time) })
+	return results, err
+}
+
+// scan returns the results in c that match q, ordered by time. With Last
+// set it walks backwards from To, so only the results it keeps are read.
+func (q Query) scan(c *bolt.Cursor) ([]Result, error) {
+	var results []Result
+	keep := func(v []byte) error {
+		var r Result
+		if err := json.Unmarshal(v, &r); err != nil {
+			return err
+		}
+		if q.matches(r) {
+			results = append(results, r)
+		}
+		return nil
+	}
+	if q.Last <= 0 {
+		k, v := c.First()
+		if !q.From.IsZero() {
+			k, v = c.Seek(timeKey(q.From))
+		}
+		for ; k != nil; k, v = c.Next() {
+			if !q.To.IsZero() && !keyTime(k).Before(q.To) {
+				break
+			}
+			if err := keep(v); err != nil {
+				return nil, err
+			}
+		}
+		return results, nil
+	}
+
+	k, v := c.Last()
+	if !q.To.IsZero() {
+		// Seek lands on the first result at or after To, which is left out.
+		if k, v = c.Seek(timeKey(q.To)); k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+	}
+	for ; k != nil && len(results) < q.Last; k, v = c.Prev() {
+		if !q.From.IsZero() && keyTime(k).Before(q.From) {
+			break
+		}
+		if err := keep(v); err != nil {
+			return nil, err
+		}
+	}
+	slices.Reverse(results)
+	return results, nil
+}
+
+// Rollups returns the hourly rollups of url for the hours starting in
+// [from, to), ordered by time. Zero times leave the range open.
+func (s *Store) Rollups(url string, from, to time.Time) ([]Rollup, error) {
+	var rollups []Rollup
+	err := s.view(func(tx *bolt.Tx) error {
+		b := tx.Bucket(hourlyBucket).Bucket([]byte(url))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		k, v := c.First()
+		if !from.IsZero() {
+			k, v = c.Seek(timeKey(from.UTC().Truncate(time.Hour)))
+		}
+		for ; k != nil; k, v = c.Next() {
+			if !to.IsZero() && !keyTime(k).Before(to) {
+				break
+			}
+			var r Rollup
+			if err := json.Unmarshal(v, &r); err != nil {
+				return fmt.Errorf("corrupt rollup for %s: %w", url, err)
+			}
+			rollups = append(rollups, r)
+		}
+		return nil
+	})
+	return rollups, err
+}
+
+// Prune deletes the raw results and rollups that have outlived the
+// retention policy at now.
+func (s *Store) Prune(now time.Time) error {
+	return s.update(func(tx *bolt.Tx) error {
+		for bucket, keep := range map[string]time.Duration{string(rawBucket): s.retention.Raw, string(hourlyBucket): s.retention.Rollups} {
+			if keep <= 0 {
+				continue
+			}
+			cutoff := timeKey(now.Add(-keep))
+			err := tx.Bucket([]byte(bucket)).ForEachBucket(func(name []byte) error {
+				c := tx.Bucket([]byte(bucket)).Bucket(name).Cursor()
+				for k, _ := c.First(); k != nil && string(k) < string(cutoff); k, _ = c.First() {
+					if err := c.Delete(); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marianina8/gocodecli/mod5-example/healthcheck/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func openTemp(t *testing.T, retention Retention) *Store {
+	t.Helper()
+	st, err := Open(filepath.Join(t.TempDir(), "healthcheck.db"), retention)
+	assert.NoError(t, err)
+	return st
+}
+
+var start = time.Date(2024, 4, 19, 8, 0, 0, 0, time.UTC)
+
+func at(m int) time.Time { return start.Add(time.Duration(m) * time.Minute) }
+
+func TestQuery(t *testing.T) {
+	st := openTemp(t, DefaultRetention)
+	assert.NoError(t, st.Write(
+		Result{Time: at(0), URL: "http://api", Up: true, State: status.Up, StatusCode: 200, Latency: 100 * time.Millisecond},
+		Result{Time: at(0), URL: "http://api", Up: true, State: status.Up, StatusCode: 200, Latency: 120 * time.Millisecond},
+		Result{Time: at(1), URL: "http://docs", Up: true, State: status.Up, StatusCode: 200},
+		Result{Time: at(2), URL: "http://api", State: status.Down, Err: "connection refused"},
+		Result{Time: at(90), URL: "http://api", State: status.Maintenance, Maintenance: "deploy"},
+	))
+
+	tests := []struct {
+		name     string
+		query    Query
+		expected int
+	}{
+		{"Everything", Query{}, 5},
+		{"By URL", Query{URLs: []string{"http://api"}}, 4},
+		{"Unknown URL", Query{URLs: []string{"http://missing"}}, 0},
+		{"Time range", Query{From: at(1), To: at(90)}, 2},
+		{"By state", Query{States: []status.State{status.Down, status.Maintenance}}, 2},
+		{"Last per URL", Query{Last: 2}, 3},
+		{"Last in time range", Query{To: at(90), Last: 2}, 3},
+		{"Last by state", Query{URLs: []string{"http://api"}, States: []status.State{status.Up}, Last: 1}, 1},
+		{"Last since", Query{URLs: []string{"http://api"}, From: at(1), Last: 3}, 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			results, err := st.Query(tc.query)
+			assert.NoError(t, err)
+			assert.Len(t, results, tc.expected)
+			for i := 1; i < len(results); i++ {
+				assert.False(t, results[i].Time.Before(results[i-1].Time), "results should be ordered by time")
+			}
+		})
+	}
+
+	urls, err := st.URLs()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http://api", "http://docs"}, urls)
+}
+
+func TestRollups(t *testing.T) {
+	st := openTemp(t, DefaultRetention)
+	assert.NoError(t, st.Write(
+		Result{Time: at(0), URL: "http://api", Up: true, Latency: 100 * time.Millisecond},
+		Result{Time: at(30), URL: "http://api", Up: true, Latency: 300 * time.Millisecond},
+		Result{Time: at(45), URL: "http://api", Err: "timeout"},
+		Result{Time: at(70), URL: "http://api", Maintenance: "deploy"},
+	))
+
+	rollups, err := st.Rollups("http://api", start, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, rollups, 2)
+	assert.Equal(t, start, rollups[0].Hour)
+	assert.Equal(t, 3, rollups[0].Checks)
+	assert.Equal(t, 2, rollups[0].Up)
+	assert.Equal(t, 200*time.Millisecond, rollups[0].AvgLatency())
+	assert.Equal(t, 300*time.Millisecond, rollups[0].MaxLatency)
+	assert.Equal(t, 0, rollups[1].Checks)
+	assert.Equal(t, 1, rollups[1].Maintenance)
+}
+
+func TestPrune(t *testing.T) {
+	st := openTemp(t, Retention{Raw: time.Hour, Rollups: 24 * time.Hour})
+	now := time.Now()
+	assert.NoError(t, st.Write(
+		Result{Time: now.Add(-48 * time.Hour), URL: "http://api", Up: true},
+		Result{Time: now.Add(-2 * time.Hour), URL: "http://api", Up: true},
+		Result{Time: now, URL: "http://api", Up: true},
+	))
+	assert.NoError(t, st.Prune(now))
+
+	results, err := st.Query(Query{})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1, "raw results older than an hour should be pruned")
+	rollups, err := st.Rollups("http://api", time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, rollups, 2, "rollups are kept for a day")
+}
+
+func TestQuery_MissingFile(t *testing.T) {
+	st := &Store{path: filepath.Join(t.TempDir(), "missing.db")}
+	results, err := st.Query(Query{})
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
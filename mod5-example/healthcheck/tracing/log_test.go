@@ -0,0 +1,26 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	record(t)
+	var buf bytes.Buffer
+	l := slog.New(NewHandler(slog.NewTextHandler(&buf, nil))).With("component", "check")
+
+	l.InfoContext(context.Background(), "no span")
+	assert.NotContains(t, buf.String(), "trace_id")
+
+	ctx, span := Tracer().Start(context.Background(), "check")
+	defer span.End()
+	buf.Reset()
+	l.InfoContext(ctx, "in span")
+	sc := span.SpanContext()
+	assert.Contains(t, buf.String(), "component=check trace_id="+sc.TraceID().String()+" span_id="+sc.SpanID().String())
+}
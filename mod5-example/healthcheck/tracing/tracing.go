@@ -0,0 +1,150 @@
+// Package tracing exports OpenTelemetry spans of checks to an OTLP collector,
+// propagates their context to the checked services with the W3C traceparent
+// header and adds trace and span IDs to log records.
+package tracing
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	name = "github.com/marianina8/gocodecli/mod5-example/healthcheck"
+	// tracesPath is where OTLP/HTTP collectors receive spans.
+	tracesPath = "/v1/traces"
+)
+
+// Setup exports spans to the OTLP/HTTP collector at endpoint, or at the one
+// in $OTEL_EXPORTER_OTLP_ENDPOINT or $OTEL_EXPORTER_OTLP_TRACES_ENDPOINT when
+// endpoint is empty. Without any, spans are only used to propagate trace
+// context. The returned function flushes the spans that haven't been
+// exported yet.
+func Setup(ctx context.Context, endpoint, version string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if endpoint == "" && os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	var opts []otlptracehttp.Option
+	if endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid otlp endpoint %q, expected an http or https url", endpoint)
+		}
+		if u.Path == "" || u.Path == "/" {
+			u.Path = tracesPath
+		}
+		opts = append(opts, otlptracehttp.WithEndpointURL(u.String()))
+	}
+	exp, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create otlp exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName("healthcheck"),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Tracer returns the tracer checks are traced with.
+func Tracer() trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// Inject adds the trace context of ctx to the headers of req, so the services
+// it reaches can continue the trace.
+func Inject(ctx context.Context, req *http.Request) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+}
+
+// End records err, if any, on span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// EndResponse records the status code of the response to the request span
+// describes, which failed if it is 400 or above, and ends span.
+func EndResponse(span trace.Span, code int) {
+	span.SetAttributes(semconv.HTTPResponseStatusCode(code))
+	if code >= 400 {
+		span.SetStatus(codes.Error, http.StatusText(code))
+	}
+	span.End()
+}
+
+// WithPhases returns a context whose HTTP requests record their DNS lookup,
+// connections and TLS handshake as child spans of the span in ctx.
+func WithPhases(ctx context.Context) context.Context {
+	var (
+		mu             sync.Mutex
+		dns, handshake trace.Span
+		connects       = make(map[string]trace.Span)
+	)
+	start := func(name string, attrs ...attribute.KeyValue) trace.Span {
+		_, span := Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+		return span
+	}
+	// Connections to several addresses of a host may be dialed at once.
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			dns = start("dns", semconv.ServerAddress(info.Host))
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			if dns != nil {
+				End(dns, info.Err)
+			}
+		},
+		ConnectStart: func(network, addr string) {
+			span := start("connect", semconv.NetworkTransportKey.String(network), semconv.NetworkPeerAddress(addr))
+			mu.Lock()
+			connects[addr] = span
+			mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			mu.Lock()
+			span := connects[addr]
+			delete(connects, addr)
+			mu.Unlock()
+			if span != nil {
+				End(span, err)
+			}
+		},
+		TLSHandshakeStart: func() {
+			handshake = start("tls")
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if handshake == nil {
+				return
+			}
+			if err == nil {
+				handshake.SetAttributes(semconv.TLSProtocolVersion(tls.VersionName(state.Version)))
+			}
+			End(handshake, err)
+		},
+	})
+}
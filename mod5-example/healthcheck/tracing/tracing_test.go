@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// record makes the global tracer provider record spans until the test ends.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return sr
+}
+
+func names(sr *tracetest.SpanRecorder) []string {
+	var names []string
+	for _, s := range sr.Ended() {
+		names = append(names, s.Name())
+	}
+	return names
+}
+
+func TestSetup(t *testing.T) {
+	var exported atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/traces" {
+			exported.Add(1)
+		}
+	}))
+	defer collector.Close()
+	prev := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(prev)
+
+	_, err := Setup(context.Background(), "localhost:4318", "test")
+	assert.ErrorContains(t, err, "expected an http or https url")
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	flush, err := Setup(context.Background(), "", "test")
+	assert.NoError(t, err)
+	assert.NoError(t, flush(context.Background()))
+	assert.Equal(t, prev, otel.GetTracerProvider(), "spans aren't exported without an endpoint")
+
+	flush, err = Setup(context.Background(), collector.URL, "test")
+	assert.NoError(t, err)
+	_, span := Tracer().Start(context.Background(), "check")
+	span.End()
+	assert.NoError(t, flush(context.Background()))
+	assert.Equal(t, int32(1), exported.Load(), "spans are sent to /v1/traces by default")
+}
+
+func TestInject(t *testing.T) {
+	record(t)
+	Setup(context.Background(), "", "test")
+	ctx, span := Tracer().Start(context.Background(), "check")
+	defer span.End()
+
+	req := httptest.NewRequest(http.MethodGet, "http://api.test", nil)
+	Inject(ctx, req)
+	sc := span.SpanContext()
+	assert.Equal(t, "00-"+sc.TraceID().String()+"-"+sc.SpanID().String()+"-01", req.Header.Get("traceparent"))
+}
+
+func TestEndResponse(t *testing.T) {
+	sr := record(t)
+	for _, code := range []int{200, 503} {
+		_, span := Tracer().Start(context.Background(), "GET")
+		EndResponse(span, code)
+	}
+	spans := sr.Ended()
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "Service Unavailable", spans[1].Status().Description)
+}
+
+func TestWithPhases(t *testing.T) {
+	sr := record(t)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	ctx, span := Tracer().Start(context.Background(), "GET")
+	req, _ := http.NewRequestWithContext(WithPhases(ctx), http.MethodGet, srv.URL, nil)
+	resp, err := srv.Client().Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	span.End()
+
+	assert.Equal(t, []string{"connect", "tls", "GET"}, names(sr))
+	for _, s := range sr.Ended()[:2] {
+		assert.Equal(t, span.SpanContext().SpanID(), s.Parent().SpanID(), "%s is a child of the request span", s.Name())
+	}
+}
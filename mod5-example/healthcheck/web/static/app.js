@@ -0,0 +1,186 @@
+// The dashboard renders a snapshot of the monitor's state and then applies
+// the events the monitor streams as checks complete.
+'use strict';
+
+// maxPoints is the number of checks kept per target for its chart.
+const maxPoints = 120;
+
+const targets = new Map();
+let incidents = [];
+
+function formatLatency(ns) {
+  if (!ns) {
+    return '-';
+  }
+  const ms = ns / 1e6;
+  return ms >= 1000 ? (ms / 1000).toFixed(2) + 's' : Math.round(ms) + 'ms';
+}
+
+function formatDuration(ms) {
+  const s = Math.round(ms / 1000);
+  if (s < 60) {
+    return s + 's';
+  }
+  if (s < 3600) {
+    return Math.floor(s / 60) + 'm ' + (s % 60) + 's';
+  }
+  return Math.floor(s / 3600) + 'h ' + Math.floor((s % 3600) / 60) + 'm';
+}
+
+function el(tag, className, text) {
+  const node = document.createElement(tag);
+  if (className) {
+    node.className = className;
+  }
+  if (text !== undefined) {
+    node.textContent = text;
+  }
+  return node;
+}
+
+function addPoint(t, result) {
+  t.points.push({ time: result.time, latency: result.up ? result.latency : 0 });
+  if (t.points.length > maxPoints) {
+    t.points.shift();
+  }
+}
+
+// update stores the status of a target, adding its latest check to the
+// chart unless it was seen already.
+function update(status) {
+  let t = targets.get(status.name);
+  if (!t) {
+    t = { points: [] };
+    targets.set(status.name, t);
+  }
+  const last = t.status && t.status.last;
+  t.status = status;
+  if (status.last && (!last || last.time !== status.last.time)) {
+    addPoint(t, status.last);
+  }
+}
+
+const svgNS = 'http://www.w3.org/2000/svg';
+
+function chart(points) {
+  const svg = document.createElementNS(svgNS, 'svg');
+  svg.setAttribute('viewBox', '0 0 ' + maxPoints + ' 60');
+  svg.setAttribute('preserveAspectRatio', 'none');
+  const max = Math.max(1, ...points.map((p) => p.latency));
+  const offset = maxPoints - points.length;
+  const coords = [];
+  points.forEach((p, i) => {
+    const x = offset + i;
+    if (p.latency) {
+      coords.push(x + ',' + (58 - (p.latency / max) * 54).toFixed(1));
+    } else {
+      const dot = document.createElementNS(svgNS, 'circle');
+      dot.setAttribute('cx', x);
+      dot.setAttribute('cy', 57);
+      dot.setAttribute('r', 1.5);
+      svg.appendChild(dot);
+    }
+  });
+  const line = document.createElementNS(svgNS, 'polyline');
+  line.setAttribute('points', coords.join(' '));
+  line.setAttribute('vector-effect', 'non-scaling-stroke');
+  svg.appendChild(line);
+  return svg;
+}
+
+function renderTiles() {
+  const tiles = document.getElementById('tiles');
+  tiles.replaceChildren();
+  const counts = {};
+  for (const [name, t] of targets) {
+    const s = t.status;
+    counts[s.state] = (counts[s.state] || 0) + 1;
+
+    const tile = el('article', 'tile ' + s.state + (s.paused ? ' paused' : ''));
+    tile.appendChild(el('div', 'name', name));
+    if (s.url !== name) {
+      tile.appendChild(el('div', 'url', s.url));
+    }
+    const row = el('div', 'row');
+    row.appendChild(el('span', 'state ' + s.state, s.paused ? s.state + ' (paused)' : s.state));
+    row.appendChild(el('span', 'latency', s.last ? formatLatency(s.last.latency) : '-'));
+    tile.appendChild(row);
+    tile.appendChild(chart(t.points));
+    tile.appendChild(el('div', 'error', s.last && s.last.err ? s.last.err : ''));
+    tile.title = s.last ? 'Last checked ' + new Date(s.last.time).toLocaleString() : 'Not checked yet';
+    tiles.appendChild(tile);
+  }
+
+  const summary = Object.keys(counts).sort().map((state) => counts[state] + ' ' + state);
+  document.getElementById('summary').textContent = targets.size + ' targets' + (summary.length ? ': ' + summary.join(', ') : '');
+}
+
+function renderIncidents() {
+  const body = document.querySelector('#incidents tbody');
+  body.replaceChildren();
+  const now = Date.now();
+  for (const inc of incidents) {
+    const start = new Date(inc.start).getTime();
+    const end = inc.end ? new Date(inc.end).getTime() : now;
+    const row = el('tr', inc.end ? '' : 'ongoing');
+    row.appendChild(el('td', '', inc.target));
+    row.appendChild(el('td', '', new Date(start).toLocaleString()));
+    row.appendChild(el('td', '', formatDuration(end - start) + (inc.end ? '' : ' (ongoing)')));
+    row.appendChild(el('td', '', inc.reason || ''));
+    body.appendChild(row);
+  }
+  document.getElementById('incidents').hidden = incidents.length === 0;
+  document.getElementById('no-incidents').hidden = incidents.length > 0;
+}
+
+function render() {
+  renderTiles();
+  renderIncidents();
+}
+
+function setConnected(online) {
+  const node = document.getElementById('connection');
+  node.className = 'connection ' + (online ? 'online' : 'offline');
+  node.textContent = online ? 'live' : 'reconnecting…';
+}
+
+function connect() {
+  const events = new EventSource('events');
+  events.onopen = () => setConnected(true);
+  events.onerror = () => setConnected(false);
+
+  events.addEventListener('snapshot', (e) => {
+    const snap = JSON.parse(e.data);
+    targets.clear();
+    for (const status of snap.targets) {
+      targets.set(status.name, { status: status, points: [] });
+    }
+    for (const result of snap.results) {
+      const t = targets.get(result.target);
+      if (t) {
+        addPoint(t, result);
+      }
+    }
+    incidents = snap.incidents;
+    render();
+  });
+
+  events.addEventListener('target', (e) => {
+    update(JSON.parse(e.data));
+    renderTiles();
+  });
+
+  events.addEventListener('removed', (e) => {
+    targets.delete(JSON.parse(e.data).name);
+    renderTiles();
+  });
+
+  events.addEventListener('incidents', (e) => {
+    incidents = JSON.parse(e.data);
+    renderIncidents();
+  });
+}
+
+connect();
+// Keep the durations of ongoing incidents current.
+setInterval(renderIncidents, 10000);
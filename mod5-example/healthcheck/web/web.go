@@ -0,0 +1,134 @@
+// Package web serves the browser dashboard of the monitor command: a single
+// page embedded in the binary, kept up to date over Server-Sent Events. It
+// loads nothing from elsewhere, so it works on hosts without internet
+// access.
+package web
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//go:embed static
+var static embed.FS
+
+// keepAlive is how often an idle event stream gets a comment, so proxies
+// don't close it.
+const keepAlive = 15 * time.Second
+
+// clientBuffer is the number of events queued for a browser. A browser that
+// falls further behind is disconnected; it reconnects and starts over from
+// a fresh snapshot.
+const clientBuffer = 64
+
+// Broker passes events to every connected browser.
+type Broker struct {
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+}
+
+// NewBroker returns a broker without clients.
+func NewBroker() *Broker {
+	return &Broker{clients: make(map[chan []byte]struct{})}
+}
+
+// Publish sends an event named event, with data encoded as JSON, to every
+// connected browser.
+func (b *Broker) Publish(event string, data any) error {
+	msg, err := encode(event, data)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.clients {
+		select {
+		case c <- msg:
+		default:
+			delete(b.clients, c)
+			close(c)
+		}
+	}
+	return nil
+}
+
+func (b *Broker) subscribe() chan []byte {
+	c := make(chan []byte, clientBuffer)
+	b.mu.Lock()
+	b.clients[c] = struct{}{}
+	b.mu.Unlock()
+	return c
+}
+
+func (b *Broker) unsubscribe(c chan []byte) {
+	b.mu.Lock()
+	if _, ok := b.clients[c]; ok {
+		delete(b.clients, c)
+		close(c)
+	}
+	b.mu.Unlock()
+}
+
+// encode formats an event in the text/event-stream format.
+func encode(event string, data any) ([]byte, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode %s event: %w", event, err)
+	}
+	return []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", event, payload)), nil
+}
+
+// Handler serves the dashboard page and, at /events, the event stream of
+// b. Each stream starts with a "snapshot" event holding the result of
+// snapshot, which the page renders before applying later events.
+func Handler(b *Broker, snapshot func() any) http.Handler {
+	files, _ := fs.Sub(static, "static")
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServer(http.FS(files)))
+	mux.HandleFunc("GET /events", func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		// Subscribe before taking the snapshot, so no event falls between
+		// the two.
+		c := b.subscribe()
+		defer b.unsubscribe(c)
+		first, err := encode("snapshot", snapshot())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no")
+		w.Write(first)
+		flusher.Flush()
+
+		ticker := time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case msg, ok := <-c:
+				if !ok {
+					return
+				}
+				w.Write(msg)
+			case <-ticker.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			}
+			flusher.Flush()
+		}
+	})
+	return mux
+}
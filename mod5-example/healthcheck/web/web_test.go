@@ -0,0 +1,99 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_Page(t *testing.T) {
+	srv := httptest.NewServer(Handler(NewBroker(), func() any { return nil }))
+	defer srv.Close()
+
+	for path, want := range map[string]string{
+		"/":          "<title>healthcheck monitor</title>",
+		"/app.js":    "new EventSource('events')",
+		"/style.css": ".tiles",
+	} {
+		resp, err := http.Get(srv.URL + path)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+		assert.Contains(t, string(body), want, path)
+	}
+}
+
+// TestStatic_SelfContained makes sure the page loads nothing from other
+// hosts, so it works without internet access.
+func TestStatic_SelfContained(t *testing.T) {
+	err := fs.WalkDir(static, "static", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(static, path)
+		if err != nil {
+			return err
+		}
+		// The SVG namespace is an identifier, not a location.
+		content := strings.ReplaceAll(string(data), "http://www.w3.org/2000/svg", "")
+		assert.NotContains(t, content, "http://", path)
+		assert.NotContains(t, content, "https://", path)
+		assert.NotContains(t, content, `src="//`, path)
+		assert.NotContains(t, content, `href="//`, path)
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestHandler_Events(t *testing.T) {
+	b := NewBroker()
+	srv := httptest.NewServer(Handler(b, func() any { return map[string]int{"targets": 2} }))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	r := bufio.NewReader(resp.Body)
+	readEvent := func() string {
+		var lines []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil || line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+	assert.Equal(t, "event: snapshot\ndata: {\"targets\":2}\n", readEvent())
+
+	assert.NoError(t, b.Publish("target", map[string]string{"name": "api"}))
+	assert.Equal(t, "event: target\ndata: {\"name\":\"api\"}\n", readEvent())
+}
+
+func TestBroker_SlowClient(t *testing.T) {
+	b := NewBroker()
+	c := b.subscribe()
+	for i := 0; i <= clientBuffer; i++ {
+		assert.NoError(t, b.Publish("target", i))
+	}
+	n := 0
+	for range c {
+		n++
+	}
+	assert.Equal(t, clientBuffer, n, "a client that falls behind is disconnected")
+	b.unsubscribe(c)
+}